@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+/*
+GO SQLITE MIGRATIONS TESTS (Lessons 1-10)
+
+Suggested use:
+1) Install driver (if needed): go get modernc.org/sqlite
+2) Run: go test lessons/code/115-go-sqlite-migrations-tests-1-10_test.go -run TestLesson -v
+3) Focus on what the database remembers between runs
+
+Extra context:
+- lessons/notes/200-go-schema-migrations-first-principles.md
+*/
+
+type Task struct {
+	ID    int64
+	Title string
+	Done  bool
+}
+
+type TaskRepository interface {
+	List() ([]Task, error)
+	Add(title string) (Task, error)
+	MarkDone(id int64) error
+}
+
+type SQLiteTaskRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteTaskRepo(db *sql.DB) *SQLiteTaskRepo {
+	return &SQLiteTaskRepo{db: db}
+}
+
+var taskMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tasks",
+		Up: `
+CREATE TABLE IF NOT EXISTS tasks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title TEXT NOT NULL,
+  done INTEGER NOT NULL DEFAULT 0
+);`,
+		Down: `DROP TABLE tasks;`,
+	},
+}
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was edited")
+	ErrUnknownMigration = errors.New("database has a migration this binary does not know")
+)
+
+// Migration is one numbered schema step. Never edit a migration after it
+// has shipped; add a new one instead (the checksum check enforces this).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + "\n--down--\n" + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+type MigrationStatus struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// Migrator applies migrations in version order and records each one in
+// schema_migrations inside the same transaction as the schema change.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	DryRun     bool
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  applied_at TEXT NOT NULL
+);`)
+	return err
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt string
+}
+
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		out[version] = a
+	}
+	return out, rows.Err()
+}
+
+// Verify fails when history in the database disagrees with the code.
+func (m *Migrator) Verify() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	return m.verify(applied)
+}
+
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := map[int]bool{}
+	for i, mig := range m.migrations {
+		if mig.Version <= 0 || (i > 0 && mig.Version == m.migrations[i-1].Version) {
+			return fmt.Errorf("migration %d (%s): versions must be positive and unique", mig.Version, mig.Name)
+		}
+		known[mig.Version] = true
+		if a, ok := applied[mig.Version]; ok && a.checksum != mig.Checksum() {
+			return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, ErrChecksumMismatch)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("migration %d: %w", version, ErrUnknownMigration)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		a, ok := applied[mig.Version]
+		out = append(out, MigrationStatus{Migration: mig, Applied: ok, AppliedAt: a.appliedAt})
+	}
+	return out, nil
+}
+
+// Up applies every pending migration and returns what ran (or would run
+// when DryRun is set).
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Up, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+				mig.Version, mig.Name, mig.Checksum(), time.Now().UTC().Format(time.RFC3339))
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) up: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the newest `steps` applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Down, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) down: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// inTx runs a schema step and its bookkeeping row atomically.
+func (m *Migrator) inTx(step string, record string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(step); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runMigrateCommand implements `migrate status|up|down [-dry-run] [-steps N]`.
+func runMigrateCommand(migrator *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate status|up|down [-dry-run] [-steps N]")
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "print the plan without changing the database")
+	steps := fs.Int("steps", 1, "how many migrations to revert (down only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	migrator.DryRun = *dryRun
+	prefix := ""
+	if *dryRun {
+		prefix = "(dry run) "
+	}
+
+	switch args[0] {
+	case "status":
+		items, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range items {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt
+			}
+			fmt.Fprintf(out, "%04d %-24s %s\n", st.Migration.Version, st.Migration.Name, state)
+		}
+		return migrator.Verify()
+	case "up":
+		done, err := migrator.Up()
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sup   %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	case "down":
+		done, err := migrator.Down(*steps)
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sdown %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func (r *SQLiteTaskRepo) Migrate() error {
+	_, err := NewMigrator(r.db, taskMigrations).Up()
+	return err
+}
+
+func (r *SQLiteTaskRepo) Add(title string) (Task, error) {
+	result, err := r.db.Exec(`INSERT INTO tasks (title, done) VALUES (?, 0)`, title)
+	if err != nil {
+		return Task{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Task{}, err
+	}
+	return Task{ID: id, Title: title, Done: false}, nil
+}
+
+func (r *SQLiteTaskRepo) List() ([]Task, error) {
+	rows, err := r.db.Query(`SELECT id, title, done FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Task{}
+	for rows.Next() {
+		var t Task
+		var doneInt int
+		if err := rows.Scan(&t.ID, &t.Title, &doneInt); err != nil {
+			return nil, err
+		}
+		t.Done = doneInt == 1
+		items = append(items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SQLiteTaskRepo) MarkDone(id int64) error {
+	result, err := r.db.Exec(`UPDATE tasks SET done = 1 WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("task id %d not found", id)
+	}
+	return nil
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open test db error: %v", err)
+	}
+	// :memory: is per connection, so keep exactly one.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	if err != nil {
+		t.Fatalf("sqlite_master query failed: %v", err)
+	}
+	return count == 1
+}
+
+var addNotesColumn = Migration{
+	Version: 2,
+	Name:    "add_task_notes",
+	Up:      `ALTER TABLE tasks ADD COLUMN notes TEXT NOT NULL DEFAULT '';`,
+	Down:    `ALTER TABLE tasks DROP COLUMN notes;`,
+}
+
+func TestLesson1UpAppliesAndRecords(t *testing.T) {
+	db := openTestDB(t)
+	done, err := NewMigrator(db, taskMigrations).Up()
+	if err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+	if len(done) != 1 || !tableExists(t, db, "tasks") {
+		t.Fatalf("expected tasks table from migration 1, applied %v", done)
+	}
+	status, _ := NewMigrator(db, taskMigrations).Status()
+	if !status[0].Applied || status[0].AppliedAt == "" {
+		t.Fatalf("expected recorded migration, got %+v", status)
+	}
+}
+
+func TestLesson2UpIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewSQLiteTaskRepo(db)
+	_ = repo.Migrate()
+	_, _ = repo.Add("keep me")
+	done, err := NewMigrator(db, taskMigrations).Up()
+	if err != nil || len(done) != 0 {
+		t.Fatalf("second up should be a no-op, got %v %v", done, err)
+	}
+	items, _ := repo.List()
+	if len(items) != 1 {
+		t.Fatalf("data should survive re-running migrations")
+	}
+}
+
+func TestLesson3DryRunChangesNothing(t *testing.T) {
+	db := openTestDB(t)
+	m := NewMigrator(db, taskMigrations)
+	m.DryRun = true
+	done, err := m.Up()
+	if err != nil || len(done) != 1 {
+		t.Fatalf("dry run should report the plan, got %v %v", done, err)
+	}
+	if tableExists(t, db, "tasks") {
+		t.Fatalf("dry run must not create tables")
+	}
+}
+
+func TestLesson4NewMigrationAppliesOnlyTheDelta(t *testing.T) {
+	db := openTestDB(t)
+	_, _ = NewMigrator(db, taskMigrations).Up()
+	done, err := NewMigrator(db, append(taskMigrations, addNotesColumn)).Up()
+	if err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Fatalf("expected only migration 2, got %v", done)
+	}
+	if _, err := db.Exec(`UPDATE tasks SET notes = 'x'`); err != nil {
+		t.Fatalf("notes column missing: %v", err)
+	}
+}
+
+func TestLesson5DownRevertsNewestFirst(t *testing.T) {
+	db := openTestDB(t)
+	all := append(taskMigrations, addNotesColumn)
+	m := NewMigrator(db, all)
+	_, _ = m.Up()
+	done, err := m.Down(1)
+	if err != nil || len(done) != 1 || done[0].Version != 2 {
+		t.Fatalf("expected migration 2 reverted, got %v %v", done, err)
+	}
+	if _, err := db.Exec(`UPDATE tasks SET notes = 'x'`); err == nil {
+		t.Fatalf("notes column should be gone")
+	}
+	_, _ = m.Down(5)
+	if tableExists(t, db, "tasks") {
+		t.Fatalf("tasks table should be dropped after full down")
+	}
+}
+
+func TestLesson6EditedMigrationIsRejected(t *testing.T) {
+	db := openTestDB(t)
+	_, _ = NewMigrator(db, taskMigrations).Up()
+	edited := []Migration{taskMigrations[0]}
+	edited[0].Up += "\n-- sneaky edit"
+	_, err := NewMigrator(db, edited).Up()
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("want ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestLesson7UnknownAppliedVersionIsRejected(t *testing.T) {
+	db := openTestDB(t)
+	_, _ = NewMigrator(db, append(taskMigrations, addNotesColumn)).Up()
+	err := NewMigrator(db, taskMigrations).Verify()
+	if !errors.Is(err, ErrUnknownMigration) {
+		t.Fatalf("want ErrUnknownMigration, got %v", err)
+	}
+}
+
+func TestLesson8FailedMigrationRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	broken := Migration{
+		Version: 1,
+		Name:    "half_broken",
+		Up:      `CREATE TABLE half (id INTEGER); INSERT INTO missing_table VALUES (1);`,
+		Down:    `DROP TABLE half;`,
+	}
+	if _, err := NewMigrator(db, []Migration{broken}).Up(); err == nil {
+		t.Fatalf("expected failure")
+	}
+	if tableExists(t, db, "half") {
+		t.Fatalf("partial schema change should be rolled back")
+	}
+	status, _ := NewMigrator(db, []Migration{broken}).Status()
+	if status[0].Applied {
+		t.Fatalf("failed migration must not be recorded")
+	}
+}
+
+func TestLesson9CommandOutput(t *testing.T) {
+	db := openTestDB(t)
+	var out bytes.Buffer
+	if err := runMigrateCommand(NewMigrator(db, taskMigrations), []string{"up"}, &out); err != nil {
+		t.Fatalf("up command failed: %v", err)
+	}
+	out.Reset()
+	if err := runMigrateCommand(NewMigrator(db, taskMigrations), []string{"status"}, &out); err != nil {
+		t.Fatalf("status command failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "0001 create_tasks") || !strings.Contains(out.String(), "applied") {
+		t.Fatalf("unexpected status output: %q", out.String())
+	}
+	if err := runMigrateCommand(NewMigrator(db, taskMigrations), []string{"sideways"}, &out); err == nil {
+		t.Fatalf("expected unknown command error")
+	}
+}
+
+func TestLesson10Completion(t *testing.T) {
+	if false {
+		t.Fatalf("unreachable")
+	}
+}
+
+// End of Go SQLite Migrations Tests 1-10
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+/*
+GO FILE DURABILITY TESTS (Lessons 1-10)
+
+Suggested use:
+1) Run: go test lessons/code/116-go-file-durability-tests-1-10_test.go -run TestLesson -v
+2) Focus on the failure cases: crashes, truncation and two writers at once
+
+Extra context:
+- lessons/notes/169-go-file-storage-gotchas.md
+*/
+
+type Task struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+type TaskRepository interface {
+	List() ([]Task, error)
+	Add(title string) (Task, error)
+	MarkDone(id int) error
+}
+
+type TaskService struct {
+	repo TaskRepository
+}
+
+func NewTaskService(repo TaskRepository) *TaskService {
+	return &TaskService{repo: repo}
+}
+
+func (s *TaskService) CreateTask(title string) (Task, error) {
+	clean := strings.TrimSpace(title)
+	if clean == "" {
+		return Task{}, errors.New("title is required")
+	}
+	return s.repo.Add(clean)
+}
+
+func (s *TaskService) CompleteTask(id int) error {
+	if id <= 0 {
+		return errors.New("id must be positive")
+	}
+	return s.repo.MarkDone(id)
+}
+
+func (s *TaskService) Tasks() ([]Task, error) {
+	return s.repo.List()
+}
+
+type InMemoryTaskRepo struct {
+	items []Task
+}
+
+func (r *InMemoryTaskRepo) List() ([]Task, error) {
+	out := make([]Task, len(r.items))
+	copy(out, r.items)
+	return out, nil
+}
+
+func (r *InMemoryTaskRepo) Add(title string) (Task, error) {
+	nextID := 1
+	if len(r.items) > 0 {
+		nextID = r.items[len(r.items)-1].ID + 1
+	}
+	t := Task{ID: nextID, Title: title, Done: false}
+	r.items = append(r.items, t)
+	return t, nil
+}
+
+func (r *InMemoryTaskRepo) MarkDone(id int) error {
+	for i := range r.items {
+		if r.items[i].ID == id {
+			r.items[i].Done = true
+			return nil
+		}
+	}
+	return fmt.Errorf("task id %d not found", id)
+}
+
+type JSONFileTaskRepo struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewJSONFileTaskRepo(path string) *JSONFileTaskRepo {
+	return &JSONFileTaskRepo{path: path}
+}
+
+const (
+	lockTimeout    = 5 * time.Second
+	lockRetryDelay = 5 * time.Millisecond
+)
+
+// withLock serializes goroutines (mutex) and processes (a sidecar lock file
+// holding the owner's PID). The lock is a separate file because the data file
+// itself is replaced by rename on every save. Exclusive creation behaves the
+// same on every OS, unlike flock; the PID inside lets a waiter see that the
+// owner crashed and take the lock over instead of waiting for a human.
+func (r *JSONFileTaskRepo) withLock(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lockPath := r.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		owner, err := acquireLock(lockPath)
+		if err != nil {
+			return err
+		}
+		if owner == 0 {
+			break
+		}
+		if !processAlive(owner) && breakLock(lockPath, owner) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock %s: still held by process %d after %s", r.path, owner, lockTimeout)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+	defer os.Remove(lockPath)
+	return fn()
+}
+
+// acquireLock links a file that already holds our PID to lockPath; the link
+// fails if lockPath exists, so a lock is never seen without its owner. It
+// returns 0 once the lock is ours, otherwise the PID of the current owner.
+func acquireLock(lockPath string) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprint(tmp, os.Getpid())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	for {
+		err := os.Link(tmp.Name(), lockPath)
+		if err == nil {
+			return 0, nil
+		}
+		if !os.IsExist(err) {
+			return 0, err
+		}
+		owner, err := lockOwner(lockPath)
+		if os.IsNotExist(err) {
+			continue // released between the two calls
+		}
+		return owner, err
+	}
+}
+
+func lockOwner(lockPath string) (int, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("lock %s: no owner PID inside", lockPath)
+	}
+	return pid, nil
+}
+
+// processAlive asks the OS about pid. Signal 0 checks without delivering
+// anything on Unix; Windows only supports Kill, but FindProcess there
+// already fails once the process is gone.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// breakLock moves a dead owner's lock aside. Two waiters can both see the
+// same dead owner; if the file this one moved turns out to hold another
+// PID, the lock was taken in between and is linked back.
+func breakLock(lockPath string, dead int) bool {
+	aside, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".stale-*")
+	if err != nil {
+		return false
+	}
+	aside.Close()
+	defer os.Remove(aside.Name())
+	if os.Rename(lockPath, aside.Name()) != nil {
+		return false
+	}
+	if owner, err := lockOwner(aside.Name()); err == nil && owner != dead {
+		_ = os.Link(aside.Name(), lockPath)
+	}
+	return true
+}
+
+// taskFile is the CLI's (lesson 132) wrapper around the same array. next_id
+// remembers IDs of deleted tasks, so it must survive every save here too.
+type taskFile struct {
+	NextID int    `json:"next_id"`
+	Tasks  []Task `json:"tasks"`
+}
+
+// decodeTasks reads a bare array or a taskFile. nextID is 0 for a bare
+// array, which save then writes back unchanged.
+func decodeTasks(data []byte) (items []Task, nextID int, err error) {
+	items = []Task{}
+	if len(data) == 0 {
+		return items, 0, nil
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapped taskFile
+		if json.Unmarshal(data, &wrapped) != nil || wrapped.Tasks == nil {
+			return nil, 0, fmt.Errorf("invalid json file format: %w", err)
+		}
+		items = wrapped.Tasks
+		nextID = max(wrapped.NextID, 1)
+		for _, t := range items {
+			nextID = max(nextID, t.ID+1)
+		}
+	}
+	return items, nextID, nil
+}
+
+// load falls back to the backup generation when the data file exists but is
+// empty or unreadable (for example truncated by a crash or a bad copy).
+func (r *JSONFileTaskRepo) load() ([]Task, int, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Task{}, 0, nil
+		}
+		return nil, 0, err
+	}
+	items, nextID, parseErr := decodeTasks(data)
+	if parseErr == nil && len(data) > 0 {
+		return items, nextID, nil
+	}
+	if backup, err := os.ReadFile(r.path + ".bak"); err == nil && len(backup) > 0 {
+		if recovered, nextID, err := decodeTasks(backup); err == nil {
+			return recovered, nextID, nil
+		}
+	}
+	return items, nextID, parseErr
+}
+
+// save keeps the current valid file as path+".bak", then atomically replaces
+// the data file. A corrupt current file never overwrites a good backup.
+// A nextID of 0 writes the bare array this lesson has always used.
+func (r *JSONFileTaskRepo) save(items []Task, nextID int) error {
+	var doc any = items
+	if nextID > 0 {
+		doc = taskFile{NextID: nextID, Tasks: items}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if current, err := os.ReadFile(r.path); err == nil && len(current) > 0 {
+		if _, _, err := decodeTasks(current); err == nil {
+			if err := writeFileAtomic(r.path+".bak", current); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(r.path, data)
+}
+
+// writeFileAtomic writes a temp file in the same directory, fsyncs it and
+// renames it over path, so readers see the old or the new file, never half.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// The rename is only durable once the directory entry is flushed too.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// List takes no lock: save replaces the file by rename, so a reader sees
+// the previous file or the next one, never half of either.
+func (r *JSONFileTaskRepo) List() ([]Task, error) {
+	items, _, err := r.load()
+	return items, err
+}
+
+func (r *JSONFileTaskRepo) Add(title string) (Task, error) {
+	var t Task
+	err := r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		id := 1
+		if len(items) > 0 {
+			id = items[len(items)-1].ID + 1
+		}
+		if nextID > 0 {
+			// Never reuse the ID of a task the CLI already deleted.
+			id = max(id, nextID)
+			nextID = id + 1
+		}
+		t = Task{ID: id, Title: title, Done: false}
+		return r.save(append(items, t), nextID)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+func (r *JSONFileTaskRepo) MarkDone(id int) error {
+	return r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		found := false
+		for i := range items {
+			if items[i].ID == id {
+				items[i].Done = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("task id %d not found", id)
+		}
+		return r.save(items, nextID)
+	})
+}
+
+func uniqueIDs(t *testing.T, items []Task) map[int]bool {
+	t.Helper()
+	seen := map[int]bool{}
+	for _, it := range items {
+		if seen[it.ID] {
+			t.Fatalf("duplicate id %d in %+v", it.ID, items)
+		}
+		seen[it.ID] = true
+	}
+	return seen
+}
+
+func TestLesson1RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	service := NewTaskService(NewJSONFileTaskRepo(path))
+	_, _ = service.CreateTask("a")
+	_ = service.CompleteTask(1)
+	items, err := NewJSONFileTaskRepo(path).List()
+	if err != nil || len(items) != 1 || !items[0].Done {
+		t.Fatalf("unexpected round trip: %+v %v", items, err)
+	}
+
+	// A file the CLI (lesson 132) wrote after deleting task 4 loads too.
+	// Add must not hand out 4 again, and the save keeps next_id.
+	cli := filepath.Join(t.TempDir(), "tasks.json")
+	_ = os.WriteFile(cli, []byte(`{"next_id": 5, "tasks": [{"id": 3, "title": "from cli"}]}`), 0o644)
+	repo := NewJSONFileTaskRepo(cli)
+	if task, err := repo.Add("b"); err != nil || task.ID != 5 {
+		t.Fatalf("add to a CLI file: %+v %v", task, err)
+	}
+	if err := repo.MarkDone(3); err != nil {
+		t.Fatalf("mark done in a CLI file: %v", err)
+	}
+	var saved taskFile
+	if data, _ := os.ReadFile(cli); json.Unmarshal(data, &saved) != nil || saved.NextID != 6 || len(saved.Tasks) != 2 {
+		t.Fatalf("want next_id 6 and two tasks after save, got %q", data)
+	}
+
+	// A bare array stays a bare array.
+	var plain []Task
+	if data, _ := os.ReadFile(path); json.Unmarshal(data, &plain) != nil || len(plain) != 1 {
+		t.Fatalf("want a bare array after save, got %q", data)
+	}
+}
+
+func TestLesson2NoTempFilesLeftBehind(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewJSONFileTaskRepo(filepath.Join(dir, "tasks.json"))
+	for i := range 3 {
+		_, _ = repo.Add(fmt.Sprintf("task-%d", i))
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Fatalf("temp file left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestLesson3BackupKeepsPreviousGeneration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	repo := NewJSONFileTaskRepo(path)
+	_, _ = repo.Add("a")
+	_, _ = repo.Add("b")
+	data, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("expected backup file: %v", err)
+	}
+	var backup []Task
+	_ = json.Unmarshal(data, &backup)
+	if len(backup) != 1 || backup[0].Title != "a" {
+		t.Fatalf("backup should hold the generation before the last save, got %+v", backup)
+	}
+}
+
+func TestLesson4TruncatedFileRecoversFromBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	repo := NewJSONFileTaskRepo(path)
+	_, _ = repo.Add("a")
+	_, _ = repo.Add("b")
+	data, _ := os.ReadFile(path)
+	_ = os.WriteFile(path, data[:len(data)/2], 0o644)
+
+	items, err := repo.List()
+	if err != nil {
+		t.Fatalf("expected recovery, got %v", err)
+	}
+	if len(items) != 1 || items[0].Title != "a" {
+		t.Fatalf("expected backup generation, got %+v", items)
+	}
+}
+
+func TestLesson5CorruptFileDoesNotOverwriteGoodBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	repo := NewJSONFileTaskRepo(path)
+	_, _ = repo.Add("a")
+	_, _ = repo.Add("b")
+	_ = os.WriteFile(path, []byte("[{\"id\":"), 0o644)
+
+	// This write starts from the recovered backup and must keep it intact.
+	if _, err := repo.Add("c"); err != nil {
+		t.Fatalf("add after corruption failed: %v", err)
+	}
+	data, _ := os.ReadFile(path + ".bak")
+	var backup []Task
+	if err := json.Unmarshal(data, &backup); err != nil || len(backup) != 1 {
+		t.Fatalf("good backup was replaced: %q", data)
+	}
+	items, _ := repo.List()
+	if len(items) != 2 || items[1].Title != "c" {
+		t.Fatalf("unexpected items after recovery write: %+v", items)
+	}
+}
+
+func TestLesson6CorruptWithoutBackupStillErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	_ = os.WriteFile(path, []byte("{bad"), 0o644)
+	if _, err := NewJSONFileTaskRepo(path).List(); err == nil {
+		t.Fatalf("expected json parse error")
+	}
+}
+
+func TestLesson7ConcurrentGoroutinesDoNotLoseUpdates(t *testing.T) {
+	repo := NewJSONFileTaskRepo(filepath.Join(t.TempDir(), "tasks.json"))
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = repo.Add(fmt.Sprintf("task-%d", i))
+		}()
+	}
+	wg.Wait()
+	items, _ := repo.List()
+	if len(uniqueIDs(t, items)) != 20 {
+		t.Fatalf("want 20 tasks, got %d", len(items))
+	}
+}
+
+func TestLesson8SeparateRepoInstancesShareTheFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A new instance has its own mutex, so only the lock file protects it.
+			_, _ = NewJSONFileTaskRepo(path).Add(fmt.Sprintf("task-%d", i))
+		}()
+	}
+	wg.Wait()
+	items, _ := NewJSONFileTaskRepo(path).List()
+	if len(uniqueIDs(t, items)) != 10 {
+		t.Fatalf("want 10 tasks, got %d", len(items))
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("want the lock file removed after the last write, got %v", err)
+	}
+
+	// Reads never wait: a lock held by a live process (this one) does not
+	// block List.
+	_ = os.WriteFile(path+".lock", []byte(strconv.Itoa(os.Getpid())), 0o644)
+	defer os.Remove(path + ".lock")
+	if items, err := NewJSONFileTaskRepo(path).List(); err != nil || len(items) != 10 {
+		t.Fatalf("list while locked: %d items, %v", len(items), err)
+	}
+}
+
+// TestHelperProcessAdd is not a lesson; TestLesson9 re-runs the test binary
+// with this test selected to get a genuinely separate writer process.
+func TestHelperProcessAdd(t *testing.T) {
+	path := os.Getenv("LESSON_TASKS_FILE")
+	if path == "" {
+		t.Skip("helper process only")
+	}
+	repo := NewJSONFileTaskRepo(path)
+	for i := range 15 {
+		if _, err := repo.Add(fmt.Sprintf("pid-%d-%d", os.Getpid(), i)); err != nil {
+			t.Fatalf("helper add failed: %v", err)
+		}
+	}
+}
+
+func TestLesson9SeparateProcessesDoNotLoseUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	cmds := []*exec.Cmd{}
+	for range 2 {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcessAdd$")
+		cmd.Env = append(os.Environ(), "LESSON_TASKS_FILE="+path)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("start helper: %v", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("helper failed: %v", err)
+		}
+	}
+	items, err := NewJSONFileTaskRepo(path).List()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(uniqueIDs(t, items)) != 30 {
+		t.Fatalf("want 30 tasks from two processes, got %d", len(items))
+	}
+
+	// A helper that had died holding the lock leaves its PID behind; the
+	// next writer takes over instead of waiting out lockTimeout.
+	dead := cmds[0].Process.Pid
+	_ = os.WriteFile(path+".lock", []byte(strconv.Itoa(dead)), 0o644)
+	start := time.Now()
+	if _, err := NewJSONFileTaskRepo(path).Add("after crash"); err != nil {
+		t.Fatalf("add over a stale lock: %v", err)
+	}
+	if waited := time.Since(start); waited > lockTimeout/2 {
+		t.Fatalf("stale lock took %s to break", waited)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("want the lock file removed after the write, got %v", err)
+	}
+}
+
+func TestLesson10Completion(t *testing.T) {
+	if false {
+		t.Fatalf("unreachable")
+	}
+}
+
+// End of Go File Durability Tests 1-10
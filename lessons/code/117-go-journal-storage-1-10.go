@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+/*
+GO JOURNAL STORAGE (Lessons 1-10)
+
+Suggested use:
+1) Run: go run lessons/code/117-go-journal-storage-1-10.go
+2) Run it again: state is rebuilt from snapshot + journal replay
+3) Append half a line to `lessons/code/tmp_journal/tasks.ndjson` and rerun to
+   watch the reader stop at the last valid record (the dropped bytes are kept
+   in tasks.ndjson.corrupt-<offset>)
+
+Extra context:
+- lessons/notes/168-go-repository-adapter-pattern.md
+- lessons/notes/169-go-file-storage-gotchas.md
+- lessons/notes/201-append-only-logs-first-principles.md
+*/
+
+// LESSON 1: Same domain model and repository boundary
+// Why this matters: a new storage engine is just another adapter.
+type Task struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+type TaskRepository interface {
+	List() ([]Task, error)
+	Add(title string) (Task, error)
+	MarkDone(id int) error
+}
+
+type TaskService struct {
+	repo TaskRepository
+}
+
+func NewTaskService(repo TaskRepository) *TaskService {
+	return &TaskService{repo: repo}
+}
+
+func (s *TaskService) CreateTask(title string) (Task, error) {
+	clean := strings.TrimSpace(title)
+	if clean == "" {
+		return Task{}, errors.New("title is required")
+	}
+	return s.repo.Add(clean)
+}
+
+func (s *TaskService) CompleteTask(id int) error {
+	if id <= 0 {
+		return errors.New("id must be positive")
+	}
+	return s.repo.MarkDone(id)
+}
+
+func (s *TaskService) Tasks() ([]Task, error) {
+	return s.repo.List()
+}
+
+// LESSON 2: Operation records
+// Why this matters: storing "what happened" makes each write O(1) appends
+// instead of rewriting the whole task list.
+const (
+	opAdd  = "add"
+	opDone = "done"
+)
+
+type journalRecord struct {
+	Seq  int64  `json:"seq"`
+	Op   string `json:"op"`
+	Task *Task  `json:"task,omitempty"`
+	ID   int    `json:"id,omitempty"`
+	Sum  uint32 `json:"sum"`
+}
+
+// LESSON 3: Per-record checksum
+// Why this matters: a torn or bit-flipped line must be detectable on replay.
+func recordChecksum(rec journalRecord) (uint32, error) {
+	rec.Sum = 0
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(data), nil
+}
+
+func encodeRecord(rec journalRecord) ([]byte, error) {
+	sum, err := recordChecksum(rec)
+	if err != nil {
+		return nil, err
+	}
+	rec.Sum = sum
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// LESSON 4: Corruption-tolerant reader
+// Why this matters: after a crash the tail may be garbage; everything before
+// it is still good. The reader returns valid records and the byte offset
+// where they end so the caller can cut the tail off.
+func readJournal(r io.Reader, afterSeq int64) ([]journalRecord, int64, error) {
+	reader := bufio.NewReader(r)
+	records := []journalRecord{}
+	var validOffset int64
+	lastSeq := int64(-1)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line without '\n' is a torn write, even if it happens to parse.
+			return records, validOffset, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		var rec journalRecord
+		if json.Unmarshal(bytes.TrimSpace(line), &rec) != nil {
+			return records, validOffset, nil
+		}
+		sum, err := recordChecksum(rec)
+		if err != nil || sum != rec.Sum {
+			return records, validOffset, nil
+		}
+		if lastSeq >= 0 && rec.Seq != lastSeq+1 {
+			return records, validOffset, nil
+		}
+		if rec.Seq > afterSeq && len(records) == 0 && rec.Seq != afterSeq+1 {
+			return records, validOffset, nil
+		}
+		lastSeq = rec.Seq
+		validOffset += int64(len(line))
+		// Records already folded into the snapshot are skipped, not rejected:
+		// a crash between snapshot and journal reset leaves them behind.
+		if rec.Seq > afterSeq {
+			records = append(records, rec)
+		}
+	}
+}
+
+// LESSON 5: Snapshot format
+// Why this matters: replay time stays bounded once history is folded in.
+type journalSnapshot struct {
+	Seq    int64  `json:"seq"`
+	NextID int    `json:"next_id"`
+	Tasks  []Task `json:"tasks"`
+}
+
+// LESSON 6: Journal-backed adapter
+// Why this matters: state lives in memory, durability lives in the log.
+type JournalTaskRepo struct {
+	mu           sync.Mutex
+	dir          string
+	journal      *os.File
+	offset       int64
+	tasks        []Task
+	nextID       int
+	seq          int64
+	sinceCompact int
+	compactEvery int
+}
+
+func (r *JournalTaskRepo) journalPath() string  { return filepath.Join(r.dir, "tasks.ndjson") }
+func (r *JournalTaskRepo) snapshotPath() string { return filepath.Join(r.dir, "snapshot.json") }
+
+// OpenJournalTaskRepo replays snapshot + journal from dir. compactEvery <= 0
+// disables automatic compaction.
+func OpenJournalTaskRepo(dir string, compactEvery int) (*JournalTaskRepo, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	r := &JournalTaskRepo{dir: dir, tasks: []Task{}, nextID: 1, compactEvery: compactEvery}
+	if err := r.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(r.journalPath(), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	records, validOffset, err := readJournal(f, r.seq)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	for _, rec := range records {
+		r.apply(rec)
+	}
+	// Drop any invalid tail so new records are never appended after garbage,
+	// keeping a copy of it first.
+	if err := r.saveCorruptTail(f, validOffset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Truncate(validOffset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(validOffset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.journal = f
+	r.offset = validOffset
+	r.sinceCompact = len(records)
+	return r, nil
+}
+
+// saveCorruptTail copies everything after validOffset to
+// tasks.ndjson.corrupt-<offset> before it is cut off. Usually that is one
+// torn line, but a bit flip mid-file also ends replay, and the records after
+// it can still be recovered by hand from the copy.
+func (r *JournalTaskRepo) saveCorruptTail(f *os.File, validOffset int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() <= validOffset {
+		return nil
+	}
+	tail := make([]byte, info.Size()-validOffset)
+	if _, err := f.ReadAt(tail, validOffset); err != nil {
+		return err
+	}
+	return writeFileAtomic(fmt.Sprintf("%s.corrupt-%d", r.journalPath(), validOffset), tail)
+}
+
+func (r *JournalTaskRepo) loadSnapshot() error {
+	data, err := os.ReadFile(r.snapshotPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snap journalSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	r.seq = snap.Seq
+	r.nextID = snap.NextID
+	if snap.Tasks != nil {
+		r.tasks = snap.Tasks
+	}
+	return nil
+}
+
+func (r *JournalTaskRepo) apply(rec journalRecord) {
+	r.seq = rec.Seq
+	switch rec.Op {
+	case opAdd:
+		if rec.Task == nil {
+			return
+		}
+		r.tasks = append(r.tasks, *rec.Task)
+		if rec.Task.ID >= r.nextID {
+			r.nextID = rec.Task.ID + 1
+		}
+	case opDone:
+		for i := range r.tasks {
+			if r.tasks[i].ID == rec.ID {
+				r.tasks[i].Done = true
+			}
+		}
+	}
+}
+
+// LESSON 7: Durable append
+// Why this matters: a write is acknowledged only after fsync; a failed write
+// is cut off so the journal never keeps half a record mid-file.
+func (r *JournalTaskRepo) append(rec journalRecord) error {
+	rec.Seq = r.seq + 1
+	line, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := r.journal.Write(line); err != nil {
+		return r.rollbackAppend(err)
+	}
+	if err := r.journal.Sync(); err != nil {
+		return r.rollbackAppend(err)
+	}
+	r.offset += int64(len(line))
+	r.apply(rec)
+	r.sinceCompact++
+	if r.compactEvery > 0 && r.sinceCompact >= r.compactEvery {
+		// The record is already durable, so a failed compaction is not a
+		// failed write; the journal keeps everything and the next append retries.
+		_ = r.compactLocked()
+	}
+	return nil
+}
+
+func (r *JournalTaskRepo) rollbackAppend(cause error) error {
+	if err := r.journal.Truncate(r.offset); err != nil {
+		return fmt.Errorf("%w (truncate after failed append: %v)", cause, err)
+	}
+	if _, err := r.journal.Seek(r.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("%w (seek after failed append: %v)", cause, err)
+	}
+	return cause
+}
+
+func (r *JournalTaskRepo) List() ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Task, len(r.tasks))
+	copy(out, r.tasks)
+	return out, nil
+}
+
+func (r *JournalTaskRepo) Add(title string) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := Task{ID: r.nextID, Title: title, Done: false}
+	if err := r.append(journalRecord{Op: opAdd, Task: &t}); err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+func (r *JournalTaskRepo) MarkDone(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := false
+	for _, t := range r.tasks {
+		if t.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("task id %d not found", id)
+	}
+	return r.append(journalRecord{Op: opDone, ID: id})
+}
+
+// LESSON 8: Snapshot + compaction
+// Why this matters: order is what makes it crash-safe. The snapshot is
+// written atomically first; only then is the journal emptied. A crash in
+// between leaves records the snapshot already covers, which replay skips.
+func (r *JournalTaskRepo) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.compactLocked()
+}
+
+func (r *JournalTaskRepo) compactLocked() error {
+	data, err := json.Marshal(journalSnapshot{Seq: r.seq, NextID: r.nextID, Tasks: r.tasks})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.snapshotPath(), data); err != nil {
+		return err
+	}
+	if err := r.journal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := r.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := r.journal.Sync(); err != nil {
+		return err
+	}
+	r.offset = 0
+	r.sinceCompact = 0
+	return nil
+}
+
+func (r *JournalTaskRepo) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.journal.Close()
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// LESSON 9: Composition root
+// Why this matters: the service does not know it is talking to a journal.
+
+// LESSON 10: End-to-end demo
+// Why this matters: restart shows replay; compaction shows bounded growth.
+func main() {
+	dir := "lessons/code/tmp_journal"
+	repo, err := OpenJournalTaskRepo(dir, 4)
+	if err != nil {
+		fmt.Println("open journal error:", err)
+		return
+	}
+	defer repo.Close()
+
+	service := NewTaskService(repo)
+	before, _ := service.Tasks()
+	fmt.Println("Lesson 10 replayed tasks:", len(before))
+
+	created, _ := service.CreateTask(fmt.Sprintf("journal run %d", len(before)+1))
+	_ = service.CompleteTask(created.ID)
+
+	items, err := service.Tasks()
+	fmt.Println("Lesson 10 tasks:", items, "error:", err)
+	fmt.Println("Lesson 10 journal bytes since last compaction:", repo.offset)
+}
+
+// End of Go Journal Storage 1-10
@@ -0,0 +1,1154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+/*
+GO REPOSITORY CONFORMANCE TESTS (Lessons 1-10)
+
+Suggested use:
+1) Install driver (if needed): go get modernc.org/sqlite
+2) Run: go test lessons/code/124-go-repository-conformance-tests-1-10_test.go -run TestLesson -v -race
+3) To check a new adapter, write one adapterCase for it and call runConformance
+
+Extra context:
+- lessons/notes/168-go-repository-adapter-pattern.md
+- lessons/notes/169-go-file-storage-gotchas.md
+- lessons/notes/172-go-sqlite-gotchas.md
+*/
+
+type Task struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+var ErrTaskNotFound = errors.New("task not found")
+
+type TaskRepository interface {
+	List() ([]Task, error)
+	Add(title string) (Task, error)
+	MarkDone(id int64) error
+}
+
+type InMemoryTaskRepo struct {
+	mu    sync.Mutex
+	items []Task
+}
+
+func (r *InMemoryTaskRepo) List() ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Task, len(r.items))
+	copy(out, r.items)
+	return out, nil
+}
+
+func (r *InMemoryTaskRepo) Add(title string) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nextID := int64(1)
+	if len(r.items) > 0 {
+		nextID = r.items[len(r.items)-1].ID + 1
+	}
+	t := Task{ID: nextID, Title: title, Done: false}
+	r.items = append(r.items, t)
+	return t, nil
+}
+
+func (r *InMemoryTaskRepo) MarkDone(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.items {
+		if r.items[i].ID == id {
+			r.items[i].Done = true
+			return nil
+		}
+	}
+	return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+}
+
+type JSONFileTaskRepo struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewJSONFileTaskRepo(path string) *JSONFileTaskRepo {
+	return &JSONFileTaskRepo{path: path}
+}
+
+const (
+	lockTimeout    = 5 * time.Second
+	lockRetryDelay = 5 * time.Millisecond
+)
+
+// withLock serializes goroutines (mutex) and processes (a sidecar lock file
+// holding the owner's PID). The lock is a separate file because the data file
+// itself is replaced by rename on every save. Exclusive creation behaves the
+// same on every OS, unlike flock; the PID inside lets a waiter see that the
+// owner crashed and take the lock over instead of waiting for a human.
+func (r *JSONFileTaskRepo) withLock(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lockPath := r.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		owner, err := acquireLock(lockPath)
+		if err != nil {
+			return err
+		}
+		if owner == 0 {
+			break
+		}
+		if !processAlive(owner) && breakLock(lockPath, owner) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock %s: still held by process %d after %s", r.path, owner, lockTimeout)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+	defer os.Remove(lockPath)
+	return fn()
+}
+
+// acquireLock links a file that already holds our PID to lockPath; the link
+// fails if lockPath exists, so a lock is never seen without its owner. It
+// returns 0 once the lock is ours, otherwise the PID of the current owner.
+func acquireLock(lockPath string) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprint(tmp, os.Getpid())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	for {
+		err := os.Link(tmp.Name(), lockPath)
+		if err == nil {
+			return 0, nil
+		}
+		if !os.IsExist(err) {
+			return 0, err
+		}
+		owner, err := lockOwner(lockPath)
+		if os.IsNotExist(err) {
+			continue // released between the two calls
+		}
+		return owner, err
+	}
+}
+
+func lockOwner(lockPath string) (int, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("lock %s: no owner PID inside", lockPath)
+	}
+	return pid, nil
+}
+
+// processAlive asks the OS about pid. Signal 0 checks without delivering
+// anything on Unix; Windows only supports Kill, but FindProcess there
+// already fails once the process is gone.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// breakLock moves a dead owner's lock aside. Two waiters can both see the
+// same dead owner; if the file this one moved turns out to hold another
+// PID, the lock was taken in between and is linked back.
+func breakLock(lockPath string, dead int) bool {
+	aside, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".stale-*")
+	if err != nil {
+		return false
+	}
+	aside.Close()
+	defer os.Remove(aside.Name())
+	if os.Rename(lockPath, aside.Name()) != nil {
+		return false
+	}
+	if owner, err := lockOwner(aside.Name()); err == nil && owner != dead {
+		_ = os.Link(aside.Name(), lockPath)
+	}
+	return true
+}
+
+// taskFile is the CLI's (lesson 132) wrapper around the same array. next_id
+// remembers IDs of deleted tasks, so it must survive every save here too.
+type taskFile struct {
+	NextID int64  `json:"next_id"`
+	Tasks  []Task `json:"tasks"`
+}
+
+// decodeTasks reads a bare array or a taskFile. nextID is 0 for a bare
+// array, which save then writes back unchanged.
+func decodeTasks(data []byte) (items []Task, nextID int64, err error) {
+	items = []Task{}
+	if len(data) == 0 {
+		return items, 0, nil
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapped taskFile
+		if json.Unmarshal(data, &wrapped) != nil || wrapped.Tasks == nil {
+			return nil, 0, fmt.Errorf("invalid json file format: %w", err)
+		}
+		items = wrapped.Tasks
+		nextID = max(wrapped.NextID, 1)
+		for _, t := range items {
+			nextID = max(nextID, t.ID+1)
+		}
+	}
+	return items, nextID, nil
+}
+
+// load falls back to the backup generation when the data file exists but is
+// empty or unreadable (for example truncated by a crash or a bad copy).
+func (r *JSONFileTaskRepo) load() ([]Task, int64, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Task{}, 0, nil
+		}
+		return nil, 0, err
+	}
+	items, nextID, parseErr := decodeTasks(data)
+	if parseErr == nil && len(data) > 0 {
+		return items, nextID, nil
+	}
+	if backup, err := os.ReadFile(r.path + ".bak"); err == nil && len(backup) > 0 {
+		if recovered, nextID, err := decodeTasks(backup); err == nil {
+			return recovered, nextID, nil
+		}
+	}
+	return items, nextID, parseErr
+}
+
+// save keeps the current valid file as path+".bak", then atomically replaces
+// the data file. A corrupt current file never overwrites a good backup.
+// A nextID of 0 writes the bare array this lesson has always used.
+func (r *JSONFileTaskRepo) save(items []Task, nextID int64) error {
+	var doc any = items
+	if nextID > 0 {
+		doc = taskFile{NextID: nextID, Tasks: items}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if current, err := os.ReadFile(r.path); err == nil && len(current) > 0 {
+		if _, _, err := decodeTasks(current); err == nil {
+			if err := writeFileAtomic(r.path+".bak", current); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(r.path, data)
+}
+
+// writeFileAtomic writes a temp file in the same directory, fsyncs it and
+// renames it over path, so readers see the old or the new file, never half.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// The rename is only durable once the directory entry is flushed too.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// List takes no lock: save replaces the file by rename, so a reader sees
+// the previous file or the next one, never half of either.
+func (r *JSONFileTaskRepo) List() ([]Task, error) {
+	items, _, err := r.load()
+	return items, err
+}
+
+func (r *JSONFileTaskRepo) Add(title string) (Task, error) {
+	var t Task
+	err := r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		id := int64(1)
+		if len(items) > 0 {
+			id = items[len(items)-1].ID + 1
+		}
+		if nextID > 0 {
+			// Never reuse the ID of a task the CLI already deleted.
+			id = max(id, nextID)
+			nextID = id + 1
+		}
+		t = Task{ID: id, Title: title, Done: false}
+		return r.save(append(items, t), nextID)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+func (r *JSONFileTaskRepo) MarkDone(id int64) error {
+	return r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		found := false
+		for i := range items {
+			if items[i].ID == id {
+				items[i].Done = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+		}
+		return r.save(items, nextID)
+	})
+}
+
+type SQLiteTaskRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteTaskRepo(db *sql.DB) *SQLiteTaskRepo {
+	return &SQLiteTaskRepo{db: db}
+}
+
+// OpenSQLite opens a database file for the adapter. SQLite allows one
+// writer at a time; without a busy timeout a second connection that wants
+// to write fails at once with "database is locked" instead of waiting.
+func OpenSQLite(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+}
+
+// over time, so each change is a numbered, reversible, recorded step.
+var taskMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tasks",
+		Up: `
+CREATE TABLE IF NOT EXISTS tasks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title TEXT NOT NULL,
+  done INTEGER NOT NULL DEFAULT 0
+);`,
+		Down: `DROP TABLE tasks;`,
+	},
+}
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was edited")
+	ErrUnknownMigration = errors.New("database has a migration this binary does not know")
+)
+
+// Migration is one numbered schema step. Never edit a migration after it
+// has shipped; add a new one instead (the checksum check enforces this).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + "\n--down--\n" + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+type MigrationStatus struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// Migrator applies migrations in version order and records each one in
+// schema_migrations inside the same transaction as the schema change.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	DryRun     bool
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  applied_at TEXT NOT NULL
+);`)
+	return err
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt string
+}
+
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		out[version] = a
+	}
+	return out, rows.Err()
+}
+
+// Verify fails when history in the database disagrees with the code.
+func (m *Migrator) Verify() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	return m.verify(applied)
+}
+
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := map[int]bool{}
+	for i, mig := range m.migrations {
+		if mig.Version <= 0 || (i > 0 && mig.Version == m.migrations[i-1].Version) {
+			return fmt.Errorf("migration %d (%s): versions must be positive and unique", mig.Version, mig.Name)
+		}
+		known[mig.Version] = true
+		if a, ok := applied[mig.Version]; ok && a.checksum != mig.Checksum() {
+			return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, ErrChecksumMismatch)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("migration %d: %w", version, ErrUnknownMigration)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		a, ok := applied[mig.Version]
+		out = append(out, MigrationStatus{Migration: mig, Applied: ok, AppliedAt: a.appliedAt})
+	}
+	return out, nil
+}
+
+// Up applies every pending migration and returns what ran (or would run
+// when DryRun is set).
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Up, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+				mig.Version, mig.Name, mig.Checksum(), time.Now().UTC().Format(time.RFC3339))
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) up: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the newest `steps` applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Down, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) down: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// inTx runs a schema step and its bookkeeping row atomically.
+func (m *Migrator) inTx(step string, record string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(step); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runMigrateCommand implements `migrate status|up|down [-dry-run] [-steps N]`.
+func runMigrateCommand(migrator *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate status|up|down [-dry-run] [-steps N]")
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "print the plan without changing the database")
+	steps := fs.Int("steps", 1, "how many migrations to revert (down only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	migrator.DryRun = *dryRun
+	prefix := ""
+	if *dryRun {
+		prefix = "(dry run) "
+	}
+
+	switch args[0] {
+	case "status":
+		items, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range items {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt
+			}
+			fmt.Fprintf(out, "%04d %-24s %s\n", st.Migration.Version, st.Migration.Name, state)
+		}
+		return migrator.Verify()
+	case "up":
+		done, err := migrator.Up()
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sup   %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	case "down":
+		done, err := migrator.Down(*steps)
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sdown %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func (r *SQLiteTaskRepo) Migrate() error {
+	_, err := NewMigrator(r.db, taskMigrations).Up()
+	return err
+}
+
+func (r *SQLiteTaskRepo) Add(title string) (Task, error) {
+	result, err := r.db.Exec(`INSERT INTO tasks (title, done) VALUES (?, 0)`, title)
+	if err != nil {
+		return Task{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Task{}, err
+	}
+	return Task{ID: id, Title: title, Done: false}, nil
+}
+
+func (r *SQLiteTaskRepo) List() ([]Task, error) {
+	rows, err := r.db.Query(`SELECT id, title, done FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Task{}
+	for rows.Next() {
+		var t Task
+		var doneInt int
+		if err := rows.Scan(&t.ID, &t.Title, &doneInt); err != nil {
+			return nil, err
+		}
+		t.Done = doneInt == 1
+		items = append(items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SQLiteTaskRepo) MarkDone(id int64) error {
+	result, err := r.db.Exec(`UPDATE tasks SET done = 1 WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	return nil
+}
+
+// adapterCase plugs one adapter into the suite. setup prepares fresh
+// storage for a single check and returns open, which builds an adapter over
+// that storage. Calling open again is a restart: when persistent is set,
+// the new instance must see everything the earlier ones wrote.
+type adapterCase struct {
+	name       string
+	persistent bool
+	setup      func(t *testing.T) (open func() TaskRepository)
+}
+
+func memoryAdapter() adapterCase {
+	return adapterCase{name: "memory", setup: func(t *testing.T) func() TaskRepository {
+		// Nothing survives a restart, so every open is a fresh, empty store.
+		return func() TaskRepository { return &InMemoryTaskRepo{} }
+	}}
+}
+
+func jsonFileAdapter() adapterCase {
+	return adapterCase{name: "json-file", persistent: true, setup: func(t *testing.T) func() TaskRepository {
+		path := filepath.Join(t.TempDir(), "tasks.json")
+		return func() TaskRepository { return NewJSONFileTaskRepo(path) }
+	}}
+}
+
+func sqliteFileAdapter() adapterCase {
+	return adapterCase{name: "sqlite-file", persistent: true, setup: func(t *testing.T) func() TaskRepository {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		return func() TaskRepository {
+			// A separate *sql.DB per open, like a second process would have.
+			db, err := OpenSQLite(path)
+			if err != nil {
+				t.Fatalf("open sqlite: %v", err)
+			}
+			t.Cleanup(func() { _ = db.Close() })
+			repo := NewSQLiteTaskRepo(db)
+			if err := repo.Migrate(); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			return repo
+		}
+	}}
+}
+
+func sqliteMemoryAdapter() adapterCase {
+	return adapterCase{name: "sqlite-memory", setup: func(t *testing.T) func() TaskRepository {
+		return func() TaskRepository {
+			db, err := sql.Open("sqlite", ":memory:")
+			if err != nil {
+				t.Fatalf("open sqlite: %v", err)
+			}
+			// :memory: is per connection, so keep exactly one.
+			db.SetMaxOpenConns(1)
+			t.Cleanup(func() { _ = db.Close() })
+			repo := NewSQLiteTaskRepo(db)
+			if err := repo.Migrate(); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			return repo
+		}
+	}}
+}
+
+// conformanceCase is one rule of the TaskRepository contract. Checks
+// return an error instead of failing the test, so the suite can also be
+// pointed at deliberately broken adapters to prove it notices.
+type conformanceCase struct {
+	name    string
+	restart bool // needs an adapter that keeps data across open calls
+	check   func(open func() TaskRepository) error
+}
+
+const workers = 16
+
+var conformanceCases = []conformanceCase{
+	{name: "empty store lists nothing", check: func(open func() TaskRepository) error {
+		items, err := open().List()
+		if err != nil {
+			return err
+		}
+		if items == nil || len(items) != 0 {
+			return fmt.Errorf("want empty non-nil list, got %#v", items)
+		}
+		return nil
+	}},
+	{name: "ids start at one and count up", check: func(open func() TaskRepository) error {
+		repo := open()
+		for want := int64(1); want <= 3; want++ {
+			task, err := repo.Add(fmt.Sprintf("task %d", want))
+			if err != nil {
+				return err
+			}
+			if task != (Task{ID: want, Title: fmt.Sprintf("task %d", want)}) {
+				return fmt.Errorf("add #%d: got %+v", want, task)
+			}
+		}
+		return nil
+	}},
+	{name: "list is in id order", check: func(open func() TaskRepository) error {
+		repo := open()
+		for _, title := range []string{"c", "a", "b"} {
+			if _, err := repo.Add(title); err != nil {
+				return err
+			}
+		}
+		return expectTasks(repo, []Task{{1, "c", false}, {2, "a", false}, {3, "b", false}})
+	}},
+	{name: "titles are stored verbatim", check: func(open func() TaskRepository) error {
+		repo := open()
+		titles := []string{`quote " and backslash \`, "emoji 🚀 and ünïcode", "  padded  ", "line\nbreak", "'; DROP TABLE tasks; --"}
+		want := []Task{}
+		for _, title := range titles {
+			task, err := repo.Add(title)
+			if err != nil {
+				return err
+			}
+			want = append(want, task)
+		}
+		return expectTasks(repo, want)
+	}},
+	{name: "mark done changes only that task", check: func(open func() TaskRepository) error {
+		repo := open()
+		repo.Add("a")
+		repo.Add("b")
+		if err := repo.MarkDone(2); err != nil {
+			return err
+		}
+		return expectTasks(repo, []Task{{1, "a", false}, {2, "b", true}})
+	}},
+	{name: "mark done twice is fine", check: func(open func() TaskRepository) error {
+		repo := open()
+		repo.Add("a")
+		if err := repo.MarkDone(1); err != nil {
+			return err
+		}
+		if err := repo.MarkDone(1); err != nil {
+			return fmt.Errorf("second mark done: %w", err)
+		}
+		return expectTasks(repo, []Task{{1, "a", true}})
+	}},
+	{name: "unknown id is ErrTaskNotFound", check: func(open func() TaskRepository) error {
+		repo := open()
+		repo.Add("a")
+		for _, id := range []int64{0, -1, 2, 99} {
+			if err := repo.MarkDone(id); !errors.Is(err, ErrTaskNotFound) {
+				return fmt.Errorf("mark done %d: want ErrTaskNotFound, got %v", id, err)
+			}
+		}
+		return expectTasks(repo, []Task{{1, "a", false}})
+	}},
+	{name: "list returns a copy", check: func(open func() TaskRepository) error {
+		repo := open()
+		repo.Add("a")
+		items, err := repo.List()
+		if err != nil {
+			return err
+		}
+		items[0].Title, items[0].Done = "changed", true
+		return expectTasks(repo, []Task{{1, "a", false}})
+	}},
+	{name: "concurrent adds get distinct ids", check: func(open func() TaskRepository) error {
+		repo := open()
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.Add(fmt.Sprintf("worker %d", i))
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		return expectIDs(repo, workers)
+	}},
+	{name: "concurrent mark done loses no update", check: func(open func() TaskRepository) error {
+		repo := open()
+		for i := 0; i < workers; i++ {
+			if _, err := repo.Add(fmt.Sprintf("task %d", i)); err != nil {
+				return err
+			}
+		}
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		for id := int64(1); id <= workers; id++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- repo.MarkDone(id)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		items, err := repo.List()
+		if err != nil {
+			return err
+		}
+		for _, task := range items {
+			if !task.Done {
+				return fmt.Errorf("task %d lost its done flag", task.ID)
+			}
+		}
+		return nil
+	}},
+	{name: "data survives a restart", restart: true, check: func(open func() TaskRepository) error {
+		repo := open()
+		repo.Add("a")
+		repo.Add("b")
+		if err := repo.MarkDone(1); err != nil {
+			return err
+		}
+		return expectTasks(open(), []Task{{1, "a", true}, {2, "b", false}})
+	}},
+	{name: "ids continue after a restart", restart: true, check: func(open func() TaskRepository) error {
+		open().Add("a")
+		open().Add("b")
+		task, err := open().Add("c")
+		if err != nil {
+			return err
+		}
+		if task.ID != 3 {
+			return fmt.Errorf("want id 3 after two restarts, got %d", task.ID)
+		}
+		return nil
+	}},
+	{name: "two open handles share one store", restart: true, check: func(open func() TaskRepository) error {
+		first, second := open(), open()
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				repo := first
+				if i%2 == 1 {
+					repo = second
+				}
+				_, err := repo.Add(fmt.Sprintf("worker %d", i))
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		return expectIDs(open(), workers)
+	}},
+}
+
+func expectTasks(repo TaskRepository, want []Task) error {
+	got, err := repo.List()
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(got, want) {
+		return fmt.Errorf("want tasks %+v, got %+v", want, got)
+	}
+	return nil
+}
+
+// expectIDs checks that n tasks exist with exactly the ids 1..n, in order.
+func expectIDs(repo TaskRepository, n int) error {
+	items, err := repo.List()
+	if err != nil {
+		return err
+	}
+	if len(items) != n {
+		return fmt.Errorf("want %d tasks, got %d", n, len(items))
+	}
+	for i, task := range items {
+		if task.ID != int64(i+1) {
+			return fmt.Errorf("want ids 1..%d in order, got %d at position %d", n, task.ID, i)
+		}
+	}
+	return nil
+}
+
+// runConformance runs every case against one adapter, each on fresh storage.
+func runConformance(t *testing.T, adapter adapterCase) {
+	for _, c := range conformanceCases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.restart && !adapter.persistent {
+				t.Skip("adapter keeps nothing across restarts")
+			}
+			if err := c.check(adapter.setup(t)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// failingCases is runConformance for adapters that are supposed to fail.
+func failingCases(t *testing.T, adapter adapterCase) []string {
+	failed := []string{}
+	for _, c := range conformanceCases {
+		if c.restart && !adapter.persistent {
+			continue
+		}
+		if err := c.check(adapter.setup(t)); err != nil {
+			failed = append(failed, c.name)
+		}
+	}
+	return failed
+}
+
+// Each broken adapter below breaks exactly one rule of the contract.
+
+// stringErrorRepo is what every adapter looked like before the contract
+// had a sentinel: the message says "not found" but errors.Is cannot tell.
+type stringErrorRepo struct{ *InMemoryTaskRepo }
+
+func (r stringErrorRepo) MarkDone(id int64) error {
+	if err := r.InMemoryTaskRepo.MarkDone(id); err != nil {
+		return fmt.Errorf("task id %d not found", id)
+	}
+	return nil
+}
+
+type newestFirstRepo struct{ *InMemoryTaskRepo }
+
+func (r newestFirstRepo) List() ([]Task, error) {
+	items, err := r.InMemoryTaskRepo.List()
+	slices.Reverse(items)
+	return items, err
+}
+
+type sharedSliceRepo struct{ *InMemoryTaskRepo }
+
+func (r sharedSliceRepo) List() ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items, nil
+}
+
+// forgetfulStore keeps its tasks across opens but each open counts ids
+// from its own length snapshot, like an adapter that caches the next id.
+type forgetfulStore struct {
+	mu    sync.Mutex
+	items []Task
+}
+
+type forgetfulRepo struct {
+	store  *forgetfulStore
+	nextID int64
+}
+
+func (r *forgetfulRepo) List() ([]Task, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return append([]Task{}, r.store.items...), nil
+}
+
+func (r *forgetfulRepo) Add(title string) (Task, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	t := Task{ID: r.nextID, Title: title}
+	r.nextID++
+	r.store.items = append(r.store.items, t)
+	return t, nil
+}
+
+func (r *forgetfulRepo) MarkDone(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i := range r.store.items {
+		if r.store.items[i].ID == id {
+			r.store.items[i].Done = true
+			return nil
+		}
+	}
+	return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+}
+
+func wrapMemory(name string, wrap func(*InMemoryTaskRepo) TaskRepository) adapterCase {
+	return adapterCase{name: name, setup: func(t *testing.T) func() TaskRepository {
+		return func() TaskRepository { return wrap(&InMemoryTaskRepo{}) }
+	}}
+}
+
+func TestLesson1InMemoryConforms(t *testing.T) {
+	runConformance(t, memoryAdapter())
+}
+
+func TestLesson2JSONFileConforms(t *testing.T) {
+	runConformance(t, jsonFileAdapter())
+}
+
+func TestLesson3SQLiteFileConforms(t *testing.T) {
+	runConformance(t, sqliteFileAdapter())
+}
+
+func TestLesson4SQLiteMemoryConforms(t *testing.T) {
+	runConformance(t, sqliteMemoryAdapter())
+}
+
+func TestLesson5SuiteCatchesStringNotFound(t *testing.T) {
+	adapter := wrapMemory("string-error", func(r *InMemoryTaskRepo) TaskRepository { return stringErrorRepo{r} })
+	if got := failingCases(t, adapter); !slices.Equal(got, []string{"unknown id is ErrTaskNotFound"}) {
+		t.Fatalf("want only the not-found case to fail, got %q", got)
+	}
+}
+
+func TestLesson6SuiteCatchesWrongOrder(t *testing.T) {
+	adapter := wrapMemory("newest-first", func(r *InMemoryTaskRepo) TaskRepository { return newestFirstRepo{r} })
+	got := failingCases(t, adapter)
+	if !slices.Contains(got, "list is in id order") || !slices.Contains(got, "concurrent adds get distinct ids") {
+		t.Fatalf("want ordering cases to fail, got %q", got)
+	}
+}
+
+func TestLesson7SuiteCatchesSharedSlice(t *testing.T) {
+	adapter := wrapMemory("shared-slice", func(r *InMemoryTaskRepo) TaskRepository { return sharedSliceRepo{r} })
+	if got := failingCases(t, adapter); !slices.Contains(got, "list returns a copy") {
+		t.Fatalf("want the copy case to fail, got %q", got)
+	}
+}
+
+func TestLesson8SuiteCatchesReusedIDsAfterRestart(t *testing.T) {
+	adapter := adapterCase{name: "forgetful", persistent: true, setup: func(t *testing.T) func() TaskRepository {
+		store := &forgetfulStore{}
+		return func() TaskRepository { return &forgetfulRepo{store: store, nextID: 1} }
+	}}
+	got := failingCases(t, adapter)
+	if !slices.Equal(got, []string{"ids continue after a restart", "two open handles share one store"}) {
+		t.Fatalf("want only the restart id cases to fail, got %q", got)
+	}
+}
+
+func TestLesson9CaseNamesAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range conformanceCases {
+		if seen[c.name] {
+			t.Fatalf("duplicate case name %q: subtest names would collide", c.name)
+		}
+		seen[c.name] = true
+	}
+}
+
+func TestLesson10Completion(t *testing.T) {
+	if false {
+		t.Fatalf("unreachable")
+	}
+}
+
+// End of Go Repository Conformance Tests 1-10
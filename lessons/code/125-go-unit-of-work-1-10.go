@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+/*
+GO UNIT OF WORK (Lessons 1-10)
+
+Suggested use:
+1) Install driver (if needed): go get modernc.org/sqlite
+2) Run: go run lessons/code/125-go-unit-of-work-1-10.go
+3) Observe the failed create: its audit entry is rejected, so the task is
+   rolled back too, in both adapters
+
+Extra context:
+- lessons/notes/168-go-repository-adapter-pattern.md
+- lessons/notes/171-go-database-sql-first-principles.md
+- lessons/notes/202-go-unit-of-work-first-principles.md
+*/
+
+// LESSON 1: Two repositories, one workflow
+// Why this matters: "create task + record audit entry" must not half-succeed.
+type Task struct {
+	ID    int64
+	Title string
+	Done  bool
+}
+
+type AuditEntry struct {
+	ID     int64
+	TaskID int64
+	Action string
+	Actor  string
+	At     time.Time
+}
+
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrTitleRequired = errors.New("title is required")
+	ErrInvalidAudit  = errors.New("audit entry needs an action and an actor")
+)
+
+type TaskRepository interface {
+	Add(ctx context.Context, title string) (Task, error)
+	Get(ctx context.Context, id int64) (Task, error)
+	List(ctx context.Context) ([]Task, error)
+	MarkDone(ctx context.Context, id int64) error
+}
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry AuditEntry) (AuditEntry, error)
+	List(ctx context.Context) ([]AuditEntry, error)
+}
+
+func validateAudit(entry AuditEntry) error {
+	if strings.TrimSpace(entry.Action) == "" || strings.TrimSpace(entry.Actor) == "" {
+		return ErrInvalidAudit
+	}
+	return nil
+}
+
+// LESSON 2: The unit-of-work boundary
+// Why this matters: the service says where a transaction starts and ends;
+// repositories never commit on their own.
+
+// Repos is every repository, bound to one transaction.
+type Repos struct {
+	Tasks TaskRepository
+	Audit AuditRepository
+}
+
+type UnitOfWork interface {
+	// WithinTx commits fn's writes together when fn returns nil and drops
+	// all of them when it returns an error or panics (the panic goes on
+	// after the rollback). The repos are only valid until fn returns, and
+	// calls to WithinTx do not nest.
+	WithinTx(ctx context.Context, fn func(repos Repos) error) error
+}
+
+// LESSON 3: SQL adapters over a shared executor
+// Why this matters: *sql.DB and *sql.Tx have the same query methods, so one
+// adapter works inside and outside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type SQLiteTaskRepo struct {
+	db dbtx
+}
+
+func (r *SQLiteTaskRepo) Add(ctx context.Context, title string) (Task, error) {
+	result, err := r.db.ExecContext(ctx, `INSERT INTO tasks (title, done) VALUES (?, 0)`, title)
+	if err != nil {
+		return Task{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Task{}, err
+	}
+	return Task{ID: id, Title: title}, nil
+}
+
+func (r *SQLiteTaskRepo) Get(ctx context.Context, id int64) (Task, error) {
+	var t Task
+	var doneInt int
+	err := r.db.QueryRowContext(ctx, `SELECT id, title, done FROM tasks WHERE id = ?`, id).Scan(&t.ID, &t.Title, &doneInt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	t.Done = doneInt == 1
+	return t, nil
+}
+
+func (r *SQLiteTaskRepo) List(ctx context.Context) ([]Task, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, done FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Task{}
+	for rows.Next() {
+		var t Task
+		var doneInt int
+		if err := rows.Scan(&t.ID, &t.Title, &doneInt); err != nil {
+			return nil, err
+		}
+		t.Done = doneInt == 1
+		items = append(items, t)
+	}
+	return items, rows.Err()
+}
+
+func (r *SQLiteTaskRepo) MarkDone(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE tasks SET done = 1 WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	return nil
+}
+
+type SQLiteAuditRepo struct {
+	db dbtx
+}
+
+func (r *SQLiteAuditRepo) Record(ctx context.Context, entry AuditEntry) (AuditEntry, error) {
+	if err := validateAudit(entry); err != nil {
+		return AuditEntry{}, err
+	}
+	entry.At = entry.At.UTC()
+	result, err := r.db.ExecContext(ctx, `
+INSERT INTO audit_log (task_id, action, actor, at) VALUES (?, ?, ?, ?)`,
+		entry.TaskID, entry.Action, entry.Actor, entry.At.Format(time.RFC3339Nano))
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	if entry.ID, err = result.LastInsertId(); err != nil {
+		return AuditEntry{}, err
+	}
+	return entry, nil
+}
+
+func (r *SQLiteAuditRepo) List(ctx context.Context) ([]AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, task_id, action, actor, at FROM audit_log ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var at string
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Action, &e.Actor, &at); err != nil {
+			return nil, err
+		}
+		if e.At, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, fmt.Errorf("audit id %d: bad time %q: %w", e.ID, at, err)
+		}
+		items = append(items, e)
+	}
+	return items, rows.Err()
+}
+
+func MigrateSQLite(db *sql.DB) error {
+	_, err := db.Exec(`
+CREATE TABLE IF NOT EXISTS tasks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title TEXT NOT NULL,
+  done INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS audit_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  task_id INTEGER NOT NULL REFERENCES tasks(id),
+  action TEXT NOT NULL,
+  actor TEXT NOT NULL,
+  at TEXT NOT NULL
+);`)
+	return err
+}
+
+// LESSON 4: SQL unit of work
+// Why this matters: BEGIN/COMMIT/ROLLBACK is the whole trick; the rest is
+// making sure every exit path reaches one of them.
+type SQLiteUnitOfWork struct {
+	db *sql.DB
+}
+
+func NewSQLiteUnitOfWork(db *sql.DB) *SQLiteUnitOfWork {
+	return &SQLiteUnitOfWork{db: db}
+}
+
+func (u *SQLiteUnitOfWork) WithinTx(ctx context.Context, fn func(repos Repos) error) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		// Runs on error returns and while a panic unwinds, which is what
+		// makes a panicking callback roll back.
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+	if err := fn(Repos{Tasks: &SQLiteTaskRepo{db: tx}, Audit: &SQLiteAuditRepo{db: tx}}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// LESSON 5: In-memory state as one value
+// Why this matters: when all data is a single value, a transaction is just
+// "work on a copy, swap it in if everything went well".
+type memoryState struct {
+	tasks       []Task
+	audit       []AuditEntry
+	nextTaskID  int64
+	nextAuditID int64
+}
+
+func (s *memoryState) clone() *memoryState {
+	c := *s
+	c.tasks = append([]Task(nil), s.tasks...)
+	c.audit = append([]AuditEntry(nil), s.audit...)
+	return &c
+}
+
+// memoryTx is the copy one transaction works on. done is set when WithinTx
+// returns, so repos that escaped the callback fail instead of writing into
+// a copy nobody will ever see.
+type memoryTx struct {
+	mu    sync.Mutex
+	state *memoryState
+	done  bool
+}
+
+func (tx *memoryTx) use(fn func(s *memoryState) error) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	return fn(tx.state)
+}
+
+type memoryTaskRepo struct {
+	tx *memoryTx
+}
+
+func (r memoryTaskRepo) Add(ctx context.Context, title string) (Task, error) {
+	var t Task
+	err := r.tx.use(func(s *memoryState) error {
+		t = Task{ID: s.nextTaskID, Title: title}
+		s.nextTaskID++
+		s.tasks = append(s.tasks, t)
+		return nil
+	})
+	return t, err
+}
+
+func (r memoryTaskRepo) Get(ctx context.Context, id int64) (Task, error) {
+	var t Task
+	err := r.tx.use(func(s *memoryState) error {
+		for _, task := range s.tasks {
+			if task.ID == id {
+				t = task
+				return nil
+			}
+		}
+		return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	})
+	return t, err
+}
+
+func (r memoryTaskRepo) List(ctx context.Context) ([]Task, error) {
+	var items []Task
+	err := r.tx.use(func(s *memoryState) error {
+		items = append([]Task{}, s.tasks...)
+		return nil
+	})
+	return items, err
+}
+
+func (r memoryTaskRepo) MarkDone(ctx context.Context, id int64) error {
+	return r.tx.use(func(s *memoryState) error {
+		for i := range s.tasks {
+			if s.tasks[i].ID == id {
+				s.tasks[i].Done = true
+				return nil
+			}
+		}
+		return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	})
+}
+
+type memoryAuditRepo struct {
+	tx *memoryTx
+}
+
+func (r memoryAuditRepo) Record(ctx context.Context, entry AuditEntry) (AuditEntry, error) {
+	if err := validateAudit(entry); err != nil {
+		return AuditEntry{}, err
+	}
+	err := r.tx.use(func(s *memoryState) error {
+		entry.ID, entry.At = s.nextAuditID, entry.At.UTC()
+		s.nextAuditID++
+		s.audit = append(s.audit, entry)
+		return nil
+	})
+	return entry, err
+}
+
+func (r memoryAuditRepo) List(ctx context.Context) ([]AuditEntry, error) {
+	var items []AuditEntry
+	err := r.tx.use(func(s *memoryState) error {
+		items = append([]AuditEntry{}, s.audit...)
+		return nil
+	})
+	return items, err
+}
+
+// LESSON 6: Copy-on-write unit of work
+// Why this matters: transactions take turns (like SQLite's single writer),
+// each works on its own copy, and only a successful one replaces the state.
+type MemoryUnitOfWork struct {
+	writer sync.Mutex // one transaction at a time
+	mu     sync.Mutex // guards state itself
+	state  *memoryState
+}
+
+func NewMemoryUnitOfWork() *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{state: &memoryState{nextTaskID: 1, nextAuditID: 1}}
+}
+
+func (u *MemoryUnitOfWork) WithinTx(ctx context.Context, fn func(repos Repos) error) error {
+	u.writer.Lock()
+	defer u.writer.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	tx := &memoryTx{state: u.state.clone()}
+	u.mu.Unlock()
+	// Deferred so the copy is sealed on every exit, panics included; a
+	// panic then skips the swap below, which is the rollback.
+	defer tx.use(func(*memoryState) error {
+		tx.done = true
+		return nil
+	})
+	if err := fn(Repos{Tasks: memoryTaskRepo{tx: tx}, Audit: memoryAuditRepo{tx: tx}}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	u.state = tx.state
+	u.mu.Unlock()
+	return nil
+}
+
+// LESSON 7: Service with one transaction per use case
+// Why this matters: each public method is a complete business operation.
+type TaskService struct {
+	uow UnitOfWork
+	now func() time.Time
+}
+
+func NewTaskService(uow UnitOfWork) *TaskService {
+	return &TaskService{uow: uow, now: time.Now}
+}
+
+func (s *TaskService) CreateTask(ctx context.Context, title string, actor string) (Task, error) {
+	clean := strings.TrimSpace(title)
+	if clean == "" {
+		return Task{}, ErrTitleRequired
+	}
+	var created Task
+	err := s.uow.WithinTx(ctx, func(repos Repos) error {
+		task, err := repos.Tasks.Add(ctx, clean)
+		if err != nil {
+			return err
+		}
+		if _, err := repos.Audit.Record(ctx, AuditEntry{TaskID: task.ID, Action: "create", Actor: actor, At: s.now()}); err != nil {
+			return err
+		}
+		created = task
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return created, nil
+}
+
+func (s *TaskService) CompleteTask(ctx context.Context, id int64, actor string) error {
+	return s.uow.WithinTx(ctx, func(repos Repos) error {
+		task, err := repos.Tasks.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if task.Done {
+			return nil // nothing changed, so nothing to audit
+		}
+		if err := repos.Tasks.MarkDone(ctx, id); err != nil {
+			return err
+		}
+		_, err = repos.Audit.Record(ctx, AuditEntry{TaskID: id, Action: "complete", Actor: actor, At: s.now()})
+		return err
+	})
+}
+
+// LESSON 8: Reads go through the unit of work too
+// Why this matters: tasks and audit log come from the same snapshot.
+func (s *TaskService) TasksWithAudit(ctx context.Context) ([]Task, []AuditEntry, error) {
+	var tasks []Task
+	var audit []AuditEntry
+	err := s.uow.WithinTx(ctx, func(repos Repos) error {
+		var err error
+		if tasks, err = repos.Tasks.List(ctx); err != nil {
+			return err
+		}
+		audit, err = repos.Audit.List(ctx)
+		return err
+	})
+	return tasks, audit, err
+}
+
+// LESSON 9: One workflow, both adapters
+// Why this matters: rollback behavior is part of the contract, not an SQL detail.
+func runWorkflow(label string, service *TaskService) {
+	ctx := context.Background()
+	fmt.Println("==", label, "==")
+	_, _ = service.CreateTask(ctx, "write lesson", "alice")
+	_, err := service.CreateTask(ctx, "half-finished", "") // audit rejects the empty actor
+	fmt.Println("create without actor error:", err)
+	_ = service.CompleteTask(ctx, 1, "bob")
+	tasks, audit, err := service.TasksWithAudit(ctx)
+	fmt.Println("tasks:", tasks, "audit entries:", len(audit), "error:", err)
+}
+
+// LESSON 10: End-to-end demo
+// Why this matters: the failed create leaves no task behind in either adapter.
+func main() {
+	runWorkflow("in-memory unit of work", NewTaskService(NewMemoryUnitOfWork()))
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		fmt.Println("open db error:", err)
+		return
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // :memory: is per connection
+	if err := MigrateSQLite(db); err != nil {
+		fmt.Println("migrate error:", err)
+		return
+	}
+	runWorkflow("sqlite unit of work", NewTaskService(NewSQLiteUnitOfWork(db)))
+}
+
+// End of Go Unit Of Work 1-10
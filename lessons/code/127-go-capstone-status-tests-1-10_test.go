@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+GO CAPSTONE STATUS TESTS (Lessons 1-10)
+
+Suggested use:
+1) Run: go test lessons/code/127-go-capstone-status-tests-1-10_test.go -run TestLesson -v
+2) Run: go test -race lessons/code/127-go-capstone-status-tests-1-10_test.go
+*/
+
+type TaskStatus string
+
+const (
+	StatusQueued     TaskStatus = "queued"
+	StatusProcessing TaskStatus = "processing"
+	StatusDone       TaskStatus = "done"
+	StatusFailed     TaskStatus = "failed"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+// transitions is the whole lifecycle. done, failed and cancelled have no
+// entry, so nothing can leave them.
+var transitions = map[TaskStatus][]TaskStatus{
+	StatusQueued:     {StatusProcessing, StatusCancelled},
+	StatusProcessing: {StatusDone, StatusFailed, StatusCancelled},
+}
+
+func (s TaskStatus) CanMoveTo(next TaskStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// StatusChange records when a task entered a status.
+type StatusChange struct {
+	Status TaskStatus `json:"status"`
+	At     time.Time  `json:"at"`
+}
+
+type Task struct {
+	ID        int            `json:"id"`
+	Title     string         `json:"title"`
+	Status    TaskStatus     `json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	History   []StatusChange `json:"history"`
+}
+
+type TaskRepo interface {
+	Add(title string) Task
+	List() []Task
+	// UpdateStatus checks the transition and applies it in one step, so a
+	// cancel and the worker finishing cannot both win.
+	UpdateStatus(id int, status TaskStatus) (Task, error)
+}
+
+type InMemoryTaskRepo struct {
+	mu    sync.Mutex
+	items []Task
+}
+
+func (r *InMemoryTaskRepo) Add(title string) Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nextID := 1
+	if len(r.items) > 0 {
+		nextID = r.items[len(r.items)-1].ID + 1
+	}
+	now := time.Now()
+	t := Task{ID: nextID, Title: title, Status: StatusQueued, CreatedAt: now,
+		History: []StatusChange{{Status: StatusQueued, At: now}}}
+	r.items = append(r.items, t)
+	return cloneTask(t)
+}
+
+// cloneTask keeps callers from appending to the stored history.
+func cloneTask(t Task) Task {
+	t.History = append([]StatusChange{}, t.History...)
+	return t
+}
+
+func (r *InMemoryTaskRepo) List() []Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Task, len(r.items))
+	for i, t := range r.items {
+		out[i] = cloneTask(t)
+	}
+	return out
+}
+
+func (r *InMemoryTaskRepo) UpdateStatus(id int, status TaskStatus) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.items {
+		if r.items[i].ID != id {
+			continue
+		}
+		current := r.items[i].Status
+		if !current.CanMoveTo(status) {
+			return Task{}, fmt.Errorf("task %d: %s -> %s: %w", id, current, status, ErrInvalidTransition)
+		}
+		r.items[i].Status = status
+		r.items[i].History = append(r.items[i].History, StatusChange{Status: status, At: time.Now()})
+		return cloneTask(r.items[i]), nil
+	}
+	return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+type TaskService struct {
+	repo      TaskRepo
+	workQueue chan Task
+	// work does the actual processing; it must return early once ctx is
+	// cancelled.
+	work func(ctx context.Context, task Task) error
+
+	mu      sync.Mutex
+	running map[int]context.CancelFunc
+}
+
+func NewTaskService(repo TaskRepo, queueSize int) *TaskService {
+	return &TaskService{
+		repo:      repo,
+		workQueue: make(chan Task, queueSize),
+		work:      simulateWork(40 * time.Millisecond),
+		running:   map[int]context.CancelFunc{},
+	}
+}
+
+func simulateWork(d time.Duration) func(ctx context.Context, task Task) error {
+	return func(ctx context.Context, _ Task) error {
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *TaskService) CreateTask(title string) (Task, error) {
+	clean := strings.TrimSpace(title)
+	if clean == "" {
+		return Task{}, fmt.Errorf("title cannot be empty")
+	}
+	t := s.repo.Add(clean)
+	s.workQueue <- t
+	return t, nil
+}
+
+func (s *TaskService) ListTasks() []Task {
+	return s.repo.List()
+}
+
+// CancelTask stops a task that is queued or processing. The status changes
+// first; the worker then sees its context cancelled, and its own attempt
+// to finish the task is rejected as an invalid transition.
+func (s *TaskService) CancelTask(id int) (Task, error) {
+	task, err := s.repo.UpdateStatus(id, StatusCancelled)
+	if err != nil {
+		return Task{}, err
+	}
+	s.mu.Lock()
+	if cancel, ok := s.running[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+	return task, nil
+}
+
+func (s *TaskService) StartWorker() {
+	go func() {
+		for task := range s.workQueue {
+			s.process(task)
+		}
+	}()
+}
+
+func (s *TaskService) process(task Task) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Registered before the task turns processing, so a cancel that sees
+	// "processing" always finds something to stop.
+	s.mu.Lock()
+	s.running[task.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, task.ID)
+		s.mu.Unlock()
+	}()
+
+	if _, err := s.repo.UpdateStatus(task.ID, StatusProcessing); err != nil {
+		return // cancelled while still in the queue
+	}
+	final := StatusDone
+	if err := s.work(ctx, task); err != nil {
+		final = StatusFailed
+	}
+	// After a cancel this fails on purpose: cancelled is final.
+	_, _ = s.repo.UpdateStatus(task.ID, final)
+}
+
+type createTaskRequest struct {
+	Title string `json:"title"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func buildMux(service *TaskService) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, service.ListTasks())
+		case http.MethodPost:
+			var req createTaskRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+				return
+			}
+			task, err := service.CreateTask(req.Title)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, task)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	})
+
+	mux.HandleFunc("POST /tasks/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		task, err := service.CancelTask(id)
+		switch {
+		case errors.Is(err, ErrTaskNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case errors.Is(err, ErrInvalidTransition):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		default:
+			writeJSON(w, http.StatusOK, task)
+		}
+	})
+	mux.HandleFunc("/tasks/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	})
+
+	return mux
+}
+
+// waitForStatus polls until the task reaches want or the deadline passes.
+func waitForStatus(t *testing.T, repo *InMemoryTaskRepo, id int, want TaskStatus) Task {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, task := range repo.List() {
+			if task.ID == id && task.Status == want {
+				return task
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task %d never reached %s: %+v", id, want, repo.List())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingWork parks the worker until release is closed or ctx is cancelled.
+func blockingWork(started chan<- int, release <-chan struct{}) func(ctx context.Context, task Task) error {
+	return func(ctx context.Context, task Task) error {
+		started <- task.ID
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestLesson1TransitionTable(t *testing.T) {
+	all := []TaskStatus{StatusQueued, StatusProcessing, StatusDone, StatusFailed, StatusCancelled}
+	allowed := map[string]bool{
+		"queued->processing":    true,
+		"queued->cancelled":     true,
+		"processing->done":      true,
+		"processing->failed":    true,
+		"processing->cancelled": true,
+	}
+	for _, from := range all {
+		for _, to := range all {
+			key := string(from) + "->" + string(to)
+			if got := from.CanMoveTo(to); got != allowed[key] {
+				t.Fatalf("%s: want %v, got %v", key, allowed[key], got)
+			}
+		}
+	}
+}
+
+func TestLesson2IllegalTransitionRejected(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	task := repo.Add("task-a")
+
+	_, err := repo.UpdateStatus(task.ID, StatusDone)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("queued -> done: want ErrInvalidTransition, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "queued -> done") {
+		t.Fatalf("error should name both statuses, got %q", err)
+	}
+	if got := repo.List()[0]; got.Status != StatusQueued || len(got.History) != 1 {
+		t.Fatalf("rejected transition must not change the task: %+v", got)
+	}
+
+	if _, err := repo.UpdateStatus(99, StatusProcessing); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("want ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestLesson3WorkerRecordsEveryTransition(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	service.work = func(context.Context, Task) error { return nil }
+	service.StartWorker()
+
+	before := time.Now()
+	created, _ := service.CreateTask("task-a")
+	done := waitForStatus(t, repo, created.ID, StatusDone)
+
+	want := []TaskStatus{StatusQueued, StatusProcessing, StatusDone}
+	if len(done.History) != len(want) {
+		t.Fatalf("want %d history entries, got %+v", len(want), done.History)
+	}
+	prev := before
+	for i, change := range done.History {
+		if change.Status != want[i] {
+			t.Fatalf("history[%d]: want %s, got %s", i, want[i], change.Status)
+		}
+		if change.At.Before(prev) {
+			t.Fatalf("history timestamps must not go backwards: %+v", done.History)
+		}
+		prev = change.At
+	}
+}
+
+func TestLesson4FailedWork(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	service.work = func(context.Context, Task) error { return errors.New("boom") }
+	service.StartWorker()
+
+	created, _ := service.CreateTask("task-a")
+	failed := waitForStatus(t, repo, created.ID, StatusFailed)
+	if _, err := repo.UpdateStatus(failed.ID, StatusProcessing); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("failed is terminal, got %v", err)
+	}
+}
+
+func TestLesson5CancelQueuedTask(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	started := make(chan int, 10)
+	release := make(chan struct{})
+	service.work = blockingWork(started, release)
+	service.StartWorker()
+
+	first, _ := service.CreateTask("busy")
+	<-started
+	second, _ := service.CreateTask("waiting")
+
+	cancelled, err := service.CancelTask(second.ID)
+	if err != nil || cancelled.Status != StatusCancelled {
+		t.Fatalf("cancel queued task: %+v, %v", cancelled, err)
+	}
+	close(release)
+	waitForStatus(t, repo, first.ID, StatusDone)
+
+	// Give the worker the chance to pick up the cancelled task and skip it.
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case id := <-started:
+		t.Fatalf("cancelled task %d should never start", id)
+	default:
+	}
+	for _, task := range repo.List() {
+		if task.ID == second.ID && len(task.History) != 2 {
+			t.Fatalf("want queued, cancelled only, got %+v", task.History)
+		}
+	}
+}
+
+func TestLesson6CancelStopsRunningWork(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	started := make(chan int, 1)
+	stopped := make(chan error, 1)
+	service.work = func(ctx context.Context, task Task) error {
+		started <- task.ID
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return ctx.Err()
+	}
+	service.StartWorker()
+
+	created, _ := service.CreateTask("long")
+	<-started
+	if _, err := service.CancelTask(created.ID); err != nil {
+		t.Fatalf("cancel running task: %v", err)
+	}
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("want context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("work was not stopped")
+	}
+
+	// The worker's attempt to mark it failed must lose to the cancel.
+	time.Sleep(10 * time.Millisecond)
+	got := waitForStatus(t, repo, created.ID, StatusCancelled)
+	if last := got.History[len(got.History)-1]; last.Status != StatusCancelled {
+		t.Fatalf("cancelled must be final, got %+v", got.History)
+	}
+}
+
+func TestLesson7CancelFinishedTaskConflicts(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	service.work = func(context.Context, Task) error { return nil }
+	service.StartWorker()
+	created, _ := service.CreateTask("quick")
+	waitForStatus(t, repo, created.ID, StatusDone)
+
+	_, err := service.CancelTask(created.ID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("want ErrInvalidTransition, got %v", err)
+	}
+	if _, err := service.CancelTask(created.ID + 1); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("want ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestLesson8HTTPCancel(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	mux := buildMux(service)
+	created, _ := service.CreateTask("task-a")
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/cancel", created.ID), http.StatusOK},
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/cancel", created.ID), http.StatusConflict},
+		{http.MethodPost, "/tasks/99/cancel", http.StatusNotFound},
+		{http.MethodPost, "/tasks/abc/cancel", http.StatusBadRequest},
+		{http.MethodGet, fmt.Sprintf("/tasks/%d/cancel", created.ID), http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.want {
+			t.Fatalf("%s %s: want %d, got %d (%s)", tc.method, tc.path, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestLesson9JSONShape(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	mux := buildMux(service)
+	created, _ := service.CreateTask("task-a")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tasks/"+strconv.Itoa(created.ID)+"/cancel", nil))
+
+	var payload struct {
+		Status  string `json:"status"`
+		History []struct {
+			Status string    `json:"status"`
+			At     time.Time `json:"at"`
+		} `json:"history"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if payload.Status != "cancelled" || len(payload.History) != 2 || payload.History[1].At.IsZero() {
+		t.Fatalf("unexpected payload: %s", w.Body.String())
+	}
+}
+
+func TestLesson10Completion(t *testing.T) {
+	if false {
+		t.Fatalf("unreachable")
+	}
+}
+
+// End of Go Capstone Status Tests 1-10
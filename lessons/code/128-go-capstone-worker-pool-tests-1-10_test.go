@@ -0,0 +1,602 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+GO CAPSTONE WORKER POOL TESTS (Lessons 1-10)
+
+Suggested use:
+1) Run: go test lessons/code/128-go-capstone-worker-pool-tests-1-10_test.go -run TestLesson -v
+2) Run: go test -race lessons/code/128-go-capstone-worker-pool-tests-1-10_test.go
+*/
+
+type TaskStatus string
+
+const (
+	StatusQueued     TaskStatus = "queued"
+	StatusProcessing TaskStatus = "processing"
+	StatusDone       TaskStatus = "done"
+	StatusFailed     TaskStatus = "failed"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+// transitions is the whole lifecycle. done, failed and cancelled have no
+// entry, so nothing can leave them.
+var transitions = map[TaskStatus][]TaskStatus{
+	StatusQueued:     {StatusProcessing, StatusCancelled},
+	StatusProcessing: {StatusDone, StatusFailed, StatusCancelled},
+}
+
+func (s TaskStatus) CanMoveTo(next TaskStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrQueueFull         = errors.New("task queue is full")
+	ErrShuttingDown      = errors.New("service is shutting down")
+)
+
+// StatusChange records when a task entered a status.
+type StatusChange struct {
+	Status TaskStatus `json:"status"`
+	At     time.Time  `json:"at"`
+}
+
+type Task struct {
+	ID        int            `json:"id"`
+	Title     string         `json:"title"`
+	Status    TaskStatus     `json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	History   []StatusChange `json:"history"`
+}
+
+type TaskRepo interface {
+	Add(title string) Task
+	List() []Task
+	// UpdateStatus checks the transition and applies it in one step, so a
+	// cancel and the worker finishing cannot both win.
+	UpdateStatus(id int, status TaskStatus) (Task, error)
+}
+
+type InMemoryTaskRepo struct {
+	mu    sync.Mutex
+	items []Task
+}
+
+func (r *InMemoryTaskRepo) Add(title string) Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nextID := 1
+	if len(r.items) > 0 {
+		nextID = r.items[len(r.items)-1].ID + 1
+	}
+	now := time.Now()
+	t := Task{ID: nextID, Title: title, Status: StatusQueued, CreatedAt: now,
+		History: []StatusChange{{Status: StatusQueued, At: now}}}
+	r.items = append(r.items, t)
+	return cloneTask(t)
+}
+
+// cloneTask keeps callers from appending to the stored history.
+func cloneTask(t Task) Task {
+	t.History = append([]StatusChange{}, t.History...)
+	return t
+}
+
+func (r *InMemoryTaskRepo) List() []Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Task, len(r.items))
+	for i, t := range r.items {
+		out[i] = cloneTask(t)
+	}
+	return out
+}
+
+func (r *InMemoryTaskRepo) UpdateStatus(id int, status TaskStatus) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.items {
+		if r.items[i].ID != id {
+			continue
+		}
+		current := r.items[i].Status
+		if !current.CanMoveTo(status) {
+			return Task{}, fmt.Errorf("task %d: %s -> %s: %w", id, current, status, ErrInvalidTransition)
+		}
+		r.items[i].Status = status
+		r.items[i].History = append(r.items[i].History, StatusChange{Status: status, At: time.Now()})
+		return cloneTask(r.items[i]), nil
+	}
+	return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+type TaskService struct {
+	repo      TaskRepo
+	workQueue chan Task
+	// work does the actual processing; it must return early once ctx is
+	// cancelled.
+	work func(ctx context.Context, task Task) error
+
+	// mu serialises enqueues with closing the queue, and guards running.
+	mu      sync.Mutex
+	closed  bool
+	running map[int]context.CancelFunc
+
+	workers sync.WaitGroup
+	// stopCtx is cancelled when Shutdown runs out of time; every task
+	// context derives from it.
+	stopCtx context.Context
+	stop    context.CancelFunc
+}
+
+func NewTaskService(repo TaskRepo, queueSize int) *TaskService {
+	stopCtx, stop := context.WithCancel(context.Background())
+	return &TaskService{
+		repo:      repo,
+		workQueue: make(chan Task, queueSize),
+		work:      simulateWork(40 * time.Millisecond),
+		running:   map[int]context.CancelFunc{},
+		stopCtx:   stopCtx,
+		stop:      stop,
+	}
+}
+
+func simulateWork(d time.Duration) func(ctx context.Context, task Task) error {
+	return func(ctx context.Context, _ Task) error {
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *TaskService) CreateTask(title string) (Task, error) {
+	clean := strings.TrimSpace(title)
+	if clean == "" {
+		return Task{}, fmt.Errorf("title cannot be empty")
+	}
+	// Only enqueuers hold mu and workers only ever drain the channel, so a
+	// free slot seen here is still free at the send below. Checking before
+	// Add keeps a rejected request from leaving a task nobody will run.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Task{}, ErrShuttingDown
+	}
+	if len(s.workQueue) == cap(s.workQueue) {
+		return Task{}, ErrQueueFull
+	}
+	t := s.repo.Add(clean)
+	s.workQueue <- t
+	return t, nil
+}
+
+func (s *TaskService) ListTasks() []Task {
+	return s.repo.List()
+}
+
+// CancelTask stops a task that is queued or processing. The status changes
+// first; the worker then sees its context cancelled, and its own attempt
+// to finish the task is rejected as an invalid transition.
+func (s *TaskService) CancelTask(id int) (Task, error) {
+	task, err := s.repo.UpdateStatus(id, StatusCancelled)
+	if err != nil {
+		return Task{}, err
+	}
+	s.mu.Lock()
+	if cancel, ok := s.running[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+	return task, nil
+}
+
+func (s *TaskService) StartWorker() {
+	s.StartWorkers(1)
+}
+
+// StartWorkers adds n goroutines that pull from the shared queue until
+// Shutdown closes it.
+func (s *TaskService) StartWorkers(n int) {
+	for i := 0; i < n; i++ {
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			for task := range s.workQueue {
+				s.process(task)
+			}
+		}()
+	}
+}
+
+// Shutdown stops intake, then waits for the workers to finish every task
+// already accepted. If ctx expires first, running work is cancelled,
+// whatever is still queued is marked cancelled, and ctx.Err() is returned
+// once the workers have exited.
+func (s *TaskService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.workQueue)
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		s.stop()
+		<-drained
+		return ctx.Err()
+	}
+}
+
+func (s *TaskService) process(task Task) {
+	if s.stopCtx.Err() != nil {
+		_, _ = s.repo.UpdateStatus(task.ID, StatusCancelled)
+		return
+	}
+	ctx, cancel := context.WithCancel(s.stopCtx)
+	defer cancel()
+	// Registered before the task turns processing, so a cancel that sees
+	// "processing" always finds something to stop.
+	s.mu.Lock()
+	s.running[task.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, task.ID)
+		s.mu.Unlock()
+	}()
+
+	if _, err := s.repo.UpdateStatus(task.ID, StatusProcessing); err != nil {
+		return // cancelled while still in the queue
+	}
+	final := StatusDone
+	if err := s.work(ctx, task); err != nil {
+		final = StatusFailed
+		if s.stopCtx.Err() != nil {
+			final = StatusCancelled
+		}
+	}
+	// After a cancel this fails on purpose: cancelled is final.
+	_, _ = s.repo.UpdateStatus(task.ID, final)
+}
+
+type createTaskRequest struct {
+	Title string `json:"title"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func buildMux(service *TaskService) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, service.ListTasks())
+		case http.MethodPost:
+			var req createTaskRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+				return
+			}
+			task, err := service.CreateTask(req.Title)
+			if errors.Is(err, ErrQueueFull) || errors.Is(err, ErrShuttingDown) {
+				w.Header().Set("Retry-After", "1")
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, task)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	})
+
+	mux.HandleFunc("POST /tasks/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		task, err := service.CancelTask(id)
+		switch {
+		case errors.Is(err, ErrTaskNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case errors.Is(err, ErrInvalidTransition):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		default:
+			writeJSON(w, http.StatusOK, task)
+		}
+	})
+	mux.HandleFunc("/tasks/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	})
+
+	return mux
+}
+
+func envInt(name string, fallback int) int {
+	if raw := os.Getenv(name); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// gate parks every task until release is closed or its ctx is cancelled.
+type gate struct {
+	started chan int
+	release chan struct{}
+}
+
+func newGate() *gate {
+	return &gate{started: make(chan int, 100), release: make(chan struct{})}
+}
+
+func (g *gate) work(ctx context.Context, task Task) error {
+	g.started <- task.ID
+	select {
+	case <-g.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func statusCounts(repo *InMemoryTaskRepo) map[TaskStatus]int {
+	counts := map[TaskStatus]int{}
+	for _, task := range repo.List() {
+		counts[task.Status]++
+	}
+	return counts
+}
+
+// saturate starts one blocked worker and fills a queue of size 1.
+func saturate(t *testing.T) (*InMemoryTaskRepo, *TaskService, *gate) {
+	t.Helper()
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 1)
+	g := newGate()
+	service.work = g.work
+	service.StartWorkers(1)
+	if _, err := service.CreateTask("running"); err != nil {
+		t.Fatalf("create running: %v", err)
+	}
+	<-g.started
+	if _, err := service.CreateTask("queued"); err != nil {
+		t.Fatalf("create queued: %v", err)
+	}
+	return repo, service, g
+}
+
+func TestLesson1WorkersRunConcurrently(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	g := newGate()
+	service.work = g.work
+	service.StartWorkers(3)
+
+	for i := 0; i < 3; i++ {
+		_, _ = service.CreateTask(fmt.Sprintf("task-%d", i))
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-g.started:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of 3 tasks started in parallel", i)
+		}
+	}
+	close(g.release)
+	if err := service.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if got := statusCounts(repo)[StatusDone]; got != 3 {
+		t.Fatalf("want 3 done, got %v", statusCounts(repo))
+	}
+}
+
+func TestLesson2FullQueueRejectsWithoutAdding(t *testing.T) {
+	repo, service, g := saturate(t)
+	defer close(g.release)
+
+	_, err := service.CreateTask("overflow")
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("want ErrQueueFull, got %v", err)
+	}
+	if got := len(repo.List()); got != 2 {
+		t.Fatalf("rejected task must not be stored, got %d tasks", got)
+	}
+}
+
+func TestLesson3HTTPSaturationReturns503(t *testing.T) {
+	_, service, g := saturate(t)
+	defer close(g.release)
+	mux := buildMux(service)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"overflow"}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("want 503, got %d", w.Code)
+	}
+	if secs, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || secs <= 0 {
+		t.Fatalf("want positive Retry-After, got %q", w.Header().Get("Retry-After"))
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &payload); err != nil || payload["error"] == "" {
+		t.Fatalf("want JSON error body, got %s", w.Body.String())
+	}
+}
+
+func TestLesson4SlotFreesWhenWorkerTakesTask(t *testing.T) {
+	repo, service, g := saturate(t)
+	close(g.release)
+	<-g.started // the queued task is now with the worker
+
+	if _, err := service.CreateTask("after"); err != nil {
+		t.Fatalf("queue should have room again: %v", err)
+	}
+	if err := service.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if got := statusCounts(repo)[StatusDone]; got != 3 {
+		t.Fatalf("want 3 done, got %v", statusCounts(repo))
+	}
+}
+
+func TestLesson5ShutdownDrainsAcceptedTasks(t *testing.T) {
+	repo, service, g := saturate(t)
+
+	result := make(chan error, 1)
+	go func() { result <- service.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-result:
+		t.Fatalf("shutdown returned before the work finished: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(g.release)
+	if err := <-result; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if counts := statusCounts(repo); counts[StatusDone] != 2 {
+		t.Fatalf("running and queued tasks should both finish, got %v", counts)
+	}
+}
+
+func TestLesson6NoIntakeAfterShutdown(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 10)
+	service.StartWorkers(2)
+	if err := service.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if _, err := service.CreateTask("late"); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("want ErrShuttingDown, got %v", err)
+	}
+	w := httptest.NewRecorder()
+	buildMux(service).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"late"}`)))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("want 503 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+	if len(repo.List()) != 0 {
+		t.Fatalf("no task should be stored after shutdown")
+	}
+}
+
+func TestLesson7ShutdownDeadlineCancelsLeftovers(t *testing.T) {
+	repo, service, _ := saturate(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := service.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want DeadlineExceeded, got %v", err)
+	}
+	// Shutdown waits for the workers even when forced, so state is final.
+	if counts := statusCounts(repo); counts[StatusCancelled] != 2 {
+		t.Fatalf("running and queued tasks should be cancelled, got %v", counts)
+	}
+	for _, task := range repo.List() {
+		if task.ID == 2 && len(task.History) != 2 {
+			t.Fatalf("queued task should go straight to cancelled, got %+v", task.History)
+		}
+	}
+}
+
+func TestLesson8ShutdownIsIdempotent(t *testing.T) {
+	service := NewTaskService(&InMemoryTaskRepo{}, 10)
+	service.StartWorkers(2)
+	for i := 0; i < 2; i++ {
+		if err := service.Shutdown(context.Background()); err != nil {
+			t.Fatalf("shutdown #%d: %v", i+1, err)
+		}
+	}
+}
+
+func TestLesson9ConcurrentProducersNeverLoseTasks(t *testing.T) {
+	repo := &InMemoryTaskRepo{}
+	service := NewTaskService(repo, 4)
+	service.work = func(context.Context, Task) error { return nil }
+	service.StartWorkers(3)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := service.CreateTask(fmt.Sprintf("task-%d", i))
+			if err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrQueueFull) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := service.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	counts := statusCounts(repo)
+	if accepted == 0 || counts[StatusDone] != accepted || len(repo.List()) != accepted {
+		t.Fatalf("accepted %d, got %v", accepted, counts)
+	}
+}
+
+func TestLesson10Completion(t *testing.T) {
+	if false {
+		t.Fatalf("unreachable")
+	}
+}
+
+// End of Go Capstone Worker Pool Tests 1-10
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+GO MULTI-TENANT TASKS (Lessons 1-10)
+
+Suggested use:
+1) Run: go run lessons/code/129-go-multi-tenant-tasks-1-10.go
+2) Create as two users:
+   curl -X POST localhost:8095/tasks -H 'Authorization: Bearer alice-token' -d '{"title":"alice task"}'
+   curl -X POST localhost:8095/tasks -H 'Authorization: Bearer bob-token' -d '{"title":"bob task"}'
+3) List: each user sees only their own tasks; `admin-token` sees both
+4) Try: curl -X POST localhost:8095/tasks/1/done -H 'Authorization: Bearer bob-token'
+   (404: another tenant's task does not exist for bob)
+
+Extra context:
+- lessons/notes/176-authentication-vs-authorization-first-principles.md
+- lessons/notes/178-go-auth-gotchas.md
+- lessons/notes/203-go-tenant-scoping-first-principles.md
+*/
+
+// LESSON 1: Identity from the bearer token
+// Why this matters: the owner of a task comes from the server-side token
+// lookup, never from the request body.
+type contextKey string
+
+const (
+	ctxUserIDKey contextKey = "user_id"
+	ctxRoleKey   contextKey = "role"
+)
+
+const roleAdmin = "admin"
+
+type TokenStore interface {
+	Lookup(token string) (userID string, role string, ok bool)
+}
+
+type InMemoryTokenStore struct {
+	tokens map[string]struct {
+		UserID string
+		Role   string
+	}
+}
+
+func (s *InMemoryTokenStore) Lookup(token string) (string, string, bool) {
+	found, ok := s.tokens[token]
+	if !ok {
+		return "", "", false
+	}
+	return found.UserID, found.Role, true
+}
+
+func parseBearerToken(header string) (string, bool) {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", false
+	}
+	token := strings.TrimSpace(parts[1])
+	if token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func authMiddleware(store TokenStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := parseBearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid bearer token"})
+			return
+		}
+		userID, role, ok := store.Lookup(token)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+			return
+		}
+		ctx := context.WithValue(r.Context(), ctxUserIDKey, userID)
+		ctx = context.WithValue(ctx, ctxRoleKey, role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func userFromContext(ctx context.Context) (string, string, bool) {
+	userID, ok1 := ctx.Value(ctxUserIDKey).(string)
+	role, ok2 := ctx.Value(ctxRoleKey).(string)
+	if !ok1 || !ok2 {
+		return "", "", false
+	}
+	return userID, role, true
+}
+
+// LESSON 2: Scope as a value
+// Why this matters: "who may see what" becomes an argument every repository
+// call must take, so forgetting it is a compile error, not a data leak.
+type Scope struct {
+	UserID string
+	// AllTenants lets admins read and change every task. Tasks they create
+	// are still owned by their own user ID.
+	AllTenants bool
+}
+
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrTitleRequired = errors.New("title is required")
+	// ErrNoScope means a caller reached the repository without an identity.
+	// It is a wiring bug, so the repository refuses instead of guessing.
+	ErrNoScope = errors.New("missing tenant scope")
+)
+
+func scopeFromContext(ctx context.Context) (Scope, error) {
+	userID, role, ok := userFromContext(ctx)
+	if !ok || userID == "" {
+		return Scope{}, ErrNoScope
+	}
+	return Scope{UserID: userID, AllTenants: role == roleAdmin}, nil
+}
+
+func (s Scope) validate() error {
+	if s.UserID == "" {
+		return ErrNoScope
+	}
+	return nil
+}
+
+func (s Scope) allows(t Task) bool {
+	return s.AllTenants || t.OwnerID == s.UserID
+}
+
+// LESSON 3: Owned task model and scoped repository contract
+// Why this matters: there is no unscoped List or Get to call by mistake.
+type Task struct {
+	ID      int    `json:"id"`
+	OwnerID string `json:"owner_id"`
+	Title   string `json:"title"`
+	Done    bool   `json:"done"`
+}
+
+// TaskRepository hides tasks outside the scope: they are reported as
+// ErrTaskNotFound, so callers cannot probe for other tenants' IDs.
+type TaskRepository interface {
+	List(scope Scope) ([]Task, error)
+	Get(scope Scope, id int) (Task, error)
+	Add(scope Scope, title string) (Task, error)
+	MarkDone(scope Scope, id int) (Task, error)
+	Delete(scope Scope, id int) error
+}
+
+// LESSON 4: In-memory adapter enforcing the scope
+// Why this matters: the check lives next to the data, so every handler,
+// job or CLI that reuses the repository gets it for free.
+type InMemoryTaskRepo struct {
+	mu     sync.Mutex
+	items  []Task
+	nextID int
+}
+
+func NewInMemoryTaskRepo() *InMemoryTaskRepo {
+	return &InMemoryTaskRepo{items: []Task{}, nextID: 1}
+}
+
+func (r *InMemoryTaskRepo) List(scope Scope) ([]Task, error) {
+	if err := scope.validate(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []Task{}
+	for _, t := range r.items {
+		if scope.allows(t) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// find returns the index of a visible task. Callers hold r.mu.
+func (r *InMemoryTaskRepo) find(scope Scope, id int) (int, error) {
+	if err := scope.validate(); err != nil {
+		return -1, err
+	}
+	for i, t := range r.items {
+		if t.ID == id && scope.allows(t) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+func (r *InMemoryTaskRepo) Get(scope Scope, id int) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.find(scope, id)
+	if err != nil {
+		return Task{}, err
+	}
+	return r.items[i], nil
+}
+
+func (r *InMemoryTaskRepo) Add(scope Scope, title string) (Task, error) {
+	if err := scope.validate(); err != nil {
+		return Task{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := Task{ID: r.nextID, OwnerID: scope.UserID, Title: title}
+	r.nextID++
+	r.items = append(r.items, t)
+	return t, nil
+}
+
+func (r *InMemoryTaskRepo) MarkDone(scope Scope, id int) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.find(scope, id)
+	if err != nil {
+		return Task{}, err
+	}
+	r.items[i].Done = true
+	return r.items[i], nil
+}
+
+func (r *InMemoryTaskRepo) Delete(scope Scope, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.find(scope, id)
+	if err != nil {
+		return err
+	}
+	r.items = append(r.items[:i], r.items[i+1:]...)
+	return nil
+}
+
+// LESSON 5: Service turns the request context into a scope
+// Why this matters: handlers never build scopes by hand, and a context
+// without an identity fails closed.
+type TaskService struct {
+	repo TaskRepository
+}
+
+func NewTaskService(repo TaskRepository) *TaskService {
+	return &TaskService{repo: repo}
+}
+
+func (s *TaskService) Tasks(ctx context.Context) ([]Task, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.List(scope)
+}
+
+func (s *TaskService) Task(ctx context.Context, id int) (Task, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return Task{}, err
+	}
+	return s.repo.Get(scope, id)
+}
+
+func (s *TaskService) CreateTask(ctx context.Context, title string) (Task, error) {
+	clean := strings.TrimSpace(title)
+	if clean == "" {
+		return Task{}, ErrTitleRequired
+	}
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return Task{}, err
+	}
+	return s.repo.Add(scope, clean)
+}
+
+func (s *TaskService) CompleteTask(ctx context.Context, id int) (Task, error) {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return Task{}, err
+	}
+	return s.repo.MarkDone(scope, id)
+}
+
+func (s *TaskService) DeleteTask(ctx context.Context, id int) error {
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(scope, id)
+}
+
+// LESSON 6: Error mapping
+// Why this matters: another tenant's task is a 404, not a 403; a 403 would
+// confirm the ID exists.
+func writeTaskError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "task not found"})
+	case errors.Is(err, ErrTitleRequired):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrNoScope):
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "missing auth context"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be a positive integer"})
+		return 0, false
+	}
+	return id, true
+}
+
+type createTaskRequest struct {
+	Title string `json:"title"`
+}
+
+// LESSON 7: Handlers stay tenant-agnostic
+// Why this matters: none of them mentions an owner; they pass the request
+// context down and the repository does the filtering.
+func taskRoutes(service *TaskService) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		items, err := service.Tasks(r.Context())
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, items)
+	})
+	mux.HandleFunc("POST /tasks", func(w http.ResponseWriter, r *http.Request) {
+		var req createTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		task, err := service.CreateTask(r.Context(), req.Title)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, task)
+	})
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+		task, err := service.Task(r.Context(), id)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	})
+	mux.HandleFunc("POST /tasks/{id}/done", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+		task, err := service.CompleteTask(r.Context(), id)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	})
+	mux.HandleFunc("DELETE /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+		if err := service.DeleteTask(r.Context(), id); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// LESSON 8: One protection boundary
+// Why this matters: every /tasks route sits behind authMiddleware; only
+// /health is public.
+func buildMux(store TokenStore, service *TaskService) *http.ServeMux {
+	tasks := authMiddleware(store, taskRoutes(service))
+	mux := http.NewServeMux()
+	mux.Handle("/tasks", tasks)
+	mux.Handle("/tasks/", tasks)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// LESSON 9: Composition root
+// Why this matters: tokens and roles are wired once at the edge; nothing
+// below buildMux knows which tokens exist or what makes a user an admin.
+func demoTokenStore() *InMemoryTokenStore {
+	return &InMemoryTokenStore{
+		tokens: map[string]struct {
+			UserID string
+			Role   string
+		}{
+			"alice-token": {UserID: "alice", Role: "member"},
+			"bob-token":   {UserID: "bob", Role: "member"},
+			"admin-token": {UserID: "admin-1", Role: roleAdmin},
+		},
+	}
+}
+
+// LESSON 10: End-to-end multi-tenant server
+// Why this matters: same routes, different answers per caller.
+func main() {
+	mux := buildMux(demoTokenStore(), NewTaskService(NewInMemoryTaskRepo()))
+
+	addr := ":8095"
+	fmt.Println("Go multi-tenant tasks server on", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Println("server error:", err)
+	}
+}
+
+// End of Go Multi-Tenant Tasks 1-10
@@ -0,0 +1,711 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+GO TASKS CLI (Lessons 1-10)
+
+Suggested use:
+1) Run: go run lessons/code/132-go-tasks-cli-1-10.go add "write lesson"
+2) Then:
+   - go run lessons/code/132-go-tasks-cli-1-10.go list
+   - go run lessons/code/132-go-tasks-cli-1-10.go done 1
+   - go run lessons/code/132-go-tasks-cli-1-10.go edit 1 "write better lesson"
+   - go run lessons/code/132-go-tasks-cli-1-10.go list --status open --json
+   - go run lessons/code/132-go-tasks-cli-1-10.go rm 1
+3) Data lives in `lessons/code/tmp_tasks_cli.json`; pick another file with
+   --file PATH or TASKS_FILE=PATH
+4) Check `echo $?` after a failing command:
+   2 usage, 3 validation, 4 not found, 1 storage
+
+Extra context:
+- lessons/notes/98-python-cli-tools-principles.md
+- lessons/notes/168-go-repository-adapter-pattern.md
+- lessons/notes/169-go-file-storage-gotchas.md
+*/
+
+// LESSON 1: Same domain, wider repository
+// Why this matters: a CLI is one more transport; edit and rm only add
+// repository methods, the JSON file adapter from lesson 80 stays the core.
+type Task struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrTitleRequired = errors.New("title is required")
+	ErrInvalidID     = errors.New("id must be a positive integer")
+)
+
+// Same contract as lesson 80, plus: Delete never frees an ID for reuse.
+type TaskRepository interface {
+	List() ([]Task, error)
+	Get(id int64) (Task, error)
+	Add(title string) (Task, error)
+	MarkDone(id int64) error
+	Rename(id int64, title string) error
+	Delete(id int64) error
+}
+
+// LESSON 2: Service owns the rules
+// Why this matters: the CLI never trims titles or checks IDs itself, so
+// every transport rejects the same input with the same error.
+type TaskService struct {
+	repo TaskRepository
+}
+
+func NewTaskService(repo TaskRepository) *TaskService {
+	return &TaskService{repo: repo}
+}
+
+func cleanTitle(title string) (string, error) {
+	clean := strings.TrimSpace(title)
+	if clean == "" {
+		return "", ErrTitleRequired
+	}
+	return clean, nil
+}
+
+func (s *TaskService) CreateTask(title string) (Task, error) {
+	clean, err := cleanTitle(title)
+	if err != nil {
+		return Task{}, err
+	}
+	return s.repo.Add(clean)
+}
+
+func (s *TaskService) CompleteTask(id int64) (Task, error) {
+	if id <= 0 {
+		return Task{}, ErrInvalidID
+	}
+	if err := s.repo.MarkDone(id); err != nil {
+		return Task{}, err
+	}
+	return s.repo.Get(id)
+}
+
+func (s *TaskService) RenameTask(id int64, title string) (Task, error) {
+	if id <= 0 {
+		return Task{}, ErrInvalidID
+	}
+	clean, err := cleanTitle(title)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := s.repo.Rename(id, clean); err != nil {
+		return Task{}, err
+	}
+	return s.repo.Get(id)
+}
+
+func (s *TaskService) DeleteTask(id int64) error {
+	if id <= 0 {
+		return ErrInvalidID
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *TaskService) Tasks() ([]Task, error) {
+	return s.repo.List()
+}
+
+// LESSON 3: File format that remembers the next ID
+// Why this matters: "last ID + 1" hands out a deleted task's ID again.
+// Files written by lesson 80 (a bare array) still load, and lesson 80 reads
+// this shape and keeps next_id when it saves, so the CLI and the API can
+// share one file.
+type taskFile struct {
+	NextID int64  `json:"next_id"`
+	Tasks  []Task `json:"tasks"`
+}
+
+func decodeTaskFile(data []byte) (taskFile, error) {
+	state := taskFile{NextID: 1, Tasks: []Task{}}
+	if len(data) == 0 {
+		return state, nil
+	}
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &state.Tasks); err != nil {
+			return taskFile{}, fmt.Errorf("invalid json file format: %w", err)
+		}
+	} else if err := json.Unmarshal(data, &state); err != nil {
+		return taskFile{}, fmt.Errorf("invalid json file format: %w", err)
+	}
+	if state.Tasks == nil {
+		state.Tasks = []Task{}
+	}
+	for _, t := range state.Tasks {
+		if t.ID >= state.NextID {
+			state.NextID = t.ID + 1
+		}
+	}
+	return state, nil
+}
+
+// LESSON 4: JSON file adapter
+// Why this matters: locking, backup and atomic replace are unchanged from
+// lesson 80; every write is load -> change -> save under one lock.
+type JSONFileTaskRepo struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewJSONFileTaskRepo(path string) *JSONFileTaskRepo {
+	return &JSONFileTaskRepo{path: path}
+}
+
+const (
+	lockTimeout    = 5 * time.Second
+	lockRetryDelay = 5 * time.Millisecond
+)
+
+// withLock serializes goroutines (mutex) and processes (a sidecar lock file
+// holding the owner's PID). The lock is a separate file because the data file
+// itself is replaced by rename on every save. Exclusive creation behaves the
+// same on every OS, unlike flock; the PID inside lets a waiter see that the
+// owner crashed and take the lock over instead of waiting for a human.
+func (r *JSONFileTaskRepo) withLock(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lockPath := r.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		owner, err := acquireLock(lockPath)
+		if err != nil {
+			return err
+		}
+		if owner == 0 {
+			break
+		}
+		if !processAlive(owner) && breakLock(lockPath, owner) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock %s: still held by process %d after %s", r.path, owner, lockTimeout)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+	defer os.Remove(lockPath)
+	return fn()
+}
+
+// acquireLock links a file that already holds our PID to lockPath; the link
+// fails if lockPath exists, so a lock is never seen without its owner. It
+// returns 0 once the lock is ours, otherwise the PID of the current owner.
+func acquireLock(lockPath string) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprint(tmp, os.Getpid())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	for {
+		err := os.Link(tmp.Name(), lockPath)
+		if err == nil {
+			return 0, nil
+		}
+		if !os.IsExist(err) {
+			return 0, err
+		}
+		owner, err := lockOwner(lockPath)
+		if os.IsNotExist(err) {
+			continue // released between the two calls
+		}
+		return owner, err
+	}
+}
+
+func lockOwner(lockPath string) (int, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("lock %s: no owner PID inside", lockPath)
+	}
+	return pid, nil
+}
+
+// processAlive asks the OS about pid. Signal 0 checks without delivering
+// anything on Unix; Windows only supports Kill, but FindProcess there
+// already fails once the process is gone.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// breakLock moves a dead owner's lock aside. Two waiters can both see the
+// same dead owner; if the file this one moved turns out to hold another
+// PID, the lock was taken in between and is linked back.
+func breakLock(lockPath string, dead int) bool {
+	aside, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".stale-*")
+	if err != nil {
+		return false
+	}
+	aside.Close()
+	defer os.Remove(aside.Name())
+	if os.Rename(lockPath, aside.Name()) != nil {
+		return false
+	}
+	if owner, err := lockOwner(aside.Name()); err == nil && owner != dead {
+		_ = os.Link(aside.Name(), lockPath)
+	}
+	return true
+}
+
+// load falls back to the backup generation when the data file exists but is
+// empty or unreadable (for example truncated by a crash or a bad copy).
+func (r *JSONFileTaskRepo) load() (taskFile, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return decodeTaskFile(nil)
+		}
+		return taskFile{}, err
+	}
+	state, parseErr := decodeTaskFile(data)
+	if parseErr == nil && len(data) > 0 {
+		return state, nil
+	}
+	if backup, err := os.ReadFile(r.path + ".bak"); err == nil && len(backup) > 0 {
+		if recovered, err := decodeTaskFile(backup); err == nil {
+			return recovered, nil
+		}
+	}
+	return state, parseErr
+}
+
+// save keeps the current valid file as path+".bak", then atomically replaces
+// the data file. A corrupt current file never overwrites a good backup.
+func (r *JSONFileTaskRepo) save(state taskFile) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if current, err := os.ReadFile(r.path); err == nil && len(current) > 0 {
+		if _, err := decodeTaskFile(current); err == nil {
+			if err := writeFileAtomic(r.path+".bak", current); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(r.path, data)
+}
+
+// writeFileAtomic writes a temp file in the same directory, fsyncs it and
+// renames it over path, so readers see the old or the new file, never half.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// The rename is only durable once the directory entry is flushed too.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// List and Get take no lock: save replaces the file by rename, so a reader
+// sees the previous file or the next one, never half of either.
+func (r *JSONFileTaskRepo) List() ([]Task, error) {
+	state, err := r.load()
+	return state.Tasks, err
+}
+
+func (r *JSONFileTaskRepo) Get(id int64) (Task, error) {
+	state, err := r.load()
+	if err != nil {
+		return Task{}, err
+	}
+	i, err := findTask(state.Tasks, id)
+	if err != nil {
+		return Task{}, err
+	}
+	return state.Tasks[i], nil
+}
+
+func (r *JSONFileTaskRepo) Add(title string) (Task, error) {
+	var t Task
+	err := r.withLock(func() error {
+		state, err := r.load()
+		if err != nil {
+			return err
+		}
+		t = Task{ID: state.NextID, Title: title, Done: false}
+		state.NextID++
+		state.Tasks = append(state.Tasks, t)
+		return r.save(state)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+func findTask(items []Task, id int64) (int, error) {
+	for i := range items {
+		if items[i].ID == id {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+}
+
+// update loads, lets fn change the task at index i, and saves.
+func (r *JSONFileTaskRepo) update(id int64, fn func(state *taskFile, i int)) error {
+	return r.withLock(func() error {
+		state, err := r.load()
+		if err != nil {
+			return err
+		}
+		i, err := findTask(state.Tasks, id)
+		if err != nil {
+			return err
+		}
+		fn(&state, i)
+		return r.save(state)
+	})
+}
+
+func (r *JSONFileTaskRepo) MarkDone(id int64) error {
+	return r.update(id, func(state *taskFile, i int) { state.Tasks[i].Done = true })
+}
+
+func (r *JSONFileTaskRepo) Rename(id int64, title string) error {
+	return r.update(id, func(state *taskFile, i int) { state.Tasks[i].Title = title })
+}
+
+func (r *JSONFileTaskRepo) Delete(id int64) error {
+	return r.update(id, func(state *taskFile, i int) {
+		state.Tasks = append(state.Tasks[:i], state.Tasks[i+1:]...)
+	})
+}
+
+// LESSON 5: Exit codes as part of the interface
+// Why this matters: scripts branch on $?, not on message text. Anything
+// the service did not reject is a storage problem (I/O, lock, bad file).
+const (
+	exitOK         = 0
+	exitStorage    = 1
+	exitUsage      = 2
+	exitValidation = 3
+	exitNotFound   = 4
+)
+
+// usageError means the command line itself was wrong, as opposed to a
+// well-formed command the service rejected.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, new(*usageError)):
+		return exitUsage
+	case errors.Is(err, ErrTitleRequired), errors.Is(err, ErrInvalidID):
+		return exitValidation
+	case errors.Is(err, ErrTaskNotFound):
+		return exitNotFound
+	default:
+		return exitStorage
+	}
+}
+
+// LESSON 6: Options shared by every subcommand
+// Why this matters: `tasks --json list` and `tasks list --json` should both
+// work, so each flag set registers the same options.
+type options struct {
+	file string
+	json bool
+}
+
+func addCommonFlags(fs *flag.FlagSet, opts *options) {
+	fs.StringVar(&opts.file, "file", opts.file, "path of the JSON data file (env TASKS_FILE)")
+	fs.BoolVar(&opts.json, "json", opts.json, "print JSON instead of text")
+}
+
+// parseInterspersed lets flags follow positional arguments, which the flag
+// package alone stops at. Everything after "--" is positional, so a title
+// may start with a dash.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			rest = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+const defaultTasksFile = "lessons/code/tmp_tasks_cli.json"
+
+const usageText = `usage: tasks [--file PATH] [--json] <command> [args]
+
+commands:
+  add <title>          create a task
+  list [--status S]    list tasks (S: all, open, done)
+  done <id>            mark a task done
+  edit <id> <title>    change a task's title
+  rm <id>              delete a task
+
+Flags may come before or after the command; use -- before a title that
+starts with a dash.
+`
+
+func parseID(raw string) (int64, error) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: got %q", ErrInvalidID, raw)
+	}
+	return id, nil
+}
+
+// LESSON 7: Output formatting kept apart from the commands
+// Why this matters: every command returns data; one place decides text or
+// JSON.
+type printer struct {
+	out  io.Writer
+	json bool
+}
+
+func (p printer) task(verb string, t Task) error {
+	if p.json {
+		return json.NewEncoder(p.out).Encode(t)
+	}
+	_, err := fmt.Fprintf(p.out, "%s %d: %s\n", verb, t.ID, t.Title)
+	return err
+}
+
+func (p printer) list(items []Task) error {
+	if p.json {
+		return json.NewEncoder(p.out).Encode(items)
+	}
+	if len(items) == 0 {
+		_, err := fmt.Fprintln(p.out, "no tasks")
+		return err
+	}
+	for _, t := range items {
+		mark := " "
+		if t.Done {
+			mark = "x"
+		}
+		if _, err := fmt.Fprintf(p.out, "%4d [%s] %s\n", t.ID, mark, t.Title); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p printer) deleted(id int64) error {
+	if p.json {
+		return json.NewEncoder(p.out).Encode(map[string]int64{"deleted": id})
+	}
+	_, err := fmt.Fprintf(p.out, "deleted %d\n", id)
+	return err
+}
+
+// LESSON 8: Dispatcher
+// Why this matters: parse -> validate -> call service -> print, the same
+// shape for every command.
+func runCommand(name string, args []string, opts options, stdout io.Writer) error {
+	fs := flag.NewFlagSet("tasks "+name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addCommonFlags(fs, &opts)
+	status := "all"
+	if name == "list" {
+		fs.StringVar(&status, "status", status, "all, open or done")
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(stdout, usageText)
+			return err
+		}
+		return usagef("%v", err)
+	}
+	service := NewTaskService(NewJSONFileTaskRepo(opts.file))
+	p := printer{out: stdout, json: opts.json}
+
+	switch name {
+	case "add":
+		if len(args) == 0 {
+			return usagef("add needs a title")
+		}
+		// Unquoted words are joined: `tasks add buy milk` works too.
+		task, err := service.CreateTask(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		return p.task("added", task)
+	case "list":
+		if len(args) != 0 {
+			return usagef("list takes no arguments")
+		}
+		if status != "all" && status != "open" && status != "done" {
+			return usagef("--status must be all, open or done, got %q", status)
+		}
+		items, err := service.Tasks()
+		if err != nil {
+			return err
+		}
+		filtered := []Task{}
+		for _, t := range items {
+			if status == "all" || (status == "done") == t.Done {
+				filtered = append(filtered, t)
+			}
+		}
+		return p.list(filtered)
+	case "done", "rm":
+		if len(args) != 1 {
+			return usagef("%s needs exactly one id", name)
+		}
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		if name == "rm" {
+			if err := service.DeleteTask(id); err != nil {
+				return err
+			}
+			return p.deleted(id)
+		}
+		task, err := service.CompleteTask(id)
+		if err != nil {
+			return err
+		}
+		return p.task("completed", task)
+	case "edit":
+		if len(args) < 2 {
+			return usagef("edit needs an id and a title")
+		}
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		task, err := service.RenameTask(id, strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		return p.task("updated", task)
+	default:
+		return usagef("unknown command %q", name)
+	}
+}
+
+// LESSON 9: Composition root
+// Why this matters: flags and environment decide the file, and errors become
+// exit codes in one place; main only touches the real process.
+// run is main without the process: arguments, streams and environment come
+// in, an exit code goes out. That keeps the whole CLI testable.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	opts := options{file: defaultTasksFile}
+	if env := getenv("TASKS_FILE"); env != "" {
+		opts.file = env
+	}
+	global := flag.NewFlagSet("tasks", flag.ContinueOnError)
+	global.SetOutput(io.Discard)
+	addCommonFlags(global, &opts)
+
+	err := global.Parse(args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		fmt.Fprint(stdout, usageText)
+	case err != nil:
+		err = usagef("%v", err)
+	case global.NArg() == 0:
+		err = usagef("missing command")
+	default:
+		err = runCommand(global.Arg(0), global.Args()[1:], opts, stdout)
+	}
+
+	code := exitCode(err)
+	if code != exitOK {
+		fmt.Fprintln(stderr, "tasks:", err)
+		if code == exitUsage {
+			fmt.Fprint(stderr, usageText)
+		}
+	}
+	return code
+}
+
+// LESSON 10: End-to-end CLI
+// Why this matters: same service, no server, durable between invocations.
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// End of Go Tasks CLI 1-10
@@ -0,0 +1,691 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+)
+
+/*
+GO MONEY TESTS (Lessons 1-10)
+
+Suggested use:
+1) Run: go test lessons/code/134-go-money-tests-1-10_test.go -run TestLesson -v
+2) Why this command is file-specific: lesson files are standalone by design
+
+Extra context:
+- lessons/notes/204-money-as-integers-first-principles.md
+*/
+
+// Currency is an ISO 4217 code such as "EUR".
+type Currency string
+
+// currencyExponents says how many minor units a currency has (cents for
+// EUR, none for JPY). Only listed currencies are accepted; a real system
+// would load the full ISO 4217 table.
+var currencyExponents = map[Currency]int{
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"USD": 2,
+}
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrAmountOverflow   = errors.New("amount overflows int64 minor units")
+	ErrMissingRate      = errors.New("no exchange rate")
+)
+
+func (c Currency) exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+// Money is an exact amount: an integer count of minor units (cents) plus
+// its currency. Adding integers never drifts the way float64 sums do.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney reads a plain decimal such as "12.5" or "-3.05" without ever
+// going through float64. More decimals than the currency has is an error,
+// not a silent rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	raw := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, frac, hasPoint := strings.Cut(raw, ".")
+	if whole == "" || (hasPoint && frac == "") || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var minor int64
+	for _, r := range whole + frac {
+		if minor > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney is for literals in code and tests; it panics on bad input.
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) ||
+		(other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Minor: m.Minor + other.Minor, Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or +1; amounts in different currencies do not compare.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String prints the exact decimal, e.g. "12.50 EUR".
+func (m Money) String() string {
+	exp, err := m.Currency.exponent()
+	if err != nil {
+		return fmt.Sprintf("%d minor units of %s", m.Minor, m.Currency)
+	}
+	sign := ""
+	abs := new(big.Int).SetInt64(m.Minor) // big.Int: -MinInt64 does not fit int64
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+	digits := abs.String()
+	if exp == 0 {
+		return fmt.Sprintf("%s%s %s", sign, digits, m.Currency)
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:cut], digits[cut:], m.Currency)
+}
+
+// RateTable converts into one reporting currency. A rate says how many
+// units of the base one unit of the currency is worth ("USD": "0.92" with
+// base EUR). Rates are exact decimals held as big.Rat, never float64.
+type RateTable struct {
+	base  Currency
+	rates map[Currency]*big.Rat
+}
+
+func NewRateTable(base Currency, rates map[Currency]string) (*RateTable, error) {
+	if _, err := base.exponent(); err != nil {
+		return nil, err
+	}
+	t := &RateTable{base: base, rates: map[Currency]*big.Rat{base: big.NewRat(1, 1)}}
+	for currency, raw := range rates {
+		if _, err := currency.exponent(); err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(raw))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate for %s must be a positive decimal, got %q", currency, raw)
+		}
+		t.rates[currency] = rate
+	}
+	return t, nil
+}
+
+func (t *RateTable) Base() Currency {
+	return t.base
+}
+
+// Convert rounds once, half away from zero, to the target's minor unit.
+// Callers should convert sums rather than sum conversions, so that only
+// one rounding happens per currency.
+func (t *RateTable) Convert(m Money, to Currency) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	if t == nil {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, to)
+	}
+	fromExp, err := m.Currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	toExp, err := to.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	fromRate, ok := t.rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, t.base)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, to, t.base)
+	}
+	// minor / 10^fromExp major units, times fromRate/toRate, times 10^toExp.
+	value := new(big.Rat).SetInt64(m.Minor)
+	value.Mul(value, fromRate)
+	value.Quo(value, toRate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toExp), pow10(fromExp)))
+
+	minor, err := roundHalfAway(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: to}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func roundHalfAway(r *big.Rat) (int64, error) {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Denom is always positive, so rem carries the sign of the value.
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return q.Int64(), nil
+}
+
+type Expense struct {
+	Category string
+	Amount   Money
+}
+
+// Totals holds one exact sum per currency; nothing is converted until the
+// report asks for it.
+type Totals map[Currency]Money
+
+func (t Totals) add(m Money) error {
+	current, ok := t[m.Currency]
+	if !ok {
+		t[m.Currency] = m
+		return nil
+	}
+	sum, err := current.Add(m)
+	if err != nil {
+		return err
+	}
+	t[m.Currency] = sum
+	return nil
+}
+
+// Currencies returns the currencies present, sorted for stable output.
+func (t Totals) Currencies() []Currency {
+	out := make([]Currency, 0, len(t))
+	for c := range t {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// In converts each currency's sum once and adds the results.
+func (t Totals) In(rates *RateTable, to Currency) (Money, error) {
+	total := Money{Currency: to}
+	for _, c := range t.Currencies() {
+		converted, err := rates.Convert(t[c], to)
+		if err != nil {
+			return Money{}, err
+		}
+		if total, err = total.Add(converted); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+func TotalsByCategory(items []Expense) (map[string]Totals, error) {
+	out := make(map[string]Totals)
+	for _, it := range items {
+		if out[it.Category] == nil {
+			out[it.Category] = Totals{}
+		}
+		if err := out[it.Category].add(it.Amount); err != nil {
+			return nil, fmt.Errorf("category %q: %w", it.Category, err)
+		}
+	}
+	return out, nil
+}
+
+func ValidateExpense(e Expense) error {
+	if strings.TrimSpace(e.Category) == "" {
+		return errors.New("category is required")
+	}
+	if _, err := e.Amount.Currency.exponent(); err != nil {
+		return err
+	}
+	if e.Amount.Minor < 0 {
+		return errors.New("amount cannot be negative")
+	}
+	return nil
+}
+
+func NormalizeCategory(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+// the exact per-currency sums, then (when more than the reporting currency
+// is involved) their converted total. With nil rates nothing is converted.
+func BuildReportLines(totals map[string]Totals, rates *RateTable) ([]string, error) {
+	keys := make([]string, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		currencies := totals[k].Currencies()
+		parts := make([]string, 0, len(currencies))
+		for _, c := range currencies {
+			parts = append(parts, totals[k][c].String())
+		}
+		line := fmt.Sprintf("- %s: %s", k, strings.Join(parts, " + "))
+		if rates != nil && (len(currencies) > 1 || currencies[0] != rates.Base()) {
+			converted, err := totals[k].In(rates, rates.Base())
+			if err != nil {
+				return nil, fmt.Errorf("category %q: %w", k, err)
+			}
+			line += " = " + converted.String()
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func ProcessExpenses(items []Expense, rates *RateTable) ([]string, error) {
+	normalized := make([]Expense, 0, len(items))
+	for _, e := range items {
+		e.Category = NormalizeCategory(e.Category)
+		if err := ValidateExpense(e); err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, e)
+	}
+	totals, err := TotalsByCategory(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return BuildReportLines(totals, rates)
+}
+
+func RunReport(items []Expense, rates *RateTable) {
+	lines, err := ProcessExpenses(items, rates)
+	if err != nil {
+		fmt.Println("Lesson 6 error:", err)
+		return
+	}
+	fmt.Println("Lesson 6 report:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// in other currencies are converted into min's currency to compare.
+func FilterByMin(items []Expense, min Money, rates *RateTable) ([]Expense, error) {
+	out := make([]Expense, 0)
+	for _, e := range items {
+		amount, err := rates.Convert(e.Amount, min.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if amount.Minor >= min.Minor {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func BudgetStatus(limit Money, spent Money) (string, error) {
+	cmp, err := spent.Cmp(limit)
+	if err != nil {
+		return "", err
+	}
+	switch cmp {
+	case -1:
+		return "under", nil
+	case 1:
+		return "over", nil
+	default:
+		return "exact", nil
+	}
+}
+
+func SumAmounts(items []Expense) (Totals, error) {
+	totals := Totals{}
+	for _, e := range items {
+		if err := totals.add(e.Amount); err != nil {
+			return nil, err
+		}
+	}
+	return totals, nil
+}
+
+func testRates(t *testing.T) *RateTable {
+	t.Helper()
+	rates, err := NewRateTable("EUR", map[Currency]string{"USD": "0.92", "GBP": "1.17", "JPY": "0.0061"})
+	if err != nil {
+		t.Fatalf("rates: %v", err)
+	}
+	return rates
+}
+
+func TestLesson1ParseMoney(t *testing.T) {
+	cases := []struct {
+		amount   string
+		currency Currency
+		want     int64
+	}{
+		{"12.50", "EUR", 1250},
+		{"12.5", "EUR", 1250},
+		{"12", "EUR", 1200},
+		{" 0.07 ", "USD", 7},
+		{"-3.05", "EUR", -305},
+		{"1500", "JPY", 1500},
+		{"1.234", "KWD", 1234},
+		{"92233720368547758.07", "EUR", math.MaxInt64},
+	}
+	for _, tc := range cases {
+		got, err := ParseMoney(tc.amount, tc.currency)
+		if err != nil || got != (Money{Minor: tc.want, Currency: tc.currency}) {
+			t.Fatalf("ParseMoney(%q, %s) = %+v, %v; want %d", tc.amount, tc.currency, got, err, tc.want)
+		}
+	}
+}
+
+func TestLesson2ParseMoneyRejects(t *testing.T) {
+	for _, tc := range []struct {
+		amount   string
+		currency Currency
+		want     error
+	}{
+		{"1.005", "EUR", ErrInvalidAmount},
+		{"12.5", "JPY", ErrInvalidAmount},
+		{"12.", "EUR", ErrInvalidAmount},
+		{".5", "EUR", ErrInvalidAmount},
+		{"1,50", "EUR", ErrInvalidAmount},
+		{"1e3", "EUR", ErrInvalidAmount},
+		{"+1", "EUR", ErrInvalidAmount},
+		{"", "EUR", ErrInvalidAmount},
+		{"--1", "EUR", ErrInvalidAmount},
+		{"5", "XYZ", ErrUnknownCurrency},
+		{"5", "eur", ErrUnknownCurrency},
+		{"92233720368547758.08", "EUR", ErrAmountOverflow},
+	} {
+		if _, err := ParseMoney(tc.amount, tc.currency); !errors.Is(err, tc.want) {
+			t.Fatalf("ParseMoney(%q, %s): want %v, got %v", tc.amount, tc.currency, tc.want, err)
+		}
+	}
+}
+
+func TestLesson3MoneyString(t *testing.T) {
+	for _, tc := range []struct {
+		m    Money
+		want string
+	}{
+		{Money{1250, "EUR"}, "12.50 EUR"},
+		{Money{7, "USD"}, "0.07 USD"},
+		{Money{-5, "EUR"}, "-0.05 EUR"},
+		{Money{0, "GBP"}, "0.00 GBP"},
+		{Money{1500, "JPY"}, "1500 JPY"},
+		{Money{1234, "KWD"}, "1.234 KWD"},
+		{Money{math.MinInt64, "EUR"}, "-92233720368547758.08 EUR"},
+	} {
+		if got := tc.m.String(); got != tc.want {
+			t.Fatalf("%+v: want %q, got %q", tc.m, tc.want, got)
+		}
+		if tc.m.Minor == math.MinInt64 {
+			continue // one past what ParseMoney accepts
+		}
+		back, err := ParseMoney(strings.TrimSuffix(tc.want, " "+string(tc.m.Currency)), tc.m.Currency)
+		if err != nil || back != tc.m {
+			t.Fatalf("%q does not round-trip: %+v %v", tc.want, back, err)
+		}
+	}
+}
+
+func TestLesson4AdditionIsExact(t *testing.T) {
+	dime := MustParseMoney("0.10", "EUR")
+	total := Money{Currency: "EUR"}
+	float := 0.0
+	for i := 0; i < 1000; i++ {
+		total, _ = total.Add(dime)
+		float += 0.10
+	}
+	if total.String() != "100.00 EUR" {
+		t.Fatalf("1000 x 0.10: want 100.00 EUR, got %s", total)
+	}
+	if float == 100 {
+		t.Fatalf("float64 was expected to drift; the lesson's premise changed")
+	}
+
+	if _, err := dime.Add(MustParseMoney("1", "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("EUR + USD: want ErrCurrencyMismatch, got %v", err)
+	}
+	if _, err := (Money{math.MaxInt64, "EUR"}).Add(Money{1, "EUR"}); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("overflow: want ErrAmountOverflow, got %v", err)
+	}
+	if _, err := (Money{math.MinInt64, "EUR"}).Add(Money{-1, "EUR"}); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("underflow: want ErrAmountOverflow, got %v", err)
+	}
+}
+
+func TestLesson5Convert(t *testing.T) {
+	rates := testRates(t)
+	for _, tc := range []struct {
+		from Money
+		to   Currency
+		want string
+	}{
+		{MustParseMoney("12.99", "USD"), "EUR", "11.95 EUR"}, // 11.9508
+		{MustParseMoney("0.50", "USD"), "EUR", "0.46 EUR"},   // exactly 0.46
+		{MustParseMoney("0.25", "USD"), "EUR", "0.23 EUR"},   // 0.2300
+		{MustParseMoney("-12.99", "USD"), "EUR", "-11.95 EUR"},
+		{MustParseMoney("10", "GBP"), "USD", "12.72 USD"}, // 11.70 EUR / 0.92 = 12.717...
+		{MustParseMoney("1000", "JPY"), "EUR", "6.10 EUR"},
+		{MustParseMoney("6.10", "EUR"), "JPY", "1000 JPY"},
+		{MustParseMoney("7", "EUR"), "EUR", "7.00 EUR"}, // same currency needs no rate
+	} {
+		got, err := rates.Convert(tc.from, tc.to)
+		if err != nil || got.String() != tc.want {
+			t.Fatalf("%s -> %s: want %s, got %s (%v)", tc.from, tc.to, tc.want, got, err)
+		}
+	}
+
+	// Half away from zero, on both sides of zero.
+	half, _ := NewRateTable("EUR", map[Currency]string{"USD": "0.5"})
+	for amount, want := range map[string]string{"0.01": "0.01 EUR", "0.03": "0.02 EUR", "-0.01": "-0.01 EUR", "-0.03": "-0.02 EUR"} {
+		got, _ := half.Convert(MustParseMoney(amount, "USD"), "EUR")
+		if got.String() != want {
+			t.Fatalf("%s USD at 0.5: want %s, got %s", amount, want, got)
+		}
+	}
+
+	if _, err := rates.Convert(MustParseMoney("1", "CHF"), "EUR"); !errors.Is(err, ErrMissingRate) {
+		t.Fatalf("CHF without rate: want ErrMissingRate, got %v", err)
+	}
+	if _, err := rates.Convert(MustParseMoney("1", "EUR"), "CHF"); !errors.Is(err, ErrMissingRate) {
+		t.Fatalf("to CHF without rate: want ErrMissingRate, got %v", err)
+	}
+}
+
+func TestLesson6RateTableValidation(t *testing.T) {
+	for _, tc := range []struct {
+		base  Currency
+		rates map[Currency]string
+	}{
+		{"XYZ", nil},
+		{"EUR", map[Currency]string{"XYZ": "1"}},
+		{"EUR", map[Currency]string{"USD": "0"}},
+		{"EUR", map[Currency]string{"USD": "-0.9"}},
+		{"EUR", map[Currency]string{"USD": "abc"}},
+		{"EUR", map[Currency]string{"USD": "1/0.92"}},
+	} {
+		if _, err := NewRateTable(tc.base, tc.rates); err == nil {
+			t.Fatalf("NewRateTable(%s, %v) should fail", tc.base, tc.rates)
+		}
+	}
+	rates, err := NewRateTable("USD", map[Currency]string{"EUR": "1.0870", "USD": "1"})
+	if err != nil {
+		t.Fatalf("decimal rates: %v", err)
+	}
+	if rates.Base() != "USD" {
+		t.Fatalf("base: want USD, got %s", rates.Base())
+	}
+	if got, _ := rates.Convert(MustParseMoney("0.92", "EUR"), "USD"); got.String() != "1.00 USD" {
+		t.Fatalf("0.92 EUR at 1.0870: want 1.00 USD, got %s", got)
+	}
+}
+
+func TestLesson7ReportLines(t *testing.T) {
+	items := []Expense{
+		{Category: " Food ", Amount: MustParseMoney("20.50", "EUR")},
+		{Category: "food", Amount: MustParseMoney("15", "EUR")},
+		{Category: "food", Amount: MustParseMoney("12.99", "USD")},
+		{Category: "transport", Amount: MustParseMoney("12.25", "EUR")},
+		{Category: "travel", Amount: MustParseMoney("80", "GBP")},
+	}
+	lines, err := ProcessExpenses(items, testRates(t))
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	want := []string{
+		"- food: 35.50 EUR + 12.99 USD = 47.45 EUR",
+		"- transport: 12.25 EUR",
+		"- travel: 80.00 GBP = 93.60 EUR",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("report:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	totals, _ := TotalsByCategory([]Expense{{Category: "x", Amount: MustParseMoney("1", "USD")}})
+	lines, err = BuildReportLines(totals, nil)
+	if err != nil || len(lines) != 1 || lines[0] != "- x: 1.00 USD" {
+		t.Fatalf("nil rates: per-currency only, got %v %v", lines, err)
+	}
+}
+
+func TestLesson8ProcessErrors(t *testing.T) {
+	rates := testRates(t)
+	for _, tc := range []struct {
+		item Expense
+		want string
+	}{
+		{Expense{Category: " ", Amount: MustParseMoney("1", "EUR")}, "category is required"},
+		{Expense{Category: "food", Amount: MustParseMoney("-1", "EUR")}, "amount cannot be negative"},
+		{Expense{Category: "food", Amount: Money{Minor: 100, Currency: "XYZ"}}, "unknown currency"},
+		{Expense{Category: "food", Amount: MustParseMoney("1", "CHF")}, "no exchange rate"},
+	} {
+		_, err := ProcessExpenses([]Expense{tc.item}, rates)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: want error containing %q, got %v", tc.item, tc.want, err)
+		}
+	}
+}
+
+func TestLesson9FilterBudgetAndSum(t *testing.T) {
+	rates := testRates(t)
+	items := []Expense{
+		{Category: "a", Amount: MustParseMoney("14.99", "EUR")},
+		{Category: "b", Amount: MustParseMoney("15", "EUR")},
+		{Category: "c", Amount: MustParseMoney("16.30", "USD")}, // 15.00 EUR after rounding
+		{Category: "d", Amount: MustParseMoney("16.29", "USD")}, // 14.99 EUR
+	}
+	filtered, err := FilterByMin(items, MustParseMoney("15", "EUR"), rates)
+	got := []string{}
+	for _, e := range filtered {
+		got = append(got, e.Category)
+	}
+	if err != nil || fmt.Sprint(got) != "[b c]" {
+		t.Fatalf("FilterByMin: want [b c], got %v %v", got, err)
+	}
+	if _, err := FilterByMin(items, MustParseMoney("15", "EUR"), nil); !errors.Is(err, ErrMissingRate) {
+		t.Fatalf("FilterByMin without rates: want ErrMissingRate, got %v", err)
+	}
+
+	totals, err := SumAmounts(items)
+	if err != nil || totals["EUR"].String() != "29.99 EUR" || totals["USD"].String() != "32.59 USD" {
+		t.Fatalf("SumAmounts keeps currencies apart: %v %v", totals, err)
+	}
+	spent, err := totals.In(rates, "EUR")
+	if err != nil || spent.String() != "59.97 EUR" { // 29.99 + round(29.9828)
+		t.Fatalf("converted sum: want 59.97 EUR, got %s %v", spent, err)
+	}
+
+	for limit, want := range map[string]string{"60": "under", "59.97": "exact", "59.96": "over"} {
+		if status, err := BudgetStatus(MustParseMoney(limit, "EUR"), spent); err != nil || status != want {
+			t.Fatalf("limit %s: want %s, got %s %v", limit, want, status, err)
+		}
+	}
+	if _, err := BudgetStatus(MustParseMoney("60", "USD"), spent); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("budget in another currency: want ErrCurrencyMismatch, got %v", err)
+	}
+}
+
+func TestLesson10Completion(t *testing.T) {
+	if false {
+		t.Fatalf("unreachable")
+	}
+}
+
+// End of Go Money Tests 1-10
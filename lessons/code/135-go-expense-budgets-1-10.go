@@ -0,0 +1,723 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+GO EXPENSE BUDGETS (Lessons 1-10)
+
+Suggested use:
+1) Run: go run lessons/code/135-go-expense-budgets-1-10.go
+2) Move the report date (asOf in main) a few days forward and predict how
+   the burn rate and projection change before running
+3) Add a 50 threshold to the monitor and watch it fire once per window,
+   not once per expense
+
+Extra context:
+- lessons/notes/155-go-projects-principles.md
+- lessons/notes/204-money-as-integers-first-principles.md
+- lessons/notes/205-budget-periods-first-principles.md
+*/
+
+// Currency is an ISO 4217 code such as "EUR".
+type Currency string
+
+// currencyExponents says how many minor units a currency has (cents for
+// EUR, none for JPY). Only listed currencies are accepted; a real system
+// would load the full ISO 4217 table.
+var currencyExponents = map[Currency]int{
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"USD": 2,
+}
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrAmountOverflow   = errors.New("amount overflows int64 minor units")
+	ErrMissingRate      = errors.New("no exchange rate")
+)
+
+func (c Currency) exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+// Money is an exact amount: an integer count of minor units (cents) plus
+// its currency. Adding integers never drifts the way float64 sums do.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney reads a plain decimal such as "12.5" or "-3.05" without ever
+// going through float64. More decimals than the currency has is an error,
+// not a silent rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	raw := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, frac, hasPoint := strings.Cut(raw, ".")
+	if whole == "" || (hasPoint && frac == "") || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var minor int64
+	for _, r := range whole + frac {
+		if minor > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney is for literals in code and tests; it panics on bad input.
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) ||
+		(other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Minor: m.Minor + other.Minor, Currency: m.Currency}, nil
+}
+
+// Sub is Add with the sign flipped; a budget's remaining amount goes
+// negative once it is overspent.
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Minor == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(Money{Minor: -other.Minor, Currency: other.Currency})
+}
+
+// Cmp returns -1, 0 or +1; amounts in different currencies do not compare.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String prints the exact decimal, e.g. "12.50 EUR".
+func (m Money) String() string {
+	exp, err := m.Currency.exponent()
+	if err != nil {
+		return fmt.Sprintf("%d minor units of %s", m.Minor, m.Currency)
+	}
+	sign := ""
+	abs := new(big.Int).SetInt64(m.Minor) // big.Int: -MinInt64 does not fit int64
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+	digits := abs.String()
+	if exp == 0 {
+		return fmt.Sprintf("%s%s %s", sign, digits, m.Currency)
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:cut], digits[cut:], m.Currency)
+}
+
+// RateTable converts into one reporting currency. A rate says how many
+// units of the base one unit of the currency is worth ("USD": "0.92" with
+// base EUR). Rates are exact decimals held as big.Rat, never float64.
+type RateTable struct {
+	base  Currency
+	rates map[Currency]*big.Rat
+}
+
+func NewRateTable(base Currency, rates map[Currency]string) (*RateTable, error) {
+	if _, err := base.exponent(); err != nil {
+		return nil, err
+	}
+	t := &RateTable{base: base, rates: map[Currency]*big.Rat{base: big.NewRat(1, 1)}}
+	for currency, raw := range rates {
+		if _, err := currency.exponent(); err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(raw))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate for %s must be a positive decimal, got %q", currency, raw)
+		}
+		t.rates[currency] = rate
+	}
+	return t, nil
+}
+
+func (t *RateTable) Base() Currency {
+	return t.base
+}
+
+// Convert rounds once, half away from zero, to the target's minor unit.
+// Callers should convert sums rather than sum conversions, so that only
+// one rounding happens per currency.
+func (t *RateTable) Convert(m Money, to Currency) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	if t == nil {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, to)
+	}
+	fromExp, err := m.Currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	toExp, err := to.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	fromRate, ok := t.rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, t.base)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, to, t.base)
+	}
+	// minor / 10^fromExp major units, times fromRate/toRate, times 10^toExp.
+	value := new(big.Rat).SetInt64(m.Minor)
+	value.Mul(value, fromRate)
+	value.Quo(value, toRate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toExp), pow10(fromExp)))
+
+	minor, err := roundHalfAway(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: to}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func roundHalfAway(r *big.Rat) (int64, error) {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Denom is always positive, so rem carries the sign of the value.
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return q.Int64(), nil
+}
+
+// Expense now carries the day it happened; budgets are per period, so an
+// undated expense cannot be counted anywhere.
+type Expense struct {
+	Date     time.Time
+	Category string
+	Amount   Money
+}
+
+// Totals holds one exact sum per currency; nothing is converted until the
+// report asks for it.
+type Totals map[Currency]Money
+
+func (t Totals) add(m Money) error {
+	current, ok := t[m.Currency]
+	if !ok {
+		t[m.Currency] = m
+		return nil
+	}
+	sum, err := current.Add(m)
+	if err != nil {
+		return err
+	}
+	t[m.Currency] = sum
+	return nil
+}
+
+// Currencies returns the currencies present, sorted for stable output.
+func (t Totals) Currencies() []Currency {
+	out := make([]Currency, 0, len(t))
+	for c := range t {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// In converts each currency's sum once and adds the results.
+func (t Totals) In(rates *RateTable, to Currency) (Money, error) {
+	total := Money{Currency: to}
+	for _, c := range t.Currencies() {
+		converted, err := rates.Convert(t[c], to)
+		if err != nil {
+			return Money{}, err
+		}
+		if total, err = total.Add(converted); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// ValidateExpense is the check from the projects lesson plus a date.
+func ValidateExpense(e Expense) error {
+	if e.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	if strings.TrimSpace(e.Category) == "" {
+		return errors.New("category is required")
+	}
+	if _, err := e.Amount.Currency.exponent(); err != nil {
+		return err
+	}
+	if e.Amount.Minor < 0 {
+		return errors.New("amount cannot be negative")
+	}
+	return nil
+}
+
+func NormalizeCategory(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+// LESSON 1: Periods as half-open windows
+// Why this matters: "this month" is [1st 00:00, 1st of next month 00:00);
+// with an exclusive end no expense falls between two windows or into both.
+type Period string
+
+const (
+	PeriodWeekly  Period = "weekly"
+	PeriodMonthly Period = "monthly"
+)
+
+var ErrUnknownPeriod = errors.New("unknown period")
+
+// Window returns the period containing t, in t's location. Weeks start on
+// Monday (ISO 8601).
+func (p Period) Window(t time.Time) (start, end time.Time, err error) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch p {
+	case PeriodWeekly:
+		sinceMonday := (int(day.Weekday()) + 6) % 7
+		start = day.AddDate(0, 0, -sinceMonday)
+		return start, start.AddDate(0, 0, 7), nil
+	case PeriodMonthly:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrUnknownPeriod, string(p))
+	}
+}
+
+// calendarDays counts midnights between two dates. Going through UTC
+// keeps a 23-hour DST day from counting as zero days.
+func calendarDays(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// LESSON 2: Budgets per category and period
+// Why this matters: a limit only means something together with what it
+// covers and for how long.
+type Budget struct {
+	Category string
+	Period   Period
+	Limit    Money
+}
+
+var (
+	ErrInvalidBudget   = errors.New("invalid budget")
+	ErrDuplicateBudget = errors.New("duplicate budget")
+)
+
+// BudgetBook holds validated budgets: at most one per category and period,
+// so "food" may have both a weekly and a monthly limit.
+type BudgetBook struct {
+	budgets []Budget
+}
+
+func NewBudgetBook(budgets ...Budget) (*BudgetBook, error) {
+	seen := map[string]bool{}
+	book := &BudgetBook{}
+	for _, b := range budgets {
+		b.Category = NormalizeCategory(b.Category)
+		if b.Category == "" {
+			return nil, fmt.Errorf("%w: category is required", ErrInvalidBudget)
+		}
+		if _, _, err := b.Period.Window(time.Time{}); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidBudget, b.Category, err)
+		}
+		if _, err := b.Limit.Currency.exponent(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidBudget, b.Category, err)
+		}
+		if b.Limit.Minor <= 0 {
+			return nil, fmt.Errorf("%w: %s: limit must be positive", ErrInvalidBudget, b.Category)
+		}
+		key := b.Category + "/" + string(b.Period)
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateBudget, key)
+		}
+		seen[key] = true
+		book.budgets = append(book.budgets, b)
+	}
+	sort.Slice(book.budgets, func(i, j int) bool {
+		if book.budgets[i].Category != book.budgets[j].Category {
+			return book.budgets[i].Category < book.budgets[j].Category
+		}
+		return book.budgets[i].Period < book.budgets[j].Period
+	})
+	return book, nil
+}
+
+func (b *BudgetBook) Budgets() []Budget {
+	return append([]Budget(nil), b.budgets...)
+}
+
+// For returns the budgets that an expense in category counts against.
+func (b *BudgetBook) For(category string) []Budget {
+	category = NormalizeCategory(category)
+	out := []Budget{}
+	for _, budget := range b.budgets {
+		if budget.Category == category {
+			out = append(out, budget)
+		}
+	}
+	return out
+}
+
+// LESSON 3: Spent within a window
+// Why this matters: sum per currency first and convert once into the
+// budget's currency, exactly like the category report does.
+func SpentInWindow(items []Expense, category string, start, end time.Time, rates *RateTable, to Currency) (Money, error) {
+	category = NormalizeCategory(category)
+	totals := Totals{}
+	for _, e := range items {
+		if NormalizeCategory(e.Category) != category || e.Date.Before(start) || !e.Date.Before(end) {
+			continue
+		}
+		if err := totals.add(e.Amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return totals.In(rates, to)
+}
+
+// LESSON 4: Percent used without floats
+// Why this matters: "did we reach 80%?" is spent*100 >= limit*80, which
+// integers answer exactly; float percentages wobble right at the edge.
+func reachedPercent(spent, limit Money, percent int) bool {
+	lhs := new(big.Int).Mul(big.NewInt(spent.Minor), big.NewInt(100))
+	rhs := new(big.Int).Mul(big.NewInt(limit.Minor), big.NewInt(int64(percent)))
+	return lhs.Cmp(rhs) >= 0
+}
+
+// usedPercent is for display only, e.g. "82.5%".
+func usedPercent(spent, limit Money) string {
+	r := new(big.Rat).SetFrac(big.NewInt(spent.Minor), big.NewInt(limit.Minor))
+	r.Mul(r, big.NewRat(100, 1))
+	return r.FloatString(1) + "%"
+}
+
+// LESSON 5: Burn rate and projection
+// Why this matters: "60% used" is fine on the 25th and alarming on the
+// 5th; spend per elapsed day tells which.
+type BudgetLine struct {
+	Budget
+	Start, End   time.Time
+	Spent        Money
+	Remaining    Money // negative once overspent
+	DaysElapsed  int
+	DaysInPeriod int
+	BurnPerDay   Money
+	Projected    Money
+}
+
+// burn divides once and rounds once. Projected is spent*days/elapsed, not
+// the rounded daily burn times days, which would multiply the rounding.
+func burn(spent Money, elapsed, days int) (perDay, projected Money, err error) {
+	perDayMinor, err := roundHalfAway(big.NewRat(spent.Minor, int64(elapsed)))
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+	projectedMinor, err := roundHalfAway(new(big.Rat).Mul(
+		big.NewRat(spent.Minor, int64(elapsed)), big.NewRat(int64(days), 1)))
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+	return Money{Minor: perDayMinor, Currency: spent.Currency},
+		Money{Minor: projectedMinor, Currency: spent.Currency}, nil
+}
+
+// LESSON 6: Budget report as of a day
+// Why this matters: the report is a pure function of budgets, expenses
+// and a date, so it is easy to test and to rerun for any past day.
+// Expenses dated after asOf are not counted yet.
+func BudgetReport(book *BudgetBook, items []Expense, rates *RateTable, asOf time.Time) ([]BudgetLine, error) {
+	lines := make([]BudgetLine, 0, len(book.budgets))
+	for _, b := range book.budgets {
+		start, end, err := b.Period.Window(asOf)
+		if err != nil {
+			return nil, err
+		}
+		cutoff := time.Date(asOf.Year(), asOf.Month(), asOf.Day()+1, 0, 0, 0, 0, asOf.Location())
+		spent, err := SpentInWindow(items, b.Category, start, cutoff, rates, b.Limit.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("budget %s/%s: %w", b.Category, b.Period, err)
+		}
+		remaining, err := b.Limit.Sub(spent)
+		if err != nil {
+			return nil, err
+		}
+		line := BudgetLine{
+			Budget:       b,
+			Start:        start,
+			End:          end,
+			Spent:        spent,
+			Remaining:    remaining,
+			DaysElapsed:  calendarDays(start, cutoff),
+			DaysInPeriod: calendarDays(start, end),
+		}
+		if line.BurnPerDay, line.Projected, err = burn(spent, line.DaysElapsed, line.DaysInPeriod); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// LESSON 7: Format report lines
+// Why this matters: keep formatting separate from computation.
+func FormatBudgetLine(l BudgetLine) string {
+	status := "ok"
+	if l.Remaining.Minor < 0 {
+		status = "OVER"
+	} else if l.Projected.Minor > l.Limit.Minor {
+		status = "on track to overspend"
+	}
+	return fmt.Sprintf("- %s %s %s..%s: spent %s of %s (%s), remaining %s, burn %s/day over %d/%d days, projected %s [%s]",
+		l.Category, l.Period, l.Start.Format(time.DateOnly), l.End.AddDate(0, 0, -1).Format(time.DateOnly),
+		l.Spent, l.Limit, usedPercent(l.Spent, l.Limit), l.Remaining,
+		l.BurnPerDay, l.DaysElapsed, l.DaysInPeriod, l.Projected, status)
+}
+
+// LESSON 8: Alerts as hooks
+// Why this matters: the budget code decides *when* to alert; what an alert
+// does (log, email, chat) is plugged in by the caller.
+type Alert struct {
+	Budget    Budget
+	Threshold int // percent of the limit, e.g. 80
+	Start     time.Time
+	Spent     Money
+	Expense   Expense // the expense that crossed the threshold
+}
+
+type AlertHook func(Alert)
+
+var DefaultThresholds = []int{80, 100}
+
+// LESSON 9: Monitor that fires each threshold once per window
+// Why this matters: without remembering what already fired, every
+// expense after 80% would send another "80% reached" message.
+type BudgetMonitor struct {
+	mu         sync.Mutex
+	book       *BudgetBook
+	rates      *RateTable
+	thresholds []int
+	hooks      []AlertHook
+	windows    map[string]*budgetWindow
+}
+
+type budgetWindow struct {
+	spent Totals
+	fired int // thresholds[:fired] already alerted
+}
+
+func NewBudgetMonitor(book *BudgetBook, rates *RateTable, thresholds ...int) (*BudgetMonitor, error) {
+	if len(thresholds) == 0 {
+		thresholds = DefaultThresholds
+	}
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+	for i, pct := range sorted {
+		if pct <= 0 || (i > 0 && pct == sorted[i-1]) {
+			return nil, fmt.Errorf("thresholds must be positive and distinct, got %v", thresholds)
+		}
+	}
+	return &BudgetMonitor{
+		book:       book,
+		rates:      rates,
+		thresholds: sorted,
+		windows:    map[string]*budgetWindow{},
+	}, nil
+}
+
+func (m *BudgetMonitor) OnAlert(hook AlertHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Record counts one expense against every budget of its category. Hooks
+// run after the lock is released, so a hook may call back into the
+// monitor.
+func (m *BudgetMonitor) Record(e Expense) error {
+	e.Category = NormalizeCategory(e.Category)
+	if err := ValidateExpense(e); err != nil {
+		return err
+	}
+	budgets := m.book.For(e.Category)
+	// Check every conversion before touching state, so a missing rate
+	// does not leave the expense counted against some budgets only.
+	for _, b := range budgets {
+		if _, err := m.rates.Convert(e.Amount, b.Limit.Currency); err != nil {
+			return fmt.Errorf("budget %s/%s: %w", b.Category, b.Period, err)
+		}
+	}
+
+	m.mu.Lock()
+	var alerts []Alert
+	for _, b := range budgets {
+		start, _, _ := b.Period.Window(e.Date)
+		key := b.Category + "/" + string(b.Period) + "/" + start.Format(time.DateOnly)
+		w := m.windows[key]
+		if w == nil {
+			w = &budgetWindow{spent: Totals{}}
+			m.windows[key] = w
+		}
+		if err := w.spent.add(e.Amount); err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		spent, err := w.spent.In(m.rates, b.Limit.Currency)
+		if err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		for w.fired < len(m.thresholds) && reachedPercent(spent, b.Limit, m.thresholds[w.fired]) {
+			alerts = append(alerts, Alert{Budget: b, Threshold: m.thresholds[w.fired], Start: start, Spent: spent, Expense: e})
+			w.fired++
+		}
+	}
+	hooks := append([]AlertHook(nil), m.hooks...)
+	m.mu.Unlock()
+
+	for _, alert := range alerts {
+		for _, hook := range hooks {
+			hook(alert)
+		}
+	}
+	return nil
+}
+
+// LESSON 10: End-to-end sample
+// Why this matters: budgets, a report and alerts over the same expenses.
+func main() {
+	rates, err := NewRateTable("EUR", map[Currency]string{"USD": "0.92"})
+	if err != nil {
+		fmt.Println("Lesson 10 rates error:", err)
+		return
+	}
+	book, err := NewBudgetBook(
+		Budget{Category: "food", Period: PeriodMonthly, Limit: MustParseMoney("300", "EUR")},
+		Budget{Category: "food", Period: PeriodWeekly, Limit: MustParseMoney("80", "EUR")},
+		Budget{Category: "Transport", Period: PeriodMonthly, Limit: MustParseMoney("60", "EUR")},
+	)
+	if err != nil {
+		fmt.Println("Lesson 10 budgets error:", err)
+		return
+	}
+	day := func(d int) time.Time { return time.Date(2026, time.October, d, 12, 0, 0, 0, time.UTC) }
+	items := []Expense{
+		{Date: day(2), Category: "food", Amount: MustParseMoney("42.10", "EUR")},
+		{Date: day(5), Category: "transport", Amount: MustParseMoney("25", "EUR")},
+		{Date: day(9), Category: "Food", Amount: MustParseMoney("58.40", "EUR")},
+		{Date: day(12), Category: "food", Amount: MustParseMoney("31.99", "USD")},
+		{Date: day(13), Category: "transport", Amount: MustParseMoney("38", "EUR")},
+		{Date: day(14), Category: "food", Amount: MustParseMoney("36.25", "EUR")},
+		{Date: day(15), Category: "food", Amount: MustParseMoney("19.80", "EUR")},
+		{Date: day(20), Category: "food", Amount: MustParseMoney("12", "EUR")}, // after asOf
+	}
+
+	monitor, err := NewBudgetMonitor(book, rates)
+	if err != nil {
+		fmt.Println("Lesson 10 monitor error:", err)
+		return
+	}
+	monitor.OnAlert(func(a Alert) {
+		fmt.Printf("Lesson 9 alert: %s %s reached %d%% on %s (%s of %s)\n",
+			a.Budget.Category, a.Budget.Period, a.Threshold, a.Expense.Date.Format(time.DateOnly), a.Spent, a.Budget.Limit)
+	})
+	for _, e := range items[:7] {
+		if err := monitor.Record(e); err != nil {
+			fmt.Println("Lesson 9 record error:", err)
+			return
+		}
+	}
+
+	asOf := day(16)
+	lines, err := BudgetReport(book, items, rates, asOf)
+	if err != nil {
+		fmt.Println("Lesson 10 report error:", err)
+		return
+	}
+	fmt.Println("Lesson 10 budgets as of", asOf.Format(time.DateOnly)+":")
+	for _, line := range lines {
+		fmt.Println(FormatBudgetLine(line))
+	}
+}
+
+// End of Go Expense Budgets 1-10
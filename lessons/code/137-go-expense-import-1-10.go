@@ -0,0 +1,720 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+GO EXPENSE IMPORT (Lessons 1-10)
+
+Suggested use:
+1) Run: go run lessons/code/137-go-expense-import-1-10.go
+2) Give the "rent" rule a lower priority than a new rule that contains
+   "oktober" and predict which category the rent row gets
+3) Add a rule for the unmatched kiosk row and watch it leave the report
+
+Extra context:
+- lessons/notes/155-go-projects-principles.md
+- lessons/notes/204-money-as-integers-first-principles.md
+- lessons/notes/206-statement-import-first-principles.md
+*/
+
+// Currency is an ISO 4217 code such as "EUR".
+type Currency string
+
+// currencyExponents says how many minor units a currency has (cents for
+// EUR, none for JPY). Only listed currencies are accepted; a real system
+// would load the full ISO 4217 table.
+var currencyExponents = map[Currency]int{
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"USD": 2,
+}
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrAmountOverflow   = errors.New("amount overflows int64 minor units")
+)
+
+func (c Currency) exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+// Money is an exact amount: an integer count of minor units (cents) plus
+// its currency. Adding integers never drifts the way float64 sums do.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney reads a plain decimal such as "12.5" or "-3.05" without ever
+// going through float64. More decimals than the currency has is an error,
+// not a silent rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	raw := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, frac, hasPoint := strings.Cut(raw, ".")
+	if whole == "" || (hasPoint && frac == "") || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var minor int64
+	for _, r := range whole + frac {
+		if minor > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney is for literals in code and tests; it panics on bad input.
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) ||
+		(other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Minor: m.Minor + other.Minor, Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or +1; amounts in different currencies do not compare.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String prints the exact decimal, e.g. "12.50 EUR".
+func (m Money) String() string {
+	exp, err := m.Currency.exponent()
+	if err != nil {
+		return fmt.Sprintf("%d minor units of %s", m.Minor, m.Currency)
+	}
+	sign := ""
+	abs := new(big.Int).SetInt64(m.Minor) // big.Int: -MinInt64 does not fit int64
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+	digits := abs.String()
+	if exp == 0 {
+		return fmt.Sprintf("%s%s %s", sign, digits, m.Currency)
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:cut], digits[cut:], m.Currency)
+}
+
+// Totals holds one exact sum per currency; nothing is converted until the
+// report asks for it.
+type Totals map[Currency]Money
+
+func (t Totals) add(m Money) error {
+	current, ok := t[m.Currency]
+	if !ok {
+		t[m.Currency] = m
+		return nil
+	}
+	sum, err := current.Add(m)
+	if err != nil {
+		return err
+	}
+	t[m.Currency] = sum
+	return nil
+}
+
+// Currencies returns the currencies present, sorted for stable output.
+func (t Totals) Currencies() []Currency {
+	out := make([]Currency, 0, len(t))
+	for c := range t {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Transaction is one statement row before it has a category. Amount is
+// positive and means money spent.
+type Transaction struct {
+	Line        int
+	Date        time.Time
+	Description string
+	Amount      Money
+}
+
+type Expense struct {
+	Date        time.Time
+	Description string
+	Category    string
+	Amount      Money
+}
+
+func ValidateExpense(e Expense) error {
+	if strings.TrimSpace(e.Category) == "" {
+		return errors.New("category is required")
+	}
+	if _, err := e.Amount.Currency.exponent(); err != nil {
+		return err
+	}
+	if e.Amount.Minor < 0 {
+		return errors.New("amount cannot be negative")
+	}
+	return nil
+}
+
+func NormalizeCategory(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+func TotalsByCategory(items []Expense) (map[string]Totals, error) {
+	out := make(map[string]Totals)
+	for _, it := range items {
+		if out[it.Category] == nil {
+			out[it.Category] = Totals{}
+		}
+		if err := out[it.Category].add(it.Amount); err != nil {
+			return nil, fmt.Errorf("category %q: %w", it.Category, err)
+		}
+	}
+	return out, nil
+}
+
+// LESSON 1: Column mappings as data
+// Why this matters: every bank names and formats its columns differently;
+// a mapping value per bank beats one parser per bank.
+type CSVMapping struct {
+	Delimiter rune // ',' when zero
+
+	// Header names, matched case-insensitively. Use either one signed
+	// Amount column or separate Debit/Credit columns.
+	Date        string
+	Description string
+	Amount      string
+	Debit       string
+	Credit      string
+	Currency    string // empty: every row is in DefaultCurrency
+
+	DefaultCurrency Currency
+	DateLayout      string // time.Parse layout, e.g. "02.01.2006"
+	DecimalSep      rune   // '.' when zero
+	ThousandsSep    rune   // optional, e.g. ',' in "1,234.56"
+
+	// Most banks export spending as negative amounts; card statements
+	// often print it positive.
+	SpendingPositive bool
+}
+
+var ErrInvalidMapping = errors.New("invalid CSV mapping")
+
+// MappingUSBank and MappingDEBank are two common export shapes.
+var (
+	MappingUSBank = CSVMapping{
+		Date: "Date", Description: "Description", Amount: "Amount",
+		DefaultCurrency: "USD", DateLayout: "01/02/2006", ThousandsSep: ',',
+	}
+	MappingDEBank = CSVMapping{
+		Delimiter: ';',
+		Date:      "Buchungstag", Description: "Verwendungszweck",
+		Debit: "Soll", Credit: "Haben", Currency: "Waehrung",
+		DefaultCurrency: "EUR", DateLayout: "02.01.2006", DecimalSep: ',', ThousandsSep: '.',
+	}
+)
+
+func (m CSVMapping) withDefaults() (CSVMapping, error) {
+	if m.Delimiter == 0 {
+		m.Delimiter = ','
+	}
+	if m.DecimalSep == 0 {
+		m.DecimalSep = '.'
+	}
+	switch {
+	case m.Date == "" || m.Description == "":
+		return m, fmt.Errorf("%w: date and description columns are required", ErrInvalidMapping)
+	case (m.Amount == "") == (m.Debit == ""):
+		return m, fmt.Errorf("%w: set either an amount column or a debit column", ErrInvalidMapping)
+	case m.Amount != "" && m.Credit != "":
+		return m, fmt.Errorf("%w: a credit column only goes with a debit column", ErrInvalidMapping)
+	case m.DateLayout == "":
+		return m, fmt.Errorf("%w: date layout is required", ErrInvalidMapping)
+	case m.DecimalSep == m.ThousandsSep:
+		return m, fmt.Errorf("%w: decimal and thousands separators must differ", ErrInvalidMapping)
+	}
+	if m.Currency == "" {
+		if _, err := m.DefaultCurrency.exponent(); err != nil {
+			return m, fmt.Errorf("%w: no currency column and default currency: %w", ErrInvalidMapping, err)
+		}
+	}
+	return m, nil
+}
+
+// LESSON 2: Resolve header names to column positions once
+// Why this matters: exports reorder columns between versions; looking
+// them up by name survives that, fixed indexes do not.
+func resolveColumns(header []string, names ...string) (map[string]int, error) {
+	positions := map[string]int{}
+	for i, name := range header {
+		// Spreadsheets like to save a byte order mark in front of the header.
+		positions[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	columns := map[string]int{}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		i, ok := positions[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w: column %q not in header", ErrInvalidMapping, name)
+		}
+		columns[name] = i
+	}
+	return columns, nil
+}
+
+// LESSON 3: Dates with an explicit layout
+// Why this matters: "03/04/2026" is March in the US and April in Europe;
+// guessing is how a statement ends up a month off.
+func parseStatementDate(raw, layout string) (time.Time, error) {
+	d, err := time.Parse(layout, strings.TrimSpace(raw))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q does not match layout %q", raw, layout)
+	}
+	return d, nil
+}
+
+// LESSON 4: Amounts in local formats
+// Why this matters: "1.234,56" and "1,234.56" are the same number; turn
+// both into the plain decimal ParseMoney expects, never into a float.
+// Accounting-style "(12.50)" means negative.
+func ParseLocalAmount(raw string, decimalSep, thousandsSep rune, currency Currency) (Money, error) {
+	if _, err := currency.exponent(); err != nil {
+		return Money{}, err
+	}
+	s := strings.TrimSpace(raw)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	if thousandsSep != 0 && strings.ContainsRune(s, thousandsSep) {
+		// "12,50" read with ',' as thousands separator must not become
+		// 1250: every group after the first has exactly three digits.
+		whole, _, _ := strings.Cut(s, string(decimalSep))
+		for _, group := range strings.Split(whole, string(thousandsSep))[1:] {
+			if len(group) != 3 {
+				return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+			}
+		}
+		s = strings.ReplaceAll(s, string(thousandsSep), "")
+	}
+	s = strings.ReplaceAll(s, string(decimalSep), ".")
+	if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	if negative {
+		if strings.HasPrefix(s, "-") {
+			return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+		}
+		s = "-" + s
+	}
+	m, err := ParseMoney(s, currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+	return m, nil
+}
+
+// LESSON 5: Import rows, collecting row errors
+// Why this matters: one bad row should not throw away a 500-row
+// statement; report it by line and keep going. Incoming money (salary,
+// refunds) is counted as skipped, not as an expense.
+type ImportRowError struct {
+	Line    int
+	Message string
+}
+
+type ImportResult struct {
+	Transactions []Transaction
+	Skipped      int
+	Errors       []ImportRowError
+}
+
+func ImportCSV(r io.Reader, mapping CSVMapping) (ImportResult, error) {
+	m, err := mapping.withDefaults()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = m.Delimiter
+	reader.FieldsPerRecord = -1 // row width is checked against the header below
+	header, err := reader.Read()
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("%w: header: %w", ErrInvalidMapping, err)
+	}
+	columns, err := resolveColumns(header, m.Date, m.Description, m.Amount, m.Debit, m.Credit, m.Currency)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{Transactions: []Transaction{}, Errors: []ImportRowError{}}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// After a quoting error the reader may be out of step with the
+			// rows, so nothing after it can be trusted.
+			result.Errors = append(result.Errors, ImportRowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			break
+		}
+		if err != nil {
+			return ImportResult{}, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) != len(header) {
+			result.Errors = append(result.Errors, ImportRowError{Line: line, Message: fmt.Sprintf("want %d fields, got %d", len(header), len(row))})
+			continue
+		}
+		tx, spending, err := parseStatementRow(row, columns, m)
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		if !spending {
+			result.Skipped++
+			continue
+		}
+		tx.Line = line
+		result.Transactions = append(result.Transactions, tx)
+	}
+	return result, nil
+}
+
+func parseStatementRow(row []string, columns map[string]int, m CSVMapping) (Transaction, bool, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	date, err := parseStatementDate(field(m.Date), m.DateLayout)
+	if err != nil {
+		return Transaction{}, false, err
+	}
+	currency := m.DefaultCurrency
+	if raw := field(m.Currency); raw != "" {
+		currency = Currency(strings.ToUpper(raw))
+	}
+	tx := Transaction{Date: date, Description: field(m.Description)}
+
+	if m.Amount != "" {
+		amount, err := ParseLocalAmount(field(m.Amount), m.DecimalSep, m.ThousandsSep, currency)
+		if err != nil {
+			return Transaction{}, false, err
+		}
+		if !m.SpendingPositive {
+			amount.Minor = -amount.Minor
+		}
+		tx.Amount = amount
+		return tx, amount.Minor > 0, nil
+	}
+
+	debit, credit := field(m.Debit), field(m.Credit)
+	switch {
+	case debit != "":
+		amount, err := ParseLocalAmount(debit, m.DecimalSep, m.ThousandsSep, currency)
+		if err != nil {
+			return Transaction{}, false, err
+		}
+		if amount.Minor < 0 { // some banks sign the debit column too
+			amount.Minor = -amount.Minor
+		}
+		tx.Amount = amount
+		return tx, amount.Minor > 0, nil
+	case credit != "":
+		return tx, false, nil
+	default:
+		return Transaction{}, false, errors.New("neither debit nor credit is set")
+	}
+}
+
+// LESSON 6: Rules that match a transaction
+// Why this matters: "REWE" in the description, or any 2.80 EUR charge,
+// says "groceries" or "transport" better than a person typing it monthly.
+// Every condition that is set must match.
+type Rule struct {
+	Name     string
+	Category string
+	Priority int    // higher runs first; ties keep declaration order
+	Contains string // case-insensitive substring of the description
+	Pattern  string // regular expression on the description
+	Min, Max *Money // inclusive; a rule never matches another currency
+}
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+type compiledRule struct {
+	Rule
+	contains string
+	re       *regexp.Regexp
+}
+
+func (r compiledRule) matches(tx Transaction) bool {
+	if r.contains != "" && !strings.Contains(strings.ToLower(tx.Description), r.contains) {
+		return false
+	}
+	if r.re != nil && !r.re.MatchString(tx.Description) {
+		return false
+	}
+	if r.Min != nil {
+		if cmp, err := tx.Amount.Cmp(*r.Min); err != nil || cmp < 0 {
+			return false
+		}
+	}
+	if r.Max != nil {
+		if cmp, err := tx.Amount.Cmp(*r.Max); err != nil || cmp > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LESSON 7: Validate and order rules up front
+// Why this matters: a broken regex or a rule with no condition should fail
+// at startup, not silently match everything at import time.
+type Categorizer struct {
+	rules []compiledRule
+}
+
+func NewCategorizer(rules ...Rule) (*Categorizer, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		if NormalizeCategory(r.Category) == "" {
+			return nil, fmt.Errorf("%w: %s: category is required", ErrInvalidRule, name)
+		}
+		if r.Contains == "" && r.Pattern == "" && r.Min == nil && r.Max == nil {
+			return nil, fmt.Errorf("%w: %s: needs at least one condition", ErrInvalidRule, name)
+		}
+		c := compiledRule{Rule: r, contains: strings.ToLower(r.Contains)}
+		c.Name = name
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidRule, name, err)
+			}
+			c.re = re
+		}
+		if r.Min != nil && r.Max != nil {
+			if cmp, err := r.Min.Cmp(*r.Max); err != nil || cmp > 0 {
+				return nil, fmt.Errorf("%w: %s: min must not exceed max in the same currency", ErrInvalidRule, name)
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	sort.SliceStable(compiled, func(i, j int) bool { return compiled[i].Priority > compiled[j].Priority })
+	return &Categorizer{rules: compiled}, nil
+}
+
+// Categorize returns the first matching rule's category, then normalizes
+// it the same way hand-typed categories are.
+func (c *Categorizer) Categorize(tx Transaction) (Expense, string, bool) {
+	for _, r := range c.rules {
+		if r.matches(tx) {
+			return Expense{
+				Date:        tx.Date,
+				Description: tx.Description,
+				Category:    NormalizeCategory(r.Category),
+				Amount:      tx.Amount,
+			}, r.Name, true
+		}
+	}
+	return Expense{}, "", false
+}
+
+// LESSON 8: Categorize a whole import and report what is left over
+// Why this matters: unmatched rows are the to-do list for new rules.
+type CategorizeResult struct {
+	Expenses  []Expense
+	RuleHits  map[string]int
+	Unmatched []Transaction
+}
+
+func (c *Categorizer) CategorizeAll(txs []Transaction) (CategorizeResult, error) {
+	result := CategorizeResult{Expenses: []Expense{}, RuleHits: map[string]int{}, Unmatched: []Transaction{}}
+	for _, tx := range txs {
+		e, rule, ok := c.Categorize(tx)
+		if !ok {
+			result.Unmatched = append(result.Unmatched, tx)
+			continue
+		}
+		if err := ValidateExpense(e); err != nil {
+			return CategorizeResult{}, fmt.Errorf("line %d: %w", tx.Line, err)
+		}
+		result.RuleHits[rule]++
+		result.Expenses = append(result.Expenses, e)
+	}
+	return result, nil
+}
+
+// LESSON 9: Import report
+// Why this matters: the person running the import needs to see totals,
+// rejected lines and unmatched rows in one place.
+func BuildImportReport(imported ImportResult, categorized CategorizeResult) ([]string, error) {
+	totals, err := TotalsByCategory(categorized.Expenses)
+	if err != nil {
+		return nil, err
+	}
+	categories := make([]string, 0, len(totals))
+	for k := range totals {
+		categories = append(categories, k)
+	}
+	sort.Strings(categories)
+
+	lines := []string{fmt.Sprintf("imported %d, skipped %d incoming, %d rejected, %d unmatched",
+		len(imported.Transactions), imported.Skipped, len(imported.Errors), len(categorized.Unmatched))}
+	for _, k := range categories {
+		parts := []string{}
+		for _, c := range totals[k].Currencies() {
+			parts = append(parts, totals[k][c].String())
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", k, strings.Join(parts, " + ")))
+	}
+	for _, e := range imported.Errors {
+		lines = append(lines, fmt.Sprintf("rejected line %d: %s", e.Line, e.Message))
+	}
+	for _, tx := range categorized.Unmatched {
+		lines = append(lines, fmt.Sprintf("unmatched line %d: %s %q %s", tx.Line, tx.Date.Format(time.DateOnly), tx.Description, tx.Amount))
+	}
+	return lines, nil
+}
+
+// LESSON 10: End-to-end sample
+// Why this matters: two banks, one set of rules, one report.
+func main() {
+	de := strings.Join([]string{
+		"Buchungstag;Verwendungszweck;Soll;Haben;Waehrung",
+		"01.10.2026;REWE Markt Berlin;-54,20;;EUR",
+		"02.10.2026;Gehalt Oktober;;3.100,00;EUR",
+		"03.10.2026;BVG Ticket;2,80;;EUR",
+		"05.10.2026;Miete Oktober;1.150,00;;EUR",
+		"07.10.2026;Kiosk am Eck;4,50;;EUR",
+		"31.09.2026;REWE Markt Berlin;12,00;;EUR",
+	}, "\n")
+	us := strings.Join([]string{
+		"Date,Description,Amount",
+		`10/08/2026,"WHOLE FOODS #123, NYC",-86.10`,
+		"10/09/2026,Uber *Trip,-23.45",
+		"10/10/2026,Refund Uber,12.00",
+		`10/11/2026,Hotel Booking,"-1,240.00"`,
+		"10/12/2026,Coffee,-3.5O",
+	}, "\n")
+
+	two := MustParseMoney("2.80", "EUR")
+	categorizer, err := NewCategorizer(
+		Rule{Name: "groceries", Category: "Food", Pattern: `(?i)\b(rewe|whole foods|edeka)\b`},
+		Rule{Name: "rent", Category: "Housing", Contains: "miete", Priority: 10},
+		Rule{Name: "transit fare", Category: "Transport", Min: &two, Max: &two},
+		Rule{Name: "rides", Category: "Transport", Contains: "uber"},
+		Rule{Name: "hotels", Category: "Travel", Pattern: `(?i)hotel`},
+	)
+	if err != nil {
+		fmt.Println("Lesson 10 rules error:", err)
+		return
+	}
+
+	for _, input := range []struct {
+		name    string
+		csv     string
+		mapping CSVMapping
+	}{
+		{"DE bank", de, MappingDEBank},
+		{"US bank", us, MappingUSBank},
+	} {
+		imported, err := ImportCSV(strings.NewReader(input.csv), input.mapping)
+		if err != nil {
+			fmt.Println("Lesson 10 import error:", err)
+			return
+		}
+		categorized, err := categorizer.CategorizeAll(imported.Transactions)
+		if err != nil {
+			fmt.Println("Lesson 10 categorize error:", err)
+			return
+		}
+		lines, err := BuildImportReport(imported, categorized)
+		if err != nil {
+			fmt.Println("Lesson 10 report error:", err)
+			return
+		}
+		fmt.Println("Lesson 10", input.name+":")
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	}
+}
+
+// End of Go Expense Import 1-10
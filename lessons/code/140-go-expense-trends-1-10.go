@@ -0,0 +1,707 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+GO EXPENSE TRENDS (Lessons 1-10)
+
+Suggested use:
+1) Run: go run lessons/code/140-go-expense-trends-1-10.go
+2) Switch BucketSeries to GranularityWeek and predict how many buckets
+   each series gets before running
+3) Lower the anomaly multiple to "1.1" and see which categories get flagged
+
+Extra context:
+- lessons/notes/155-go-projects-principles.md
+- lessons/notes/204-money-as-integers-first-principles.md
+- lessons/notes/207-expense-trends-first-principles.md
+*/
+
+// Currency is an ISO 4217 code such as "EUR".
+type Currency string
+
+// currencyExponents says how many minor units a currency has (cents for
+// EUR, none for JPY). Only listed currencies are accepted; a real system
+// would load the full ISO 4217 table.
+var currencyExponents = map[Currency]int{
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"USD": 2,
+}
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrAmountOverflow   = errors.New("amount overflows int64 minor units")
+	ErrMissingRate      = errors.New("no exchange rate")
+)
+
+func (c Currency) exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+// Money is an exact amount: an integer count of minor units (cents) plus
+// its currency. Adding integers never drifts the way float64 sums do.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney reads a plain decimal such as "12.5" or "-3.05" without ever
+// going through float64. More decimals than the currency has is an error,
+// not a silent rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	raw := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, frac, hasPoint := strings.Cut(raw, ".")
+	if whole == "" || (hasPoint && frac == "") || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var minor int64
+	for _, r := range whole + frac {
+		if minor > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney is for literals in code and tests; it panics on bad input.
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) ||
+		(other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Minor: m.Minor + other.Minor, Currency: m.Currency}, nil
+}
+
+// Sub is Add with the sign flipped; a delta is negative when spending fell.
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Minor == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(Money{Minor: -other.Minor, Currency: other.Currency})
+}
+
+// Cmp returns -1, 0 or +1; amounts in different currencies do not compare.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String prints the exact decimal, e.g. "12.50 EUR".
+func (m Money) String() string {
+	exp, err := m.Currency.exponent()
+	if err != nil {
+		return fmt.Sprintf("%d minor units of %s", m.Minor, m.Currency)
+	}
+	sign := ""
+	abs := new(big.Int).SetInt64(m.Minor) // big.Int: -MinInt64 does not fit int64
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+	digits := abs.String()
+	if exp == 0 {
+		return fmt.Sprintf("%s%s %s", sign, digits, m.Currency)
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:cut], digits[cut:], m.Currency)
+}
+
+// RateTable converts into one reporting currency. A rate says how many
+// units of the base one unit of the currency is worth ("USD": "0.92" with
+// base EUR). Rates are exact decimals held as big.Rat, never float64.
+type RateTable struct {
+	base  Currency
+	rates map[Currency]*big.Rat
+}
+
+func NewRateTable(base Currency, rates map[Currency]string) (*RateTable, error) {
+	if _, err := base.exponent(); err != nil {
+		return nil, err
+	}
+	t := &RateTable{base: base, rates: map[Currency]*big.Rat{base: big.NewRat(1, 1)}}
+	for currency, raw := range rates {
+		if _, err := currency.exponent(); err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(raw))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate for %s must be a positive decimal, got %q", currency, raw)
+		}
+		t.rates[currency] = rate
+	}
+	return t, nil
+}
+
+func (t *RateTable) Base() Currency {
+	return t.base
+}
+
+// Convert rounds once, half away from zero, to the target's minor unit.
+// Callers should convert sums rather than sum conversions, so that only
+// one rounding happens per currency.
+func (t *RateTable) Convert(m Money, to Currency) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	if t == nil {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, to)
+	}
+	fromExp, err := m.Currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	toExp, err := to.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	fromRate, ok := t.rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, t.base)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, to, t.base)
+	}
+	// minor / 10^fromExp major units, times fromRate/toRate, times 10^toExp.
+	value := new(big.Rat).SetInt64(m.Minor)
+	value.Mul(value, fromRate)
+	value.Quo(value, toRate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toExp), pow10(fromExp)))
+
+	minor, err := roundHalfAway(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: to}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func roundHalfAway(r *big.Rat) (int64, error) {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Denom is always positive, so rem carries the sign of the value.
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return q.Int64(), nil
+}
+
+// Expense carries the day it happened; every trend is built from dates,
+// so an undated expense cannot be placed in any bucket.
+type Expense struct {
+	Date     time.Time
+	Category string
+	Amount   Money
+}
+
+// Totals holds one exact sum per currency; nothing is converted until the
+// report asks for it.
+type Totals map[Currency]Money
+
+func (t Totals) add(m Money) error {
+	current, ok := t[m.Currency]
+	if !ok {
+		t[m.Currency] = m
+		return nil
+	}
+	sum, err := current.Add(m)
+	if err != nil {
+		return err
+	}
+	t[m.Currency] = sum
+	return nil
+}
+
+// Currencies returns the currencies present, sorted for stable output.
+func (t Totals) Currencies() []Currency {
+	out := make([]Currency, 0, len(t))
+	for c := range t {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// In converts each currency's sum once and adds the results.
+func (t Totals) In(rates *RateTable, to Currency) (Money, error) {
+	total := Money{Currency: to}
+	for _, c := range t.Currencies() {
+		converted, err := rates.Convert(t[c], to)
+		if err != nil {
+			return Money{}, err
+		}
+		if total, err = total.Add(converted); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// ValidateExpense is the check from the projects lesson plus a date.
+func ValidateExpense(e Expense) error {
+	if e.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	if strings.TrimSpace(e.Category) == "" {
+		return errors.New("category is required")
+	}
+	if _, err := e.Amount.Currency.exponent(); err != nil {
+		return err
+	}
+	if e.Amount.Minor < 0 {
+		return errors.New("amount cannot be negative")
+	}
+	return nil
+}
+
+func NormalizeCategory(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+// LESSON 1: Buckets by day, week or month
+// Why this matters: a trend is a sum per bucket; the bucket boundaries
+// decide which month an expense at 23:30 on the 31st belongs to.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week"
+	GranularityMonth Granularity = "month"
+)
+
+var ErrUnknownGranularity = errors.New("unknown granularity")
+
+// Start returns the first instant of the bucket containing t, in t's
+// location. Weeks start on Monday (ISO 8601).
+func (g Granularity) Start(t time.Time) (time.Time, error) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch g {
+	case GranularityDay:
+		return day, nil
+	case GranularityWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %q", ErrUnknownGranularity, string(g))
+	}
+}
+
+// next moves a bucket start to the following bucket start. AddDate works
+// on calendar dates, so DST days and short months need no special case.
+func (g Granularity) next(start time.Time) time.Time {
+	switch g {
+	case GranularityDay:
+		return start.AddDate(0, 0, 1)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// LESSON 2: Series with the empty buckets filled in
+// Why this matters: a month with no spending is a zero, not a missing
+// point; skipping it would make averages and deltas lie.
+type Bucket struct {
+	Start time.Time
+	Total Money
+}
+
+type Series struct {
+	Category string
+	Buckets  []Bucket
+}
+
+// LESSON 3: Sum per currency, convert once per bucket
+// Why this matters: the same rule as the category report; rounding happens
+// once per bucket and currency, not once per expense.
+//
+// BucketSeries returns one series per category, sorted by name. All series
+// cover the same buckets, from the earliest expense up to the bucket that
+// contains asOf, so they line up as columns. Expenses dated after asOf
+// are left out.
+func BucketSeries(items []Expense, g Granularity, asOf time.Time, rates *RateTable, to Currency) ([]Series, error) {
+	last, err := g.Start(asOf)
+	if err != nil {
+		return nil, err
+	}
+	first := last
+	cutoff := time.Date(asOf.Year(), asOf.Month(), asOf.Day()+1, 0, 0, 0, 0, asOf.Location())
+	sums := map[string]map[string]Totals{} // category -> bucket date -> sums
+	for _, e := range items {
+		e.Category = NormalizeCategory(e.Category)
+		if err := ValidateExpense(e); err != nil {
+			return nil, err
+		}
+		if !e.Date.Before(cutoff) {
+			continue // after asOf: not part of the history yet
+		}
+		start, _ := g.Start(e.Date.In(asOf.Location()))
+		if start.Before(first) {
+			first = start
+		}
+		key := start.Format(time.DateOnly)
+		if sums[e.Category] == nil {
+			sums[e.Category] = map[string]Totals{}
+		}
+		if sums[e.Category][key] == nil {
+			sums[e.Category][key] = Totals{}
+		}
+		if err := sums[e.Category][key].add(e.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	categories := make([]string, 0, len(sums))
+	for c := range sums {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	out := make([]Series, 0, len(categories))
+	for _, c := range categories {
+		s := Series{Category: c}
+		for start := first; !start.After(last); start = g.next(start) {
+			total, err := sums[c][start.Format(time.DateOnly)].In(rates, to)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", c, start.Format(time.DateOnly), err)
+			}
+			s.Buckets = append(s.Buckets, Bucket{Start: start, Total: total})
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// LESSON 4: Period-over-period deltas
+// Why this matters: "food +35% vs last month" is what people read first.
+type Delta struct {
+	Start    time.Time
+	Previous Money
+	Current  Money
+	Change   Money
+}
+
+// Deltas compares each bucket with the one before it; with monthly
+// buckets this is month over month.
+func Deltas(s Series) ([]Delta, error) {
+	out := []Delta{}
+	for i := 1; i < len(s.Buckets); i++ {
+		prev, cur := s.Buckets[i-1].Total, s.Buckets[i].Total
+		change, err := cur.Sub(prev)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Delta{Start: s.Buckets[i].Start, Previous: prev, Current: cur, Change: change})
+	}
+	return out, nil
+}
+
+// LESSON 5: Percent change without floats
+// Why this matters: growth from zero has no percentage; printing "+Inf%"
+// or "NaN%" is worse than saying "new".
+func (d Delta) Percent() string {
+	if d.Previous.Minor == 0 {
+		if d.Current.Minor == 0 {
+			return "0.0%"
+		}
+		return "new"
+	}
+	r := big.NewRat(d.Change.Minor, d.Previous.Minor)
+	r.Mul(r, big.NewRat(100, 1))
+	sign := ""
+	if r.Sign() > 0 {
+		sign = "+"
+	}
+	return sign + r.FloatString(1) + "%"
+}
+
+// LESSON 6: Rolling average
+// Why this matters: one expensive month should not look like a trend;
+// averaging the last few buckets smooths it out.
+//
+// RollingAverage returns one bucket per full window, dated like the last
+// bucket of that window, each rounded once, half away from zero.
+func RollingAverage(s Series, window int) ([]Bucket, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive, got %d", window)
+	}
+	out := []Bucket{}
+	sum := new(big.Int)
+	for i, b := range s.Buckets {
+		sum.Add(sum, big.NewInt(b.Total.Minor))
+		if i >= window {
+			sum.Sub(sum, big.NewInt(s.Buckets[i-window].Total.Minor))
+		}
+		if i < window-1 {
+			continue
+		}
+		avg, err := roundHalfAway(new(big.Rat).SetFrac(sum, big.NewInt(int64(window))))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Bucket{Start: b.Start, Total: Money{Minor: avg, Currency: b.Total.Currency}})
+	}
+	return out, nil
+}
+
+// LESSON 7: Anomaly settings
+// Why this matters: "unusual" needs a definition people can tune: how
+// much above normal, measured against how much history.
+type AnomalyConfig struct {
+	Multiple   string // current > Multiple × historical average, e.g. "1.5"
+	Lookback   int    // buckets of history to average; 0 means all
+	MinHistory int    // fewer buckets than this: too early to judge
+}
+
+type anomalyRule struct {
+	multiple   *big.Rat
+	lookback   int
+	minHistory int
+}
+
+func (c AnomalyConfig) compile() (anomalyRule, error) {
+	multiple, ok := new(big.Rat).SetString(strings.TrimSpace(c.Multiple))
+	if !ok || multiple.Cmp(big.NewRat(1, 1)) < 0 {
+		return anomalyRule{}, fmt.Errorf("multiple must be a decimal of at least 1, got %q", c.Multiple)
+	}
+	if c.Lookback < 0 || c.MinHistory < 1 {
+		return anomalyRule{}, fmt.Errorf("lookback must be >= 0 and min history >= 1, got %d and %d", c.Lookback, c.MinHistory)
+	}
+	if c.Lookback > 0 && c.Lookback < c.MinHistory {
+		return anomalyRule{}, fmt.Errorf("lookback %d can never reach min history %d", c.Lookback, c.MinHistory)
+	}
+	return anomalyRule{multiple: multiple, lookback: c.Lookback, minHistory: c.MinHistory}, nil
+}
+
+// LESSON 8: Flag the current bucket against its history
+// Why this matters: the comparison uses the exact average (a fraction),
+// so a category is flagged or not regardless of how averages round.
+type Anomaly struct {
+	Category string
+	Start    time.Time
+	Current  Money
+	Average  Money // rounded, for display only
+	Ratio    string
+}
+
+// DetectAnomalies looks at the last bucket of each series, which is the
+// one containing asOf and may still be in progress.
+func DetectAnomalies(series []Series, cfg AnomalyConfig) ([]Anomaly, error) {
+	rule, err := cfg.compile()
+	if err != nil {
+		return nil, err
+	}
+	out := []Anomaly{}
+	for _, s := range series {
+		if len(s.Buckets) == 0 {
+			continue
+		}
+		current := s.Buckets[len(s.Buckets)-1]
+		history := s.Buckets[:len(s.Buckets)-1]
+		if rule.lookback > 0 && len(history) > rule.lookback {
+			history = history[len(history)-rule.lookback:]
+		}
+		if len(history) < rule.minHistory {
+			continue
+		}
+		sum := new(big.Int)
+		for _, b := range history {
+			sum.Add(sum, big.NewInt(b.Total.Minor))
+		}
+		average := new(big.Rat).SetFrac(sum, big.NewInt(int64(len(history))))
+		threshold := new(big.Rat).Mul(average, rule.multiple)
+		if new(big.Rat).SetInt64(current.Total.Minor).Cmp(threshold) <= 0 {
+			continue
+		}
+		rounded, err := roundHalfAway(average)
+		if err != nil {
+			return nil, err
+		}
+		ratio := "new"
+		if average.Sign() > 0 {
+			r := new(big.Rat).Quo(new(big.Rat).SetInt64(current.Total.Minor), average)
+			ratio = r.FloatString(1) + "x"
+		}
+		out = append(out, Anomaly{
+			Category: s.Category,
+			Start:    current.Start,
+			Current:  current.Total,
+			Average:  Money{Minor: rounded, Currency: current.Total.Currency},
+			Ratio:    ratio,
+		})
+	}
+	return out, nil
+}
+
+// LESSON 9: Trend report lines
+// Why this matters: one line per category with the latest change, the
+// smoothed level and a flag is enough to spot what moved.
+func BuildTrendLines(series []Series, window int, cfg AnomalyConfig) ([]string, error) {
+	anomalies, err := DetectAnomalies(series, cfg)
+	if err != nil {
+		return nil, err
+	}
+	flagged := map[string]Anomaly{}
+	for _, a := range anomalies {
+		flagged[a.Category] = a
+	}
+	lines := []string{}
+	for _, s := range series {
+		if len(s.Buckets) == 0 {
+			continue
+		}
+		latest := s.Buckets[len(s.Buckets)-1]
+		line := fmt.Sprintf("- %s %s: %s", s.Category, latest.Start.Format(time.DateOnly), latest.Total)
+		deltas, err := Deltas(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(deltas) > 0 {
+			d := deltas[len(deltas)-1]
+			line += fmt.Sprintf(" (%s, %s vs previous)", signed(d.Change), d.Percent())
+		}
+		averages, err := RollingAverage(s, window)
+		if err != nil {
+			return nil, err
+		}
+		if len(averages) > 0 {
+			line += fmt.Sprintf(", %d-bucket average %s", window, averages[len(averages)-1].Total)
+		}
+		if a, ok := flagged[s.Category]; ok {
+			line += fmt.Sprintf(" [ANOMALY: %s of the %s average]", a.Ratio, a.Average)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func signed(m Money) string {
+	if m.Minor > 0 {
+		return "+" + m.String()
+	}
+	return m.String()
+}
+
+// LESSON 10: End-to-end sample
+// Why this matters: six months of history, one report, one anomaly.
+func main() {
+	rates, err := NewRateTable("EUR", map[Currency]string{"USD": "0.92"})
+	if err != nil {
+		fmt.Println("Lesson 10 rates error:", err)
+		return
+	}
+	on := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	items := []Expense{
+		{Date: on(time.May, 3), Category: "food", Amount: MustParseMoney("310", "EUR")},
+		{Date: on(time.June, 2), Category: "food", Amount: MustParseMoney("295.40", "EUR")},
+		{Date: on(time.July, 8), Category: "food", Amount: MustParseMoney("330", "EUR")},
+		{Date: on(time.August, 1), Category: "food", Amount: MustParseMoney("280.10", "EUR")},
+		{Date: on(time.September, 5), Category: "food", Amount: MustParseMoney("301.25", "EUR")},
+		{Date: on(time.October, 9), Category: "Food", Amount: MustParseMoney("280", "EUR")},
+		{Date: on(time.October, 14), Category: "food", Amount: MustParseMoney("45.50", "USD")},
+		{Date: on(time.May, 20), Category: "travel", Amount: MustParseMoney("120", "EUR")},
+		{Date: on(time.August, 11), Category: "travel", Amount: MustParseMoney("95", "EUR")},
+		{Date: on(time.October, 2), Category: "travel", Amount: MustParseMoney("640", "USD")},
+		{Date: on(time.September, 30), Category: "transport", Amount: MustParseMoney("49", "EUR")},
+		{Date: on(time.October, 30), Category: "transport", Amount: MustParseMoney("49", "EUR")}, // after asOf
+	}
+	asOf := on(time.October, 16)
+
+	series, err := BucketSeries(items, GranularityMonth, asOf, rates, "EUR")
+	if err != nil {
+		fmt.Println("Lesson 10 bucket error:", err)
+		return
+	}
+	for _, s := range series[:1] {
+		for _, b := range s.Buckets {
+			fmt.Printf("Lesson 3 %s %s: %s\n", s.Category, b.Start.Format("2006-01"), b.Total)
+		}
+	}
+
+	lines, err := BuildTrendLines(series, 3, AnomalyConfig{Multiple: "1.5", Lookback: 6, MinHistory: 3})
+	if err != nil {
+		fmt.Println("Lesson 10 trend error:", err)
+		return
+	}
+	fmt.Println("Lesson 10 trends as of", asOf.Format(time.DateOnly)+":")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// End of Go Expense Trends 1-10
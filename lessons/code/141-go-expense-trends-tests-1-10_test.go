@@ -0,0 +1,874 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+GO EXPENSE TRENDS TESTS (Lessons 1-10)
+
+Suggested use:
+1) Run: go test lessons/code/141-go-expense-trends-tests-1-10_test.go -run TestLesson -v
+2) Why this command is file-specific: lesson files are standalone by design
+
+Extra context:
+- lessons/notes/204-money-as-integers-first-principles.md
+- lessons/notes/207-expense-trends-first-principles.md
+*/
+
+// Currency is an ISO 4217 code such as "EUR".
+type Currency string
+
+// currencyExponents says how many minor units a currency has (cents for
+// EUR, none for JPY). Only listed currencies are accepted; a real system
+// would load the full ISO 4217 table.
+var currencyExponents = map[Currency]int{
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"USD": 2,
+}
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrAmountOverflow   = errors.New("amount overflows int64 minor units")
+	ErrMissingRate      = errors.New("no exchange rate")
+)
+
+func (c Currency) exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+// Money is an exact amount: an integer count of minor units (cents) plus
+// its currency. Adding integers never drifts the way float64 sums do.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney reads a plain decimal such as "12.5" or "-3.05" without ever
+// going through float64. More decimals than the currency has is an error,
+// not a silent rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	raw := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, frac, hasPoint := strings.Cut(raw, ".")
+	if whole == "" || (hasPoint && frac == "") || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var minor int64
+	for _, r := range whole + frac {
+		if minor > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney is for literals in code and tests; it panics on bad input.
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) ||
+		(other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Minor: m.Minor + other.Minor, Currency: m.Currency}, nil
+}
+
+// Sub is Add with the sign flipped; a delta is negative when spending fell.
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Minor == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(Money{Minor: -other.Minor, Currency: other.Currency})
+}
+
+// Cmp returns -1, 0 or +1; amounts in different currencies do not compare.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String prints the exact decimal, e.g. "12.50 EUR".
+func (m Money) String() string {
+	exp, err := m.Currency.exponent()
+	if err != nil {
+		return fmt.Sprintf("%d minor units of %s", m.Minor, m.Currency)
+	}
+	sign := ""
+	abs := new(big.Int).SetInt64(m.Minor) // big.Int: -MinInt64 does not fit int64
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+	digits := abs.String()
+	if exp == 0 {
+		return fmt.Sprintf("%s%s %s", sign, digits, m.Currency)
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:cut], digits[cut:], m.Currency)
+}
+
+// RateTable converts into one reporting currency. A rate says how many
+// units of the base one unit of the currency is worth ("USD": "0.92" with
+// base EUR). Rates are exact decimals held as big.Rat, never float64.
+type RateTable struct {
+	base  Currency
+	rates map[Currency]*big.Rat
+}
+
+func NewRateTable(base Currency, rates map[Currency]string) (*RateTable, error) {
+	if _, err := base.exponent(); err != nil {
+		return nil, err
+	}
+	t := &RateTable{base: base, rates: map[Currency]*big.Rat{base: big.NewRat(1, 1)}}
+	for currency, raw := range rates {
+		if _, err := currency.exponent(); err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(raw))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate for %s must be a positive decimal, got %q", currency, raw)
+		}
+		t.rates[currency] = rate
+	}
+	return t, nil
+}
+
+func (t *RateTable) Base() Currency {
+	return t.base
+}
+
+// Convert rounds once, half away from zero, to the target's minor unit.
+// Callers should convert sums rather than sum conversions, so that only
+// one rounding happens per currency.
+func (t *RateTable) Convert(m Money, to Currency) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	if t == nil {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, to)
+	}
+	fromExp, err := m.Currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	toExp, err := to.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	fromRate, ok := t.rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, t.base)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, to, t.base)
+	}
+	// minor / 10^fromExp major units, times fromRate/toRate, times 10^toExp.
+	value := new(big.Rat).SetInt64(m.Minor)
+	value.Mul(value, fromRate)
+	value.Quo(value, toRate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toExp), pow10(fromExp)))
+
+	minor, err := roundHalfAway(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: to}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func roundHalfAway(r *big.Rat) (int64, error) {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Denom is always positive, so rem carries the sign of the value.
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return q.Int64(), nil
+}
+
+// Expense carries the day it happened; every trend is built from dates,
+// so an undated expense cannot be placed in any bucket.
+type Expense struct {
+	Date     time.Time
+	Category string
+	Amount   Money
+}
+
+// Totals holds one exact sum per currency; nothing is converted until the
+// report asks for it.
+type Totals map[Currency]Money
+
+func (t Totals) add(m Money) error {
+	current, ok := t[m.Currency]
+	if !ok {
+		t[m.Currency] = m
+		return nil
+	}
+	sum, err := current.Add(m)
+	if err != nil {
+		return err
+	}
+	t[m.Currency] = sum
+	return nil
+}
+
+// Currencies returns the currencies present, sorted for stable output.
+func (t Totals) Currencies() []Currency {
+	out := make([]Currency, 0, len(t))
+	for c := range t {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// In converts each currency's sum once and adds the results.
+func (t Totals) In(rates *RateTable, to Currency) (Money, error) {
+	total := Money{Currency: to}
+	for _, c := range t.Currencies() {
+		converted, err := rates.Convert(t[c], to)
+		if err != nil {
+			return Money{}, err
+		}
+		if total, err = total.Add(converted); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// ValidateExpense is the check from the projects lesson plus a date.
+func ValidateExpense(e Expense) error {
+	if e.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	if strings.TrimSpace(e.Category) == "" {
+		return errors.New("category is required")
+	}
+	if _, err := e.Amount.Currency.exponent(); err != nil {
+		return err
+	}
+	if e.Amount.Minor < 0 {
+		return errors.New("amount cannot be negative")
+	}
+	return nil
+}
+
+func NormalizeCategory(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+// decide which month an expense at 23:30 on the 31st belongs to.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week"
+	GranularityMonth Granularity = "month"
+)
+
+var ErrUnknownGranularity = errors.New("unknown granularity")
+
+// Start returns the first instant of the bucket containing t, in t's
+// location. Weeks start on Monday (ISO 8601).
+func (g Granularity) Start(t time.Time) (time.Time, error) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch g {
+	case GranularityDay:
+		return day, nil
+	case GranularityWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %q", ErrUnknownGranularity, string(g))
+	}
+}
+
+// next moves a bucket start to the following bucket start. AddDate works
+// on calendar dates, so DST days and short months need no special case.
+func (g Granularity) next(start time.Time) time.Time {
+	switch g {
+	case GranularityDay:
+		return start.AddDate(0, 0, 1)
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// point; skipping it would make averages and deltas lie.
+type Bucket struct {
+	Start time.Time
+	Total Money
+}
+
+type Series struct {
+	Category string
+	Buckets  []Bucket
+}
+
+// once per bucket and currency, not once per expense.
+//
+// BucketSeries returns one series per category, sorted by name. All series
+// cover the same buckets, from the earliest expense up to the bucket that
+// contains asOf, so they line up as columns. Expenses dated after asOf
+// are left out.
+func BucketSeries(items []Expense, g Granularity, asOf time.Time, rates *RateTable, to Currency) ([]Series, error) {
+	last, err := g.Start(asOf)
+	if err != nil {
+		return nil, err
+	}
+	first := last
+	cutoff := time.Date(asOf.Year(), asOf.Month(), asOf.Day()+1, 0, 0, 0, 0, asOf.Location())
+	sums := map[string]map[string]Totals{} // category -> bucket date -> sums
+	for _, e := range items {
+		e.Category = NormalizeCategory(e.Category)
+		if err := ValidateExpense(e); err != nil {
+			return nil, err
+		}
+		if !e.Date.Before(cutoff) {
+			continue // after asOf: not part of the history yet
+		}
+		start, _ := g.Start(e.Date.In(asOf.Location()))
+		if start.Before(first) {
+			first = start
+		}
+		key := start.Format(time.DateOnly)
+		if sums[e.Category] == nil {
+			sums[e.Category] = map[string]Totals{}
+		}
+		if sums[e.Category][key] == nil {
+			sums[e.Category][key] = Totals{}
+		}
+		if err := sums[e.Category][key].add(e.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	categories := make([]string, 0, len(sums))
+	for c := range sums {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	out := make([]Series, 0, len(categories))
+	for _, c := range categories {
+		s := Series{Category: c}
+		for start := first; !start.After(last); start = g.next(start) {
+			total, err := sums[c][start.Format(time.DateOnly)].In(rates, to)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", c, start.Format(time.DateOnly), err)
+			}
+			s.Buckets = append(s.Buckets, Bucket{Start: start, Total: total})
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+type Delta struct {
+	Start    time.Time
+	Previous Money
+	Current  Money
+	Change   Money
+}
+
+// Deltas compares each bucket with the one before it; with monthly
+// buckets this is month over month.
+func Deltas(s Series) ([]Delta, error) {
+	out := []Delta{}
+	for i := 1; i < len(s.Buckets); i++ {
+		prev, cur := s.Buckets[i-1].Total, s.Buckets[i].Total
+		change, err := cur.Sub(prev)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Delta{Start: s.Buckets[i].Start, Previous: prev, Current: cur, Change: change})
+	}
+	return out, nil
+}
+
+// or "NaN%" is worse than saying "new".
+func (d Delta) Percent() string {
+	if d.Previous.Minor == 0 {
+		if d.Current.Minor == 0 {
+			return "0.0%"
+		}
+		return "new"
+	}
+	r := big.NewRat(d.Change.Minor, d.Previous.Minor)
+	r.Mul(r, big.NewRat(100, 1))
+	sign := ""
+	if r.Sign() > 0 {
+		sign = "+"
+	}
+	return sign + r.FloatString(1) + "%"
+}
+
+// averaging the last few buckets smooths it out.
+//
+// RollingAverage returns one bucket per full window, dated like the last
+// bucket of that window, each rounded once, half away from zero.
+func RollingAverage(s Series, window int) ([]Bucket, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive, got %d", window)
+	}
+	out := []Bucket{}
+	sum := new(big.Int)
+	for i, b := range s.Buckets {
+		sum.Add(sum, big.NewInt(b.Total.Minor))
+		if i >= window {
+			sum.Sub(sum, big.NewInt(s.Buckets[i-window].Total.Minor))
+		}
+		if i < window-1 {
+			continue
+		}
+		avg, err := roundHalfAway(new(big.Rat).SetFrac(sum, big.NewInt(int64(window))))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Bucket{Start: b.Start, Total: Money{Minor: avg, Currency: b.Total.Currency}})
+	}
+	return out, nil
+}
+
+// much above normal, measured against how much history.
+type AnomalyConfig struct {
+	Multiple   string // current > Multiple × historical average, e.g. "1.5"
+	Lookback   int    // buckets of history to average; 0 means all
+	MinHistory int    // fewer buckets than this: too early to judge
+}
+
+type anomalyRule struct {
+	multiple   *big.Rat
+	lookback   int
+	minHistory int
+}
+
+func (c AnomalyConfig) compile() (anomalyRule, error) {
+	multiple, ok := new(big.Rat).SetString(strings.TrimSpace(c.Multiple))
+	if !ok || multiple.Cmp(big.NewRat(1, 1)) < 0 {
+		return anomalyRule{}, fmt.Errorf("multiple must be a decimal of at least 1, got %q", c.Multiple)
+	}
+	if c.Lookback < 0 || c.MinHistory < 1 {
+		return anomalyRule{}, fmt.Errorf("lookback must be >= 0 and min history >= 1, got %d and %d", c.Lookback, c.MinHistory)
+	}
+	if c.Lookback > 0 && c.Lookback < c.MinHistory {
+		return anomalyRule{}, fmt.Errorf("lookback %d can never reach min history %d", c.Lookback, c.MinHistory)
+	}
+	return anomalyRule{multiple: multiple, lookback: c.Lookback, minHistory: c.MinHistory}, nil
+}
+
+// so a category is flagged or not regardless of how averages round.
+type Anomaly struct {
+	Category string
+	Start    time.Time
+	Current  Money
+	Average  Money // rounded, for display only
+	Ratio    string
+}
+
+// DetectAnomalies looks at the last bucket of each series, which is the
+// one containing asOf and may still be in progress.
+func DetectAnomalies(series []Series, cfg AnomalyConfig) ([]Anomaly, error) {
+	rule, err := cfg.compile()
+	if err != nil {
+		return nil, err
+	}
+	out := []Anomaly{}
+	for _, s := range series {
+		if len(s.Buckets) == 0 {
+			continue
+		}
+		current := s.Buckets[len(s.Buckets)-1]
+		history := s.Buckets[:len(s.Buckets)-1]
+		if rule.lookback > 0 && len(history) > rule.lookback {
+			history = history[len(history)-rule.lookback:]
+		}
+		if len(history) < rule.minHistory {
+			continue
+		}
+		sum := new(big.Int)
+		for _, b := range history {
+			sum.Add(sum, big.NewInt(b.Total.Minor))
+		}
+		average := new(big.Rat).SetFrac(sum, big.NewInt(int64(len(history))))
+		threshold := new(big.Rat).Mul(average, rule.multiple)
+		if new(big.Rat).SetInt64(current.Total.Minor).Cmp(threshold) <= 0 {
+			continue
+		}
+		rounded, err := roundHalfAway(average)
+		if err != nil {
+			return nil, err
+		}
+		ratio := "new"
+		if average.Sign() > 0 {
+			r := new(big.Rat).Quo(new(big.Rat).SetInt64(current.Total.Minor), average)
+			ratio = r.FloatString(1) + "x"
+		}
+		out = append(out, Anomaly{
+			Category: s.Category,
+			Start:    current.Start,
+			Current:  current.Total,
+			Average:  Money{Minor: rounded, Currency: current.Total.Currency},
+			Ratio:    ratio,
+		})
+	}
+	return out, nil
+}
+
+// smoothed level and a flag is enough to spot what moved.
+func BuildTrendLines(series []Series, window int, cfg AnomalyConfig) ([]string, error) {
+	anomalies, err := DetectAnomalies(series, cfg)
+	if err != nil {
+		return nil, err
+	}
+	flagged := map[string]Anomaly{}
+	for _, a := range anomalies {
+		flagged[a.Category] = a
+	}
+	lines := []string{}
+	for _, s := range series {
+		if len(s.Buckets) == 0 {
+			continue
+		}
+		latest := s.Buckets[len(s.Buckets)-1]
+		line := fmt.Sprintf("- %s %s: %s", s.Category, latest.Start.Format(time.DateOnly), latest.Total)
+		deltas, err := Deltas(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(deltas) > 0 {
+			d := deltas[len(deltas)-1]
+			line += fmt.Sprintf(" (%s, %s vs previous)", signed(d.Change), d.Percent())
+		}
+		averages, err := RollingAverage(s, window)
+		if err != nil {
+			return nil, err
+		}
+		if len(averages) > 0 {
+			line += fmt.Sprintf(", %d-bucket average %s", window, averages[len(averages)-1].Total)
+		}
+		if a, ok := flagged[s.Category]; ok {
+			line += fmt.Sprintf(" [ANOMALY: %s of the %s average]", a.Ratio, a.Average)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func signed(m Money) string {
+	if m.Minor > 0 {
+		return "+" + m.String()
+	}
+	return m.String()
+}
+
+func day(t *testing.T, raw string) time.Time {
+	t.Helper()
+	d, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		t.Fatalf("date %q: %v", raw, err)
+	}
+	return d.Add(12 * time.Hour)
+}
+
+func eur(amount string) Money { return MustParseMoney(amount, "EUR") }
+
+// monthly builds a series of consecutive months starting in January 2026.
+func monthly(category string, amounts ...string) Series {
+	s := Series{Category: category}
+	for i, a := range amounts {
+		s.Buckets = append(s.Buckets, Bucket{Start: time.Date(2026, time.Month(1+i), 1, 0, 0, 0, 0, time.UTC), Total: eur(a)})
+	}
+	return s
+}
+
+func totals(buckets []Bucket) string {
+	out := []string{}
+	for _, b := range buckets {
+		out = append(out, b.Start.Format(time.DateOnly)+"="+decimal(b.Total))
+	}
+	return strings.Join(out, " ")
+}
+
+// decimal drops the currency suffix; every amount in these tests is EUR.
+func decimal(m Money) string { return strings.TrimSuffix(m.String(), " "+string(m.Currency)) }
+
+func TestLesson1GranularityStart(t *testing.T) {
+	for _, tc := range []struct {
+		g    Granularity
+		at   string
+		want string
+	}{
+		{GranularityDay, "2026-10-16", "2026-10-16"},
+		{GranularityWeek, "2026-10-16", "2026-10-12"},
+		{GranularityWeek, "2026-10-12", "2026-10-12"},
+		{GranularityWeek, "2027-01-03", "2026-12-28"},
+		{GranularityMonth, "2026-10-31", "2026-10-01"},
+	} {
+		got, err := tc.g.Start(day(t, tc.at))
+		if err != nil || got.Format(time.RFC3339) != tc.want+"T00:00:00Z" {
+			t.Fatalf("%s of %s: want %s, got %s %v", tc.g, tc.at, tc.want, got, err)
+		}
+	}
+	if _, err := Granularity("year").Start(day(t, "2026-10-16")); !errors.Is(err, ErrUnknownGranularity) {
+		t.Fatalf("want ErrUnknownGranularity, got %v", err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	// 2026-10-25 is 25 hours long in Berlin; the next day still starts at midnight.
+	start, _ := GranularityDay.Start(time.Date(2026, 10, 25, 9, 0, 0, 0, berlin))
+	if next := GranularityDay.next(start); next.Hour() != 0 || next.Day() != 26 {
+		t.Fatalf("next day after DST change: %s", next)
+	}
+}
+
+func TestLesson2BucketSeriesFillsGaps(t *testing.T) {
+	items := []Expense{
+		{Date: day(t, "2026-07-05"), Category: " Food", Amount: eur("10")},
+		{Date: day(t, "2026-07-20"), Category: "food", Amount: eur("5.50")},
+		{Date: day(t, "2026-09-01"), Category: "food", Amount: eur("7")},
+		{Date: day(t, "2026-08-31"), Category: "rent", Amount: eur("900")},
+		{Date: day(t, "2026-10-17"), Category: "food", Amount: eur("99")}, // after asOf
+	}
+	series, err := BucketSeries(items, GranularityMonth, day(t, "2026-10-16"), nil, "EUR")
+	if err != nil || len(series) != 2 {
+		t.Fatalf("series: %v %v", series, err)
+	}
+	if series[0].Category != "food" || totals(series[0].Buckets) != "2026-07-01=15.50 2026-08-01=0.00 2026-09-01=7.00 2026-10-01=0.00" {
+		t.Fatalf("food: %s %s", series[0].Category, totals(series[0].Buckets))
+	}
+	if series[1].Category != "rent" || totals(series[1].Buckets) != "2026-07-01=0.00 2026-08-01=900.00 2026-09-01=0.00 2026-10-01=0.00" {
+		t.Fatalf("rent covers the same buckets: %s", totals(series[1].Buckets))
+	}
+
+	if series, _ := BucketSeries(nil, GranularityWeek, day(t, "2026-10-16"), nil, "EUR"); len(series) != 0 {
+		t.Fatalf("no expenses, no series: %v", series)
+	}
+	if _, err := BucketSeries([]Expense{{Category: "x", Amount: eur("1")}}, GranularityMonth, day(t, "2026-10-16"), nil, "EUR"); err == nil {
+		t.Fatalf("undated expense should be rejected")
+	}
+}
+
+func TestLesson3ConvertOncePerBucket(t *testing.T) {
+	rates, _ := NewRateTable("EUR", map[Currency]string{"USD": "0.5"})
+	items := []Expense{
+		{Date: day(t, "2026-10-01"), Category: "food", Amount: MustParseMoney("0.01", "USD")},
+		{Date: day(t, "2026-10-02"), Category: "food", Amount: MustParseMoney("0.01", "USD")},
+		{Date: day(t, "2026-10-02"), Category: "food", Amount: eur("1")},
+	}
+	series, err := BucketSeries(items, GranularityMonth, day(t, "2026-10-16"), rates, "EUR")
+	if err != nil || totals(series[0].Buckets) != "2026-10-01=1.01" {
+		t.Fatalf("0.02 USD at 0.5 is 0.01 EUR, not 0.01 + 0.01: %v %v", series, err)
+	}
+	if _, err := BucketSeries(items, GranularityMonth, day(t, "2026-10-16"), nil, "EUR"); !errors.Is(err, ErrMissingRate) {
+		t.Fatalf("USD without rates: want ErrMissingRate, got %v", err)
+	}
+}
+
+func TestLesson4DeltasAndPercent(t *testing.T) {
+	deltas, err := Deltas(monthly("food", "0", "0", "50", "75", "60"))
+	if err != nil || len(deltas) != 4 {
+		t.Fatalf("deltas: %v %v", deltas, err)
+	}
+	var got []string
+	for _, d := range deltas {
+		got = append(got, decimal(d.Change)+" "+d.Percent())
+	}
+	if fmt.Sprint(got) != "[0.00 0.0% 50.00 new 25.00 +50.0% -15.00 -20.0%]" {
+		t.Fatalf("deltas: %v", got)
+	}
+	if deltas[3].Start.Month() != time.May || decimal(deltas[3].Previous) != "75.00" {
+		t.Fatalf("a delta is dated by its current bucket: %+v", deltas[3])
+	}
+	if d, _ := Deltas(monthly("x", "1")); len(d) != 0 {
+		t.Fatalf("one bucket has no delta: %v", d)
+	}
+	if p := (Delta{Previous: eur("3"), Current: eur("4"), Change: eur("1")}).Percent(); p != "+33.3%" {
+		t.Fatalf("want +33.3%%, got %s", p)
+	}
+}
+
+func TestLesson5RollingAverage(t *testing.T) {
+	s := monthly("food", "10", "20", "30", "0.01")
+	avg, err := RollingAverage(s, 3)
+	if err != nil || totals(avg) != "2026-03-01=20.00 2026-04-01=16.67" {
+		t.Fatalf("window 3: %s %v", totals(avg), err)
+	}
+	if avg, _ := RollingAverage(s, 1); totals(avg) != totals(s.Buckets) {
+		t.Fatalf("window 1 is the series itself: %s", totals(avg))
+	}
+	if avg, _ := RollingAverage(s, 5); len(avg) != 0 {
+		t.Fatalf("window longer than the series: want none, got %s", totals(avg))
+	}
+	if avg, _ := RollingAverage(monthly("x", "0.01", "0.02"), 2); totals(avg) != "2026-02-01=0.02" {
+		t.Fatalf("0.015 rounds half away from zero: %s", totals(avg))
+	}
+	if _, err := RollingAverage(s, 0); err == nil {
+		t.Fatalf("window 0 should be rejected")
+	}
+}
+
+func TestLesson6AnomalyConfig(t *testing.T) {
+	for _, cfg := range []AnomalyConfig{
+		{Multiple: "abc", MinHistory: 1},
+		{Multiple: "0.9", MinHistory: 1},
+		{Multiple: "1.5", MinHistory: 0},
+		{Multiple: "1.5", MinHistory: 1, Lookback: -1},
+		{Multiple: "1.5", MinHistory: 4, Lookback: 3},
+	} {
+		if _, err := cfg.compile(); err == nil {
+			t.Fatalf("%+v should be rejected", cfg)
+		}
+	}
+	if rule, err := (AnomalyConfig{Multiple: " 1.25 ", MinHistory: 1}).compile(); err != nil || rule.multiple.String() != "5/4" {
+		t.Fatalf("1.25 should be exact 5/4: %v %v", rule.multiple, err)
+	}
+}
+
+func TestLesson7DetectAnomalies(t *testing.T) {
+	cfg := AnomalyConfig{Multiple: "1.5", MinHistory: 3}
+	anomalies, err := DetectAnomalies([]Series{
+		monthly("at-limit", "100", "100", "100", "150"),      // not above 1.5x
+		monthly("above", "100", "100", "100", "150.01"),      // just above
+		monthly("short", "1", "1", "100"),                    // two buckets of history
+		monthly("new", "0", "0", "0", "5"),                   // from nothing
+		monthly("exact-avg", "0.01", "0.01", "0.02", "0.03"), // avg 0.0133..., 1.5x = 0.02
+		{Category: "empty"},
+	}, cfg)
+	if err != nil {
+		t.Fatalf("detect: %v", err)
+	}
+	var got []string
+	for _, a := range anomalies {
+		got = append(got, fmt.Sprintf("%s %s %s %s", a.Category, decimal(a.Current), decimal(a.Average), a.Ratio))
+	}
+	want := "[above 150.01 100.00 1.5x new 5.00 0.00 new exact-avg 0.03 0.01 2.3x]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("anomalies:\n got %v\nwant %s", got, want)
+	}
+
+	// Lookback ignores old history: the expensive January no longer counts.
+	s := monthly("food", "1000", "100", "100", "100", "200")
+	if a, _ := DetectAnomalies([]Series{s}, AnomalyConfig{Multiple: "1.5", MinHistory: 3}); len(a) != 0 {
+		t.Fatalf("all history: average 325, 200 is normal: %v", a)
+	}
+	if a, _ := DetectAnomalies([]Series{s}, AnomalyConfig{Multiple: "1.5", MinHistory: 3, Lookback: 3}); len(a) != 1 {
+		t.Fatalf("last 3 buckets: average 100, 200 is an anomaly: %v", a)
+	}
+	if _, err := DetectAnomalies(nil, AnomalyConfig{}); err == nil {
+		t.Fatalf("invalid config should be rejected")
+	}
+}
+
+func TestLesson8TrendLines(t *testing.T) {
+	lines, err := BuildTrendLines([]Series{
+		monthly("food", "300", "310", "320", "330"),
+		monthly("travel", "0", "100", "0", "600"),
+		monthly("new", "5"),
+	}, 3, AnomalyConfig{Multiple: "2", MinHistory: 2})
+	want := []string{
+		"- food 2026-04-01: 330.00 EUR (+10.00 EUR, +3.1% vs previous), 3-bucket average 320.00 EUR",
+		"- travel 2026-04-01: 600.00 EUR (+600.00 EUR, new vs previous), 3-bucket average 233.33 EUR [ANOMALY: 18.0x of the 33.33 EUR average]",
+		"- new 2026-01-01: 5.00 EUR",
+	}
+	if err != nil || strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("lines:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLesson9BucketsFollowTheReportLocation(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	// 2026-09-30 20:00 UTC is already October 1st in Tokyo.
+	items := []Expense{{Date: time.Date(2026, 9, 30, 20, 0, 0, 0, time.UTC), Category: "food", Amount: eur("10")}}
+	series, err := BucketSeries(items, GranularityMonth, time.Date(2026, 10, 16, 9, 0, 0, 0, tokyo), nil, "EUR")
+	if err != nil || len(series[0].Buckets) != 1 || series[0].Buckets[0].Start.Month() != time.October {
+		t.Fatalf("want a single October bucket in JST: %v %v", series, err)
+	}
+	weekly, _ := BucketSeries(items, GranularityWeek, time.Date(2026, 10, 16, 9, 0, 0, 0, tokyo), nil, "EUR")
+	if got := totals(weekly[0].Buckets); got != "2026-09-28=10.00 2026-10-05=0.00 2026-10-12=0.00" {
+		t.Fatalf("weekly: %s", got)
+	}
+}
+
+func TestLesson10Completion(t *testing.T) {
+	if false {
+		t.Fatalf("unreachable")
+	}
+}
+
+// End of Go Expense Trends Tests 1-10
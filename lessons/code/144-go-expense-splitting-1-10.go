@@ -0,0 +1,665 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+GO EXPENSE SPLITTING (Lessons 1-10)
+
+Suggested use:
+1) Run: go run lessons/code/144-go-expense-splitting-1-10.go
+2) Change the lunch to 100.01 EUR and predict who pays the extra cent
+3) Add a fifth person who owes exactly what ana is owed and count the
+   transfers before and after
+
+Extra context:
+- lessons/notes/155-go-projects-principles.md
+- lessons/notes/204-money-as-integers-first-principles.md
+- lessons/notes/209-splitting-and-settling-up-first-principles.md
+*/
+
+// Currency is an ISO 4217 code such as "EUR".
+type Currency string
+
+// currencyExponents says how many minor units a currency has (cents for
+// EUR, none for JPY). Only listed currencies are accepted; a real system
+// would load the full ISO 4217 table.
+var currencyExponents = map[Currency]int{
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"USD": 2,
+}
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrAmountOverflow   = errors.New("amount overflows int64 minor units")
+)
+
+func (c Currency) exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+// Money is an exact amount: an integer count of minor units (cents) plus
+// its currency. Adding integers never drifts the way float64 sums do.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney reads a plain decimal such as "12.5" or "-3.05" without ever
+// going through float64. More decimals than the currency has is an error,
+// not a silent rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	raw := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, frac, hasPoint := strings.Cut(raw, ".")
+	if whole == "" || (hasPoint && frac == "") || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var minor int64
+	for _, r := range whole + frac {
+		if minor > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney is for literals in code and tests; it panics on bad input.
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) ||
+		(other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Minor: m.Minor + other.Minor, Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or +1; amounts in different currencies do not compare.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String prints the exact decimal, e.g. "12.50 EUR".
+func (m Money) String() string {
+	if _, err := m.Currency.exponent(); err != nil {
+		return fmt.Sprintf("%d minor units of %s", m.Minor, m.Currency)
+	}
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+// Decimal is the amount without its currency, e.g. "12.50", for tables
+// and files that put the currency in a header instead.
+func (m Money) Decimal() string {
+	exp, err := m.Currency.exponent()
+	if err != nil {
+		return fmt.Sprint(m.Minor)
+	}
+	sign := ""
+	abs := new(big.Int).SetInt64(m.Minor) // big.Int: -MinInt64 does not fit int64
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+	digits := abs.String()
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// LESSON 1: Who paid and who shares
+// Why this matters: a shared expense is two facts, one payment and a
+// split of the cost; balances fall out of those two facts.
+type SplitKind string
+
+const (
+	SplitEqual   SplitKind = "equal"
+	SplitPercent SplitKind = "percent"
+	SplitExact   SplitKind = "exact"
+)
+
+// Share is one participant's part of a split. Percent is read only for
+// percent splits ("33.33", at most two decimals) and Amount only for exact
+// splits; equal splits need just the name.
+type Share struct {
+	Person  string
+	Percent string
+	Amount  Money
+}
+
+type Split struct {
+	Kind   SplitKind
+	Shares []Share
+}
+
+// EqualSplit divides the cost evenly. Cents that do not divide go to the
+// people listed first, so list the payer first if they should absorb them.
+func EqualSplit(people ...string) Split {
+	shares := make([]Share, 0, len(people))
+	for _, person := range people {
+		shares = append(shares, Share{Person: person})
+	}
+	return Split{Kind: SplitEqual, Shares: shares}
+}
+
+// Expense extends the projects lesson expense with a payer and a split. The
+// payer does not have to be a participant (paying for a team lunch you
+// skipped is still a payment).
+type Expense struct {
+	Category    string
+	Description string
+	Amount      Money
+	Payer       string
+	Split       Split
+}
+
+var (
+	ErrInvalidSplit = errors.New("invalid split")
+	ErrUnbalanced   = errors.New("balances do not sum to zero")
+)
+
+func ValidateExpense(e Expense) error {
+	if strings.TrimSpace(e.Category) == "" {
+		return errors.New("category is required")
+	}
+	if _, err := e.Amount.Currency.exponent(); err != nil {
+		return err
+	}
+	if e.Amount.Minor < 0 {
+		return errors.New("amount cannot be negative")
+	}
+	if NormalizePerson(e.Payer) == "" {
+		return errors.New("payer is required")
+	}
+	return nil
+}
+
+func NormalizePerson(raw string) string {
+	return strings.TrimSpace(raw)
+}
+
+// LESSON 2: Percentages as integers
+// Why this matters: "33.33" becomes 3333 basis points, so checking that the
+// parts add up to 100% is integer equality, not a float tolerance.
+const fullBasisPoints = 100_00
+
+func parsePercent(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	whole, frac, _ := strings.Cut(raw, ".")
+	if whole == "" || !allDigits(whole) || len(whole) > 3 || len(frac) > 2 || (frac != "" && !allDigits(frac)) {
+		return 0, fmt.Errorf("%w: percent %q must look like 25 or 33.33", ErrInvalidSplit, raw)
+	}
+	bp, _ := strconv.ParseInt(whole+frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+	if bp > fullBasisPoints {
+		return 0, fmt.Errorf("%w: percent %q is over 100", ErrInvalidSplit, raw)
+	}
+	return bp, nil
+}
+
+// LESSON 3: Largest remainder allocation
+// Why this matters: 10.00 split three ways is 3.34 + 3.33 + 3.33. Each
+// part is rounded down first, then the leftover cents go to the largest
+// fractional remainders (ties to the earlier participant), so the parts
+// always add back to the total exactly.
+func allocate(total Money, weights []int64) ([]Money, error) {
+	sum := new(big.Int)
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("%w: negative weight", ErrInvalidSplit)
+		}
+		sum.Add(sum, big.NewInt(w))
+	}
+	if sum.Sign() == 0 {
+		return nil, fmt.Errorf("%w: weights sum to zero", ErrInvalidSplit)
+	}
+
+	parts := make([]Money, len(weights))
+	remainders := make([]*big.Int, len(weights))
+	leftover := total.Minor
+	for i, w := range weights {
+		// big.Int: amount × weight can overflow int64 before the division.
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(total.Minor), big.NewInt(w)), sum, new(big.Int))
+		parts[i] = Money{Minor: q.Int64(), Currency: total.Currency}
+		remainders[i] = r
+		leftover -= q.Int64()
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	for _, i := range order[:leftover] {
+		parts[i].Minor++
+	}
+	return parts, nil
+}
+
+// LESSON 4: Split one expense into portions
+// Why this matters: every split kind ends as the same []Portion whose
+// amounts sum to the expense amount; balances never see the split kind.
+type Portion struct {
+	Person string
+	Amount Money
+}
+
+func Allocate(e Expense) ([]Portion, error) {
+	if err := ValidateExpense(e); err != nil {
+		return nil, err
+	}
+	if len(e.Split.Shares) == 0 {
+		return nil, fmt.Errorf("%w: no participants", ErrInvalidSplit)
+	}
+	people := make([]string, len(e.Split.Shares))
+	seen := map[string]bool{}
+	for i, share := range e.Split.Shares {
+		person := NormalizePerson(share.Person)
+		if person == "" {
+			return nil, fmt.Errorf("%w: participant %d has no name", ErrInvalidSplit, i+1)
+		}
+		if seen[person] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidSplit, person)
+		}
+		seen[person] = true
+		people[i] = person
+	}
+
+	var amounts []Money
+	switch e.Split.Kind {
+	case SplitEqual:
+		weights := make([]int64, len(people))
+		for i := range weights {
+			weights[i] = 1
+		}
+		amounts, _ = allocate(e.Amount, weights)
+	case SplitPercent:
+		weights := make([]int64, len(people))
+		var total int64
+		for i, share := range e.Split.Shares {
+			bp, err := parsePercent(share.Percent)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", people[i], err)
+			}
+			weights[i] = bp
+			total += bp
+		}
+		if total != fullBasisPoints {
+			return nil, fmt.Errorf("%w: percents add up to %d.%02d, not 100", ErrInvalidSplit, total/100, total%100)
+		}
+		amounts, _ = allocate(e.Amount, weights)
+	case SplitExact:
+		sum := Money{Currency: e.Amount.Currency}
+		for i, share := range e.Split.Shares {
+			if share.Amount.Minor < 0 {
+				return nil, fmt.Errorf("%w: %s has a negative share", ErrInvalidSplit, people[i])
+			}
+			var err error
+			if sum, err = sum.Add(share.Amount); err != nil {
+				return nil, fmt.Errorf("%s: %w", people[i], err)
+			}
+			amounts = append(amounts, share.Amount)
+		}
+		if sum != e.Amount {
+			return nil, fmt.Errorf("%w: shares add up to %s, not %s", ErrInvalidSplit, sum, e.Amount)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidSplit, e.Split.Kind)
+	}
+
+	portions := make([]Portion, len(people))
+	for i, person := range people {
+		portions[i] = Portion{Person: person, Amount: amounts[i]}
+	}
+	return portions, nil
+}
+
+// LESSON 5: Net balance per person
+// Why this matters: paid minus owed. Positive means the group owes you,
+// negative means you owe the group, and the balances always sum to zero.
+type Balance struct {
+	Person string
+	Net    Money
+}
+
+func Balances(items []Expense, currency Currency) ([]Balance, error) {
+	if _, err := currency.exponent(); err != nil {
+		return nil, err
+	}
+	net := map[string]Money{}
+	credit := func(person string, amount Money) error {
+		current, ok := net[person]
+		if !ok {
+			current = Money{Currency: currency}
+		}
+		updated, err := current.Add(amount)
+		if err != nil {
+			return err
+		}
+		net[person] = updated
+		return nil
+	}
+
+	for i, e := range items {
+		portions, err := Allocate(e)
+		if err != nil {
+			return nil, fmt.Errorf("expense %d: %w", i+1, err)
+		}
+		if e.Amount.Currency != currency {
+			return nil, fmt.Errorf("expense %d: %w: %s in a %s group", i+1, ErrCurrencyMismatch, e.Amount.Currency, currency)
+		}
+		if err := credit(NormalizePerson(e.Payer), e.Amount); err != nil {
+			return nil, fmt.Errorf("expense %d: %w", i+1, err)
+		}
+		for _, p := range portions {
+			if err := credit(p.Person, Money{Minor: -p.Amount.Minor, Currency: currency}); err != nil {
+				return nil, fmt.Errorf("expense %d: %w", i+1, err)
+			}
+		}
+	}
+
+	out := make([]Balance, 0, len(net))
+	for person, amount := range net {
+		out = append(out, Balance{Person: person, Net: amount})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Person < out[j].Person })
+	return out, nil
+}
+
+// LESSON 6: Zero-sum groups
+// Why this matters: n people with non-zero balances can always settle in
+// n-1 transfers, but every subgroup whose balances already cancel out
+// saves one more. The fewest transfers is n minus the most disjoint
+// zero-sum groups; finding that is a subset search, exact up to
+// maxExactSettle people.
+const maxExactSettle = 16
+
+func zeroSumGroups(amounts []int64) [][]int {
+	n := len(amounts)
+	if n == 0 {
+		return nil
+	}
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+	if n > maxExactSettle {
+		return [][]int{all}
+	}
+
+	// best[mask] is the most zero-sum groups the people in mask can be
+	// cut into, counted along the best order of adding them one by one.
+	full := 1<<n - 1
+	sums := make([]int64, full+1)
+	best := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sums[mask] = sums[mask&(mask-1)] + amounts[low]
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 && best[mask^(1<<i)] > best[mask] {
+				best[mask] = best[mask^(1<<i)]
+			}
+		}
+		if sums[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	// Walk back from everyone, removing one person at a time without losing
+	// a group; read forwards, each zero running sum closes a group.
+	order := make([]int, 0, n)
+	for mask := full; mask != 0; {
+		gain := 0
+		if sums[mask] == 0 {
+			gain = 1
+		}
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 && best[mask^(1<<i)]+gain == best[mask] {
+				order = append(order, i)
+				mask ^= 1 << i
+				break
+			}
+		}
+	}
+	groups := [][]int{}
+	var current []int
+	var running int64
+	for k := len(order) - 1; k >= 0; k-- {
+		current = append(current, order[k])
+		running += amounts[order[k]]
+		if running == 0 {
+			sort.Ints(current)
+			groups = append(groups, current)
+			current = nil
+		}
+	}
+	return groups
+}
+
+// LESSON 7: Settle up with the fewest transfers
+// Why this matters: inside a zero-sum group, paying the largest debt to
+// the largest credit zeroes at least one person per transfer, so a group
+// of k people needs at most k-1 transfers.
+type Transfer struct {
+	From, To string
+	Amount   Money
+}
+
+func SettleUp(balances []Balance) ([]Transfer, error) {
+	if len(balances) == 0 {
+		return []Transfer{}, nil
+	}
+	currency := balances[0].Net.Currency
+	people := []string{}
+	amounts := []int64{}
+	var total int64
+	for _, b := range balances {
+		if b.Net.Currency != currency {
+			return nil, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, b.Net.Currency, currency)
+		}
+		if b.Net.Minor == 0 {
+			continue
+		}
+		// Balances come from checked Money sums, and debts and credits each
+		// total at most the amount spent, so this running sum cannot overflow.
+		total += b.Net.Minor
+		people = append(people, b.Person)
+		amounts = append(amounts, b.Net.Minor)
+	}
+	if total != 0 {
+		return nil, fmt.Errorf("%w: off by %s", ErrUnbalanced, Money{Minor: total, Currency: currency})
+	}
+
+	transfers := []Transfer{}
+	for _, group := range zeroSumGroups(amounts) {
+		remaining := map[int]int64{}
+		for _, i := range group {
+			remaining[i] = amounts[i]
+		}
+		largest := func(sign int64) int {
+			pick := -1
+			for _, i := range group {
+				v := remaining[i] * sign
+				if v > 0 && (pick < 0 || v > remaining[pick]*sign) {
+					pick = i
+				}
+			}
+			return pick
+		}
+		for {
+			debtor, creditor := largest(-1), largest(1)
+			if debtor < 0 || creditor < 0 {
+				break
+			}
+			amount := min(-remaining[debtor], remaining[creditor])
+			transfers = append(transfers, Transfer{From: people[debtor], To: people[creditor], Amount: Money{Minor: amount, Currency: currency}})
+			remaining[debtor] += amount
+			remaining[creditor] -= amount
+		}
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].From != transfers[j].From {
+			return transfers[i].From < transfers[j].From
+		}
+		return transfers[i].To < transfers[j].To
+	})
+	return transfers, nil
+}
+
+// LESSON 8: Apply transfers to check the result
+// Why this matters: a settle-up plan is correct only if every balance is
+// exactly zero afterwards; this is the check the tests lean on.
+func ApplyTransfers(balances []Balance, transfers []Transfer) ([]Balance, error) {
+	net := map[string]Money{}
+	for _, b := range balances {
+		net[b.Person] = b.Net
+	}
+	for _, t := range transfers {
+		from, okFrom := net[t.From]
+		to, okTo := net[t.To]
+		if !okFrom || !okTo {
+			return nil, fmt.Errorf("transfer %s -> %s names someone without a balance", t.From, t.To)
+		}
+		var err error
+		if net[t.From], err = from.Add(t.Amount); err != nil {
+			return nil, err
+		}
+		if net[t.To], err = to.Add(Money{Minor: -t.Amount.Minor, Currency: t.Amount.Currency}); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]Balance, 0, len(net))
+	for person, amount := range net {
+		out = append(out, Balance{Person: person, Net: amount})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Person < out[j].Person })
+	return out, nil
+}
+
+// LESSON 9: Settle-up report lines
+// Why this matters: people check a settle-up against their own memory of
+// who paid; show balances first, then the transfers.
+func BuildSettleLines(balances []Balance, transfers []Transfer) []string {
+	lines := []string{"balances:"}
+	for _, b := range balances {
+		sign := ""
+		if b.Net.Minor > 0 {
+			sign = "+"
+		}
+		lines = append(lines, fmt.Sprintf("  %-8s %s%s", b.Person, sign, b.Net))
+	}
+	if len(transfers) == 0 {
+		return append(lines, "settled: nobody owes anything")
+	}
+	lines = append(lines, fmt.Sprintf("transfers (%d):", len(transfers)))
+	for _, t := range transfers {
+		lines = append(lines, fmt.Sprintf("  %s pays %s %s", t.From, t.To, t.Amount))
+	}
+	return lines
+}
+
+// LESSON 10: End-to-end team trip
+// Why this matters: split, balance and settle in one pass over real-looking data.
+func main() {
+	items := []Expense{
+		{Category: "food", Description: "team lunch", Amount: MustParseMoney("100.00", "EUR"), Payer: "ana",
+			Split: EqualSplit("ana", "ben", "cleo")},
+		{Category: "travel", Description: "train tickets", Amount: MustParseMoney("240.00", "EUR"), Payer: "ben",
+			Split: Split{Kind: SplitPercent, Shares: []Share{{Person: "ana", Percent: "25"}, {Person: "ben", Percent: "25"}, {Person: "dan", Percent: "50"}}}},
+		{Category: "travel", Description: "hotel", Amount: MustParseMoney("310.00", "EUR"), Payer: "dan",
+			Split: Split{Kind: SplitExact, Shares: []Share{{Person: "cleo", Amount: MustParseMoney("155", "EUR")}, {Person: "dan", Amount: MustParseMoney("155", "EUR")}}}},
+		{Category: "food", Description: "coffee", Amount: MustParseMoney("10.00", "EUR"), Payer: "cleo",
+			Split: EqualSplit("cleo", "ana", "ben")},
+	}
+
+	portions, err := Allocate(items[0])
+	if err != nil {
+		fmt.Println("Lesson 4 error:", err)
+		return
+	}
+	for _, p := range portions {
+		fmt.Printf("Lesson 4 %s owes %s for %s\n", p.Person, p.Amount, items[0].Description)
+	}
+
+	balances, err := Balances(items, "EUR")
+	if err != nil {
+		fmt.Println("Lesson 5 error:", err)
+		return
+	}
+	transfers, err := SettleUp(balances)
+	if err != nil {
+		fmt.Println("Lesson 7 error:", err)
+		return
+	}
+	for _, line := range BuildSettleLines(balances, transfers) {
+		fmt.Println(line)
+	}
+}
+
+// End of Go Expense Splitting 1-10
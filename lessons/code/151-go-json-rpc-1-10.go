@@ -0,0 +1,1148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+/*
+GO JSON-RPC (Lessons 1-10)
+
+Suggested use:
+1) Install driver (if needed): go get modernc.org/sqlite
+2) Run: go run lessons/code/151-go-json-rpc-1-10.go
+3) Call (send X-Actor: alice to name who did it):
+   - POST /rpc {"jsonrpc":"2.0","method":"TaskService.CreateTask","params":{"title":"ship"},"id":1}
+   - POST /rpc {"jsonrpc":"2.0","method":"TaskService.CompleteTask","params":{"id":1},"id":2}
+   - POST /rpc [{"jsonrpc":"2.0","method":"TaskService.Tasks","id":3}, ...]   (a batch)
+   - GET  /tasks/1                                              (REST sees the same task)
+   - GET  /tasks?limit=2&cursor=<next_cursor from previous page>
+
+Extra context:
+- lessons/notes/158-go-api-architecture-principles.md
+- lessons/notes/215-json-rpc-first-principles.md
+*/
+
+// LESSON 1: One service, two transports
+// Why this matters: REST and RPC are framing. Validation, history and
+// error meaning live in the service, so both transports agree by design.
+
+// Version starts at 1 and increases on every write; it doubles as the ETag.
+type Task struct {
+	ID      int64  `json:"id"`
+	Title   string `json:"title"`
+	Done    bool   `json:"done"`
+	Version int64  `json:"version"`
+}
+
+// HistoryEntry records who did what, whichever transport they came through.
+type HistoryEntry struct {
+	TaskID    int64     `json:"task_id"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+var (
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrTitleRequired   = errors.New("title is required")
+	ErrInvalidID       = errors.New("id must be positive")
+	ErrInvalidIfMatch  = errors.New("If-Match must be a quoted version")
+	ErrVersionConflict = errors.New("task was modified by someone else")
+	ErrTaskBlocked     = errors.New("task has open blockers")
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	ErrInvalidQuery    = errors.New("invalid list query")
+)
+
+// LESSON 2: Schema as numbered migrations
+// Why this matters: history and blockers are what make the error mapping
+// interesting; a bare CRUD table would only ever answer "not found".
+var taskMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tasks",
+		Up: `
+CREATE TABLE tasks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title TEXT NOT NULL,
+  done INTEGER NOT NULL DEFAULT 0,
+  version INTEGER NOT NULL DEFAULT 1
+);`,
+		Down: `DROP TABLE tasks;`,
+	},
+	{
+		Version: 2,
+		Name:    "create_task_history",
+		Up: `
+CREATE TABLE task_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  task_id INTEGER NOT NULL REFERENCES tasks(id),
+  action TEXT NOT NULL,
+  actor TEXT NOT NULL,
+  changed_at TEXT NOT NULL
+);
+CREATE INDEX task_history_by_task ON task_history (task_id, id);`,
+		Down: `DROP INDEX task_history_by_task; DROP TABLE task_history;`,
+	},
+	{
+		Version: 3,
+		Name:    "create_task_dependencies",
+		Up: `
+CREATE TABLE task_dependencies (
+  task_id INTEGER NOT NULL REFERENCES tasks(id),
+  blocker_id INTEGER NOT NULL REFERENCES tasks(id),
+  PRIMARY KEY (task_id, blocker_id)
+);`,
+		Down: `DROP TABLE task_dependencies;`,
+	},
+}
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was edited")
+	ErrUnknownMigration = errors.New("database has a migration this binary does not know")
+)
+
+// Migration is one numbered schema step. Never edit a migration after it
+// has shipped; add a new one instead (the checksum check enforces this).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + "\n--down--\n" + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrator applies migrations in version order and records each one in
+// schema_migrations inside the same transaction as the schema change.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+func (m *Migrator) applied() (map[int]string, error) {
+	_, err := m.db.Exec(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  applied_at TEXT NOT NULL
+);`)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.db.Query(`SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]string{}
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		out[version] = checksum
+	}
+	return out, rows.Err()
+}
+
+// Up verifies the recorded history against the code, then applies every
+// pending migration.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	known := map[int]bool{}
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		if checksum, ok := applied[mig.Version]; ok && checksum != mig.Checksum() {
+			return nil, fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, ErrChecksumMismatch)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("migration %d: %w", version, ErrUnknownMigration)
+		}
+	}
+	done := []Migration{}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if err := m.inTx(mig); err != nil {
+			return done, fmt.Errorf("migration %d (%s) up: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+func (m *Migrator) inTx(mig Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(mig.Up); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		mig.Version, mig.Name, mig.Checksum(), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type SQLiteTaskRepo struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func NewSQLiteTaskRepo(db *sql.DB) *SQLiteTaskRepo {
+	return &SQLiteTaskRepo{db: db, now: time.Now}
+}
+
+func (r *SQLiteTaskRepo) Migrate() error {
+	_, err := NewMigrator(r.db, taskMigrations).Up()
+	return err
+}
+
+// LESSON 3: Reads
+// Why this matters: the RPC "Tasks" method and GET /tasks read through the
+// same scanTask, so they can never disagree about what a task looks like.
+const taskColumns = `id, title, done, version`
+
+// scanTask reads one taskColumns row from either *sql.Row or *sql.Rows.
+func scanTask(row interface{ Scan(...any) error }) (Task, error) {
+	var t Task
+	var doneInt int
+	if err := row.Scan(&t.ID, &t.Title, &doneInt, &t.Version); err != nil {
+		return Task{}, err
+	}
+	t.Done = doneInt == 1
+	return t, nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func getTask(q queryer, id int64) (Task, error) {
+	t, err := scanTask(q.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	return t, err
+}
+
+func (r *SQLiteTaskRepo) Get(id int64) (Task, error) {
+	return getTask(r.db, id)
+}
+
+func (r *SQLiteTaskRepo) List() ([]Task, error) {
+	return r.query(`SELECT ` + taskColumns + ` FROM tasks ORDER BY id`)
+}
+
+// Page is keyset pagination: the tasks after afterID, so a page costs the
+// same however deep into the list it is.
+func (r *SQLiteTaskRepo) Page(afterID int64, limit int) ([]Task, error) {
+	return r.query(`SELECT `+taskColumns+` FROM tasks WHERE id > ? ORDER BY id LIMIT ?`, afterID, limit)
+}
+
+func (r *SQLiteTaskRepo) query(query string, args ...any) ([]Task, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, t)
+	}
+	return items, rows.Err()
+}
+
+func (r *SQLiteTaskRepo) History(id int64) ([]HistoryEntry, error) {
+	if _, err := r.Get(id); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`
+SELECT task_id, action, actor, changed_at FROM task_history WHERE task_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		var e HistoryEntry
+		var changedAt string
+		if err := rows.Scan(&e.TaskID, &e.Action, &e.Actor, &changedAt); err != nil {
+			return nil, err
+		}
+		if e.ChangedAt, err = time.Parse(time.RFC3339Nano, changedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// LESSON 4: Writes in one transaction
+// Why this matters: "complete" checks the version and the blockers and
+// writes its history row without a gap another writer could slip into.
+func (r *SQLiteTaskRepo) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteTaskRepo) record(tx *sql.Tx, id int64, action, actor string) error {
+	_, err := tx.Exec(`INSERT INTO task_history (task_id, action, actor, changed_at) VALUES (?, ?, ?, ?)`,
+		id, action, actor, r.now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+func (r *SQLiteTaskRepo) Add(title string, actor string) (Task, error) {
+	var t Task
+	err := r.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`INSERT INTO tasks (title) VALUES (?)`, title)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		t = Task{ID: id, Title: title, Version: 1}
+		return r.record(tx, id, "created", actor)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+// Complete marks a task done. expectedVersion 0 skips the version check;
+// an open blocker always refuses.
+func (r *SQLiteTaskRepo) Complete(id int64, expectedVersion int64, actor string) (Task, error) {
+	var task Task
+	err := r.inTx(func(tx *sql.Tx) error {
+		current, err := getTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && expectedVersion != current.Version {
+			return fmt.Errorf("task id %d: %w", id, ErrVersionConflict)
+		}
+		var blocked bool
+		err = tx.QueryRow(`
+SELECT EXISTS (
+  SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocker_id
+  WHERE d.task_id = ? AND b.done = 0
+)`, id).Scan(&blocked)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return fmt.Errorf("task id %d: %w", id, ErrTaskBlocked)
+		}
+		if _, err := tx.Exec(`UPDATE tasks SET done = 1, version = version + 1 WHERE id = ?`, id); err != nil {
+			return err
+		}
+		task = current
+		task.Done = true
+		task.Version++
+		return r.record(tx, id, "completed", actor)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// AddBlocker refuses an edge when taskID is already upstream of blockerID.
+func (r *SQLiteTaskRepo) AddBlocker(taskID, blockerID int64) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		for _, id := range []int64{taskID, blockerID} {
+			if _, err := getTask(tx, id); err != nil {
+				return err
+			}
+		}
+		var cycle bool
+		err := tx.QueryRow(`
+WITH RECURSIVE upstream(id) AS (
+  SELECT ?
+  UNION
+  SELECT d.blocker_id FROM task_dependencies d JOIN upstream u ON d.task_id = u.id
+)
+SELECT EXISTS (SELECT 1 FROM upstream WHERE id = ?)`, blockerID, taskID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("task id %d blocked by %d: %w", taskID, blockerID, ErrDependencyCycle)
+		}
+		_, err = tx.Exec(`INSERT OR IGNORE INTO task_dependencies (task_id, blocker_id) VALUES (?, ?)`, taskID, blockerID)
+		return err
+	})
+}
+
+// LESSON 5: The shared service
+// Why this matters: every rule below applies to REST and RPC callers alike.
+type TaskService struct {
+	repo *SQLiteTaskRepo
+}
+
+func NewTaskService(repo *SQLiteTaskRepo) *TaskService {
+	return &TaskService{repo: repo}
+}
+
+type NewTask struct {
+	Title string `json:"title"`
+}
+
+// actor names whoever asked for a write; it ends up in the task history.
+func (s *TaskService) CreateTask(input NewTask, actor string) (Task, error) {
+	clean := strings.TrimSpace(input.Title)
+	if clean == "" {
+		return Task{}, ErrTitleRequired
+	}
+	return s.repo.Add(clean, actor)
+}
+
+func (s *TaskService) Tasks() ([]Task, error) {
+	return s.repo.List()
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type TaskPage struct {
+	Items      []Task `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListTasks returns one page in id order. A cursor only holds the last id
+// of the previous page, and resuming after any id is harmless, so unlike a
+// cursor that carries filters it needs no signature.
+func (s *TaskService) ListTasks(limit int, cursor string) (TaskPage, error) {
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxPageSize {
+		return TaskPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxPageSize)
+	}
+	var afterID int64
+	if cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			afterID, err = strconv.ParseInt(string(raw), 10, 64)
+		}
+		if err != nil || afterID <= 0 {
+			return TaskPage{}, fmt.Errorf("%w: cursor is invalid", ErrInvalidQuery)
+		}
+	}
+	// One extra row says whether another page exists.
+	items, err := s.repo.Page(afterID, limit+1)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	page := TaskPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := strconv.FormatInt(page.Items[limit-1].ID, 10)
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last))
+	}
+	return page, nil
+}
+
+func (s *TaskService) GetTask(id int64) (Task, error) {
+	if id <= 0 {
+		return Task{}, ErrInvalidID
+	}
+	return s.repo.Get(id)
+}
+
+// CompleteTask marks a task done. ifMatch is the version the caller last
+// saw; 0 skips the check.
+func (s *TaskService) CompleteTask(id int64, ifMatch int64, actor string) (Task, error) {
+	if id <= 0 {
+		return Task{}, ErrInvalidID
+	}
+	return s.repo.Complete(id, ifMatch, actor)
+}
+
+func (s *TaskService) AddBlocker(id, blockerID int64) error {
+	if id <= 0 || blockerID <= 0 {
+		return ErrInvalidID
+	}
+	if id == blockerID {
+		return fmt.Errorf("task id %d blocked by itself: %w", id, ErrDependencyCycle)
+	}
+	return s.repo.AddBlocker(id, blockerID)
+}
+
+func (s *TaskService) TaskHistory(id int64) ([]HistoryEntry, error) {
+	if id <= 0 {
+		return nil, ErrInvalidID
+	}
+	return s.repo.History(id)
+}
+
+// LESSON 6: REST routes
+// Why this matters: the RPC endpoint mounts on the same mux, so one server
+// and one service answer both.
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// statusFromError keeps the error -> HTTP status decision in one place.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrTaskBlocked), errors.Is(err, ErrDependencyCycle):
+		return http.StatusConflict
+	case errors.Is(err, ErrTitleRequired), errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidIfMatch), errors.Is(err, ErrInvalidQuery):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := statusFromError(err)
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		msg = "internal error"
+	}
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func etag(task Task) string {
+	return strconv.Quote(strconv.FormatInt(task.Version, 10))
+}
+
+// ifMatchVersion reads If-Match. A missing header or "*" means no
+// precondition (0); anything that is not a quoted version is a bad request.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, nil
+	}
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil {
+		return 0, ErrInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalidIfMatch
+	}
+	return version, nil
+}
+
+// requestActor is who the history records for this request. There is no
+// authentication here yet, so callers identify themselves with X-Actor.
+func requestActor(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get("X-Actor")); actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+func pathID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(r.PathValue("id"), 10, 64)
+}
+
+// methodNotAllowed answers paths that exist but were called with the wrong verb.
+func methodNotAllowed(allow string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Allow", allow)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func buildMux(service *TaskService) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		limit := 0
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				writeError(w, fmt.Errorf("%w: limit must be an integer", ErrInvalidQuery))
+				return
+			}
+			limit = n
+		}
+		page, err := service.ListTasks(limit, r.URL.Query().Get("cursor"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	mux.HandleFunc("POST /tasks", func(w http.ResponseWriter, r *http.Request) {
+		var input NewTask
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		task, err := service.CreateTask(input, requestActor(r))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("ETag", etag(task))
+		writeJSON(w, http.StatusCreated, task)
+	})
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		task, err := service.GetTask(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("ETag", etag(task))
+		writeJSON(w, http.StatusOK, task)
+	})
+	mux.HandleFunc("POST /tasks/{id}/done", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		ifMatch, err := ifMatchVersion(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		task, err := service.CompleteTask(id, ifMatch, requestActor(r))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("ETag", etag(task))
+		writeJSON(w, http.StatusOK, task)
+	})
+
+	// JSON-RPC shares this service with every route above.
+	mux.Handle("POST /rpc", newRPCHandler(service))
+
+	// Method-less patterns are less specific, so they only catch wrong verbs.
+	mux.HandleFunc("/tasks", methodNotAllowed("GET, HEAD, POST"))
+	mux.HandleFunc("/tasks/{id}", methodNotAllowed("GET, HEAD"))
+	mux.HandleFunc("/tasks/{id}/done", methodNotAllowed("POST"))
+	mux.HandleFunc("/rpc", methodNotAllowed("POST"))
+
+	// Anything unmatched gets the same JSON error shape as the API.
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	})
+	return mux
+}
+
+// LESSON 7: JSON-RPC 2.0 framing and error codes
+// Why this matters: the spec fixes the envelope and a handful of codes;
+// everything the service can refuse needs a stable code of its own.
+const (
+	rpcVersion = "2.0"
+
+	// Codes defined by the JSON-RPC 2.0 specification.
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+
+	maxRPCBytes = 1 << 20
+	maxRPCBatch = 100
+)
+
+// rpcErrorCodes gives service errors codes in the -32000..-32099 range the
+// spec leaves to servers. The client reads the table backwards, so
+// errors.Is(err, ErrTaskNotFound) works on both sides of the wire.
+var rpcErrorCodes = []struct {
+	err  error
+	code int
+}{
+	{ErrTaskNotFound, -32001},
+	{ErrVersionConflict, -32002},
+	{ErrTaskBlocked, -32003},
+	{ErrDependencyCycle, -32004},
+}
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func (e *RPCError) Unwrap() error {
+	for _, c := range rpcErrorCodes {
+		if c.code == e.Code {
+			return c.err
+		}
+	}
+	return nil
+}
+
+// rpcErrorFrom is the RPC counterpart of statusFromError; whatever REST
+// answers with 400 is "invalid params" here.
+func rpcErrorFrom(err error) *RPCError {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	for _, c := range rpcErrorCodes {
+		if errors.Is(err, c.err) {
+			return &RPCError{Code: c.code, Message: err.Error()}
+		}
+	}
+	if statusFromError(err) == http.StatusBadRequest {
+		return &RPCError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return &RPCError{Code: rpcInternalError, Message: "internal error"}
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// ID is nil when the member is absent, which makes the request a
+	// notification. An explicit null stays as the literal "null".
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func (r rpcResponse) decode(result any) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+func validRPCID(id json.RawMessage) bool {
+	if id == nil || string(id) == "null" {
+		return true
+	}
+	return id[0] == '"' || id[0] == '-' || (id[0] >= '0' && id[0] <= '9')
+}
+
+// decodeRPCParams accepts params by name only: positional arrays would tie
+// clients to field order. A nil dst means the method takes no params.
+func decodeRPCParams(raw json.RawMessage, dst any) error {
+	trimmed := bytes.TrimSpace(raw)
+	if dst == nil {
+		switch string(trimmed) {
+		case "", "null", "{}", "[]":
+			return nil
+		}
+		return &RPCError{Code: rpcInvalidParams, Message: "method takes no params"}
+	}
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return &RPCError{Code: rpcInvalidParams, Message: "params must be an object"}
+	}
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return &RPCError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// LESSON 8: Dispatch, batches and notifications
+// Why this matters: a batch is many calls in one round trip, and a
+// notification is a call nobody waits for; both change what "the response"
+// means.
+type completeTaskParams struct {
+	ID      int64 `json:"id"`
+	IfMatch int64 `json:"if_match,omitempty"`
+}
+
+type rpcMethod func(r *http.Request, params json.RawMessage) (any, error)
+
+func rpcMethods(service *TaskService) map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"TaskService.CreateTask": func(r *http.Request, params json.RawMessage) (any, error) {
+			var input NewTask
+			if err := decodeRPCParams(params, &input); err != nil {
+				return nil, err
+			}
+			task, err := service.CreateTask(input, requestActor(r))
+			return task, err
+		},
+		"TaskService.CompleteTask": func(r *http.Request, params json.RawMessage) (any, error) {
+			var p completeTaskParams
+			if err := decodeRPCParams(params, &p); err != nil {
+				return nil, err
+			}
+			task, err := service.CompleteTask(p.ID, p.IfMatch, requestActor(r))
+			return task, err
+		},
+		"TaskService.Tasks": func(_ *http.Request, params json.RawMessage) (any, error) {
+			if err := decodeRPCParams(params, nil); err != nil {
+				return nil, err
+			}
+			items, err := service.Tasks()
+			return items, err
+		},
+	}
+}
+
+type rpcHandler struct {
+	methods map[string]rpcMethod
+}
+
+func newRPCHandler(service *TaskService) *rpcHandler {
+	return &rpcHandler{methods: rpcMethods(service)}
+}
+
+// ServeHTTP answers one request object or a batch array. Batch entries run
+// in order, one at a time, so later calls see earlier writes. A request
+// made only of notifications gets 204 and no body.
+func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRPCBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, rpcFailure(rpcInvalidRequest, "request body too large"))
+		}
+		return
+	}
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		writeJSON(w, http.StatusOK, rpcFailure(rpcParseError, "parse error"))
+		return
+	}
+	if body[0] != '[' {
+		if resp, ok := h.handle(r, body); ok {
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		writeJSON(w, http.StatusOK, rpcFailure(rpcInvalidRequest, "batch must be a non-empty array"))
+		return
+	}
+	if len(batch) > maxRPCBatch {
+		writeJSON(w, http.StatusOK, rpcFailure(rpcInvalidRequest, fmt.Sprintf("batch is limited to %d calls", maxRPCBatch)))
+		return
+	}
+	responses := []rpcResponse{}
+	for _, raw := range batch {
+		if resp, ok := h.handle(r, raw); ok {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func rpcFailure(code int, message string) rpcResponse {
+	return rpcResponse{JSONRPC: rpcVersion, Error: &RPCError{Code: code, Message: message}}
+}
+
+// handle runs one request object. ok is false for notifications, which get
+// no response even when they fail.
+func (h *rpcHandler) handle(r *http.Request, raw json.RawMessage) (rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != rpcVersion || req.Method == "" || !validRPCID(req.ID) {
+		return rpcFailure(rpcInvalidRequest, "invalid request"), true
+	}
+
+	var result any
+	var err error
+	if method, found := h.methods[req.Method]; found {
+		result, err = method(r, req.Params)
+	} else {
+		err = &RPCError{Code: rpcMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	if req.ID == nil {
+		return rpcResponse{}, false
+	}
+
+	resp := rpcResponse{JSONRPC: rpcVersion, ID: req.ID}
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Result = nil
+		resp.Error = rpcErrorFrom(err)
+	}
+	return resp, true
+}
+
+// LESSON 9: A small client
+// Why this matters: ids, batching and error codes are easy to get wrong by
+// hand; callers should see typed results and errors.Is-able failures.
+
+// RPCClient is a small client for the /rpc endpoint. actor is sent as
+// X-Actor, the same way REST callers name themselves.
+type RPCClient struct {
+	url    string
+	actor  string
+	http   *http.Client
+	nextID atomic.Int64
+}
+
+func NewRPCClient(url, actor string, httpClient *http.Client) *RPCClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &RPCClient{url: url, actor: actor, http: httpClient}
+}
+
+// RPCCall is one entry of a batch. Err is filled per call, so one failure
+// does not hide the other results. Notify calls get no response.
+type RPCCall struct {
+	Method string
+	Params any
+	Result any
+	Notify bool
+	Err    error
+}
+
+func (c *RPCClient) newRequest(method string, params any, notify bool) (rpcRequest, error) {
+	req := rpcRequest{JSONRPC: rpcVersion, Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return rpcRequest{}, err
+		}
+		req.Params = raw
+	}
+	if !notify {
+		req.ID = json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10))
+	}
+	return req, nil
+}
+
+// post returns the response body, or nil when the server answered 204.
+func (c *RPCClient) post(ctx context.Context, payload any) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.actor != "" {
+		req.Header.Set("X-Actor", c.actor)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return data, nil
+	case http.StatusNoContent:
+		return nil, nil
+	}
+	var failure rpcResponse
+	if json.Unmarshal(data, &failure) == nil && failure.Error != nil {
+		return nil, failure.Error
+	}
+	return nil, fmt.Errorf("rpc: unexpected HTTP status %d", resp.StatusCode)
+}
+
+// Call sends one request and decodes its result into result (if non-nil).
+func (c *RPCClient) Call(ctx context.Context, method string, params, result any) error {
+	req, err := c.newRequest(method, params, false)
+	if err != nil {
+		return err
+	}
+	data, err := c.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	var resp rpcResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("rpc: invalid response: %w", err)
+	}
+	return resp.decode(result)
+}
+
+// Notify sends a call the server runs without answering; its outcome is
+// never reported, not even failures.
+func (c *RPCClient) Notify(ctx context.Context, method string, params any) error {
+	req, err := c.newRequest(method, params, true)
+	if err != nil {
+		return err
+	}
+	_, err = c.post(ctx, req)
+	return err
+}
+
+// Batch sends all calls in one round trip. The returned error is about the
+// round trip itself; per-call failures are in calls[i].Err.
+func (c *RPCClient) Batch(ctx context.Context, calls []RPCCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	reqs := make([]rpcRequest, len(calls))
+	pending := map[string]int{}
+	for i := range calls {
+		req, err := c.newRequest(calls[i].Method, calls[i].Params, calls[i].Notify)
+		if err != nil {
+			return err
+		}
+		reqs[i] = req
+		if !calls[i].Notify {
+			pending[string(req.ID)] = i
+		}
+	}
+	data, err := c.post(ctx, reqs)
+	if err != nil {
+		return err
+	}
+	var responses []rpcResponse
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &responses); err != nil {
+			// A batch the server rejected as a whole is one error object.
+			var failure rpcResponse
+			if json.Unmarshal(data, &failure) == nil && failure.Error != nil {
+				return failure.Error
+			}
+			return fmt.Errorf("rpc: invalid batch response: %w", err)
+		}
+	}
+	for _, resp := range responses {
+		i, ok := pending[string(resp.ID)]
+		if !ok {
+			continue
+		}
+		delete(pending, string(resp.ID))
+		calls[i].Err = resp.decode(calls[i].Result)
+	}
+	for _, i := range pending {
+		calls[i].Err = errors.New("rpc: no response for call")
+	}
+	return nil
+}
+
+func (c *RPCClient) CreateTask(ctx context.Context, input NewTask) (Task, error) {
+	var task Task
+	err := c.Call(ctx, "TaskService.CreateTask", input, &task)
+	return task, err
+}
+
+func (c *RPCClient) CompleteTask(ctx context.Context, id int64, ifMatch int64) (Task, error) {
+	var task Task
+	err := c.Call(ctx, "TaskService.CompleteTask", completeTaskParams{ID: id, IfMatch: ifMatch}, &task)
+	return task, err
+}
+
+func (c *RPCClient) Tasks(ctx context.Context) ([]Task, error) {
+	var items []Task
+	err := c.Call(ctx, "TaskService.Tasks", nil, &items)
+	return items, err
+}
+
+// LESSON 10: End-to-end server
+// Why this matters: curl the REST routes and POST /rpc against the same
+// process; a task created one way is visible the other way.
+func main() {
+	db, err := sql.Open("sqlite", "lessons/code/tmp_tasks_rpc.db")
+	if err != nil {
+		fmt.Println("open db error:", err)
+		return
+	}
+	defer db.Close()
+
+	repo := NewSQLiteTaskRepo(db)
+	if err := repo.Migrate(); err != nil {
+		fmt.Println("migrate error:", err)
+		return
+	}
+	mux := buildMux(NewTaskService(repo))
+
+	addr := ":8102"
+	fmt.Println("Go JSON-RPC server on", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Println("server error:", err)
+	}
+}
+
+// End of Go JSON-RPC 1-10
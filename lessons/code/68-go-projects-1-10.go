@@ -1,8 +1,15 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"math/big"
+	"os"
 	"sort"
 	"strings"
 )
@@ -13,25 +20,312 @@ GO PROJECTS (Lessons 1-10)
 Suggested use:
 1) Run: go run lessons/code/68-go-projects-1-10.go
 2) Change one business rule and predict output before running
+3) Add an expense in a currency missing from the rate table and watch the
+   report fail instead of guessing
+4) Pick another report format: REPORT_FORMAT=markdown (or csv, json, html)
+   go run lessons/code/68-go-projects-1-10.go
 
 Extra context:
 - lessons/notes/155-go-projects-principles.md
 - lessons/notes/151-go-first-principles.md
+- lessons/notes/204-money-as-integers-first-principles.md
 */
 
+// Currency is an ISO 4217 code such as "EUR".
+type Currency string
+
+// currencyExponents says how many minor units a currency has (cents for
+// EUR, none for JPY). Only listed currencies are accepted; a real system
+// would load the full ISO 4217 table.
+var currencyExponents = map[Currency]int{
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"USD": 2,
+}
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrAmountOverflow   = errors.New("amount overflows int64 minor units")
+	ErrMissingRate      = errors.New("no exchange rate")
+)
+
+func (c Currency) exponent() (int, error) {
+	exp, ok := currencyExponents[c]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, string(c))
+	}
+	return exp, nil
+}
+
+// Money is an exact amount: an integer count of minor units (cents) plus
+// its currency. Adding integers never drifts the way float64 sums do.
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+// ParseMoney reads a plain decimal such as "12.5" or "-3.05" without ever
+// going through float64. More decimals than the currency has is an error,
+// not a silent rounding.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exp, err := currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	raw := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(raw, "-")
+	whole, frac, hasPoint := strings.Cut(raw, ".")
+	if whole == "" || (hasPoint && frac == "") || !allDigits(whole) || !allDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, exp, currency)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	var minor int64
+	for _, r := range whole + frac {
+		if minor > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrAmountOverflow, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney is for literals in code and tests; it panics on bad input.
+func MustParseMoney(amount string, currency Currency) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Minor > 0 && m.Minor > math.MaxInt64-other.Minor) ||
+		(other.Minor < 0 && m.Minor < math.MinInt64-other.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Minor: m.Minor + other.Minor, Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or +1; amounts in different currencies do not compare.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String prints the exact decimal, e.g. "12.50 EUR".
+func (m Money) String() string {
+	if _, err := m.Currency.exponent(); err != nil {
+		return fmt.Sprintf("%d minor units of %s", m.Minor, m.Currency)
+	}
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+// Decimal is the amount without its currency, e.g. "12.50", for tables
+// and files that put the currency in a header instead.
+func (m Money) Decimal() string {
+	exp, err := m.Currency.exponent()
+	if err != nil {
+		return fmt.Sprint(m.Minor)
+	}
+	sign := ""
+	abs := new(big.Int).SetInt64(m.Minor) // big.Int: -MinInt64 does not fit int64
+	if abs.Sign() < 0 {
+		sign = "-"
+		abs.Neg(abs)
+	}
+	digits := abs.String()
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// RateTable converts into one reporting currency. A rate says how many
+// units of the base one unit of the currency is worth ("USD": "0.92" with
+// base EUR). Rates are exact decimals held as big.Rat, never float64.
+type RateTable struct {
+	base  Currency
+	rates map[Currency]*big.Rat
+}
+
+func NewRateTable(base Currency, rates map[Currency]string) (*RateTable, error) {
+	if _, err := base.exponent(); err != nil {
+		return nil, err
+	}
+	t := &RateTable{base: base, rates: map[Currency]*big.Rat{base: big.NewRat(1, 1)}}
+	for currency, raw := range rates {
+		if _, err := currency.exponent(); err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(raw))
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate for %s must be a positive decimal, got %q", currency, raw)
+		}
+		t.rates[currency] = rate
+	}
+	return t, nil
+}
+
+func (t *RateTable) Base() Currency {
+	return t.base
+}
+
+// Convert rounds once, half away from zero, to the target's minor unit.
+// Callers should convert sums rather than sum conversions, so that only
+// one rounding happens per currency.
+func (t *RateTable) Convert(m Money, to Currency) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	if t == nil {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, to)
+	}
+	fromExp, err := m.Currency.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	toExp, err := to.exponent()
+	if err != nil {
+		return Money{}, err
+	}
+	fromRate, ok := t.rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, m.Currency, t.base)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s -> %s", ErrMissingRate, to, t.base)
+	}
+	// minor / 10^fromExp major units, times fromRate/toRate, times 10^toExp.
+	value := new(big.Rat).SetInt64(m.Minor)
+	value.Mul(value, fromRate)
+	value.Quo(value, toRate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toExp), pow10(fromExp)))
+
+	minor, err := roundHalfAway(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: to}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func roundHalfAway(r *big.Rat) (int64, error) {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Denom is always positive, so rem carries the sign of the value.
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return q.Int64(), nil
+}
+
 type Expense struct {
 	Category string
-	Amount   float64
+	Amount   Money
+}
+
+// Totals holds one exact sum per currency; nothing is converted until the
+// report asks for it.
+type Totals map[Currency]Money
+
+func (t Totals) add(m Money) error {
+	current, ok := t[m.Currency]
+	if !ok {
+		t[m.Currency] = m
+		return nil
+	}
+	sum, err := current.Add(m)
+	if err != nil {
+		return err
+	}
+	t[m.Currency] = sum
+	return nil
+}
+
+// Currencies returns the currencies present, sorted for stable output.
+func (t Totals) Currencies() []Currency {
+	out := make([]Currency, 0, len(t))
+	for c := range t {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// In converts each currency's sum once and adds the results.
+func (t Totals) In(rates *RateTable, to Currency) (Money, error) {
+	total := Money{Currency: to}
+	for _, c := range t.Currencies() {
+		converted, err := rates.Convert(t[c], to)
+		if err != nil {
+			return Money{}, err
+		}
+		if total, err = total.Add(converted); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
 }
 
 // LESSON 1: Aggregate totals by category
 // Why this matters: report aggregation is common in real tools.
-func TotalsByCategory(items []Expense) map[string]float64 {
-	out := make(map[string]float64)
+func TotalsByCategory(items []Expense) (map[string]Totals, error) {
+	out := make(map[string]Totals)
 	for _, it := range items {
-		out[it.Category] += it.Amount
+		if out[it.Category] == nil {
+			out[it.Category] = Totals{}
+		}
+		if err := out[it.Category].add(it.Amount); err != nil {
+			return nil, fmt.Errorf("category %q: %w", it.Category, err)
+		}
 	}
-	return out
+	return out, nil
 }
 
 // LESSON 2: Input validation helper
@@ -40,7 +334,10 @@ func ValidateExpense(e Expense) error {
 	if strings.TrimSpace(e.Category) == "" {
 		return errors.New("category is required")
 	}
-	if e.Amount < 0 {
+	if _, err := e.Amount.Currency.exponent(); err != nil {
+		return err
+	}
+	if e.Amount.Minor < 0 {
 		return errors.New("amount cannot be negative")
 	}
 	return nil
@@ -52,25 +349,398 @@ func NormalizeCategory(raw string) string {
 	return strings.ToLower(strings.TrimSpace(raw))
 }
 
-// LESSON 4: Build report lines
-// Why this matters: separate formatting from computation.
-func BuildReportLines(totals map[string]float64) []string {
+// Report is the computed result that every renderer formats. Converted
+// says whether Total, GrandTotal and Permille are filled in: they are when
+// a rate table is given, or when everything is in one currency anyway.
+type Report struct {
+	Rows       []ReportRow
+	Totals     Totals // per currency, across all categories
+	Converted  bool
+	GrandTotal Money // in the reporting currency
+}
+
+type ReportRow struct {
+	Category string
+	Amounts  Totals
+	Total    Money // Amounts converted into the reporting currency
+	Permille int64 // share of GrandTotal in tenths of a percent
+}
+
+// Currency is the reporting currency, or "" when nothing was converted.
+func (r Report) Currency() Currency {
+	if !r.Converted {
+		return ""
+	}
+	return r.GrandTotal.Currency
+}
+
+// BuildReport does all the arithmetic; renderers only format.
+func BuildReport(totals map[string]Totals, rates *RateTable) (Report, error) {
 	keys := make([]string, 0, len(totals))
 	for k := range totals {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	lines := make([]string, 0, len(keys))
+	report := Report{Rows: make([]ReportRow, 0, len(keys)), Totals: Totals{}}
 	for _, k := range keys {
-		lines = append(lines, fmt.Sprintf("- %s: %.2f", k, totals[k]))
+		for _, c := range totals[k].Currencies() {
+			if err := report.Totals.add(totals[k][c]); err != nil {
+				return Report{}, fmt.Errorf("category %q: %w", k, err)
+			}
+		}
+		report.Rows = append(report.Rows, ReportRow{Category: k, Amounts: totals[k]})
+	}
+
+	var to Currency
+	switch currencies := report.Totals.Currencies(); {
+	case rates != nil:
+		to = rates.Base()
+	case len(currencies) == 1:
+		to = currencies[0]
+	default:
+		return report, nil // mixed currencies and no rates: sums only
+	}
+	report.Converted = true
+	report.GrandTotal = Money{Currency: to}
+	for i := range report.Rows {
+		total, err := report.Rows[i].Amounts.In(rates, to)
+		if err != nil {
+			return Report{}, fmt.Errorf("category %q: %w", report.Rows[i].Category, err)
+		}
+		report.Rows[i].Total = total
+		if report.GrandTotal, err = report.GrandTotal.Add(total); err != nil {
+			return Report{}, err
+		}
+	}
+	shares := make([]int64, len(report.Rows))
+	for i, row := range report.Rows {
+		shares[i] = row.Total.Minor
+	}
+	for i, p := range permilleShares(shares, report.GrandTotal.Minor) {
+		report.Rows[i].Permille = p
+	}
+	return report, nil
+}
+
+// permilleShares rounds each part to tenths of a percent so that the
+// shares add up to exactly 100.0%: everyone gets the rounded-down value
+// and the leftover tenths go to the largest remainders.
+func permilleShares(parts []int64, whole int64) []int64 {
+	out := make([]int64, len(parts))
+	if whole <= 0 {
+		return out
+	}
+	remainders := make([]*big.Int, len(parts))
+	left := int64(1000)
+	for i, part := range parts {
+		q, r := new(big.Int).QuoRem(
+			new(big.Int).Mul(big.NewInt(part), big.NewInt(1000)), big.NewInt(whole), new(big.Int))
+		out[i] = q.Int64()
+		remainders[i] = r
+		left -= out[i]
 	}
-	return lines
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]].Cmp(remainders[order[b]]) > 0 })
+	for _, i := range order[:left] {
+		out[i]++
+	}
+	return out
+}
+
+func formatPermille(p int64) string {
+	return fmt.Sprintf("%d.%d%%", p/10, p%10)
+}
+
+// LESSON 4: Build report lines
+// Why this matters: separate formatting from computation. Each line shows
+// the exact per-currency sums, then (when more than the reporting currency
+// is involved) their converted total. With nil rates nothing is converted.
+func BuildReportLines(totals map[string]Totals, rates *RateTable) ([]string, error) {
+	report, err := BuildReport(totals, rates)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(report.Rows))
+	for _, row := range report.Rows {
+		lines = append(lines, textLine(row, report))
+	}
+	return lines, nil
+}
+
+func textLine(row ReportRow, report Report) string {
+	currencies := row.Amounts.Currencies()
+	parts := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		parts = append(parts, row.Amounts[c].String())
+	}
+	line := fmt.Sprintf("- %s: %s", row.Category, strings.Join(parts, " + "))
+	if report.Converted && (len(currencies) > 1 || currencies[0] != report.Currency()) {
+		line += " = " + row.Total.String()
+	}
+	return line
+}
+
+// ReportRenderer writes one Report in one format. The computation is done
+// once in BuildReport; adding a format means adding a renderer, nothing else.
+type ReportRenderer interface {
+	Render(w io.Writer, report Report) error
+}
+
+// renderers maps the names accepted by RendererFor (and REPORT_FORMAT).
+var renderers = map[string]ReportRenderer{
+	"text":     TextRenderer{},
+	"markdown": MarkdownRenderer{},
+	"csv":      CSVRenderer{},
+	"json":     JSONRenderer{},
+	"html":     HTMLRenderer{Title: "Expense report"},
+}
+
+func RendererFor(format string) (ReportRenderer, error) {
+	r, ok := renderers[strings.ToLower(strings.TrimSpace(format))]
+	if !ok {
+		names := make([]string, 0, len(renderers))
+		for name := range renderers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown report format %q (want one of %s)", format, strings.Join(names, ", "))
+	}
+	return r, nil
+}
+
+// tableColumns is the shared layout of the tabular formats: one column
+// per currency, then the converted total and its share when there is one.
+func tableColumns(report Report) (currencies []Currency, header []string) {
+	currencies = report.Totals.Currencies()
+	header = []string{"Category"}
+	for _, c := range currencies {
+		header = append(header, string(c))
+	}
+	if report.Converted {
+		header = append(header, "Total ("+string(report.Currency())+")", "Share")
+	}
+	return currencies, header
+}
+
+// tableCells returns one row of amounts as bare decimals (no currency
+// suffix, so spreadsheets read them as numbers); empty for "none".
+func tableCells(amounts Totals, currencies []Currency, total Money, permille int64, converted bool) []string {
+	cells := []string{}
+	for _, c := range currencies {
+		if m, ok := amounts[c]; ok {
+			cells = append(cells, m.Decimal())
+		} else {
+			cells = append(cells, "")
+		}
+	}
+	if converted {
+		cells = append(cells, total.Decimal(), formatPermille(permille))
+	}
+	return cells
+}
+
+// tableRows is the body plus the grand-total row, ready for any table.
+func tableRows(report Report) (header []string, rows [][]string) {
+	currencies, header := tableColumns(report)
+	for _, row := range report.Rows {
+		rows = append(rows, append([]string{row.Category},
+			tableCells(row.Amounts, currencies, row.Total, row.Permille, report.Converted)...))
+	}
+	rows = append(rows, append([]string{"Total"},
+		tableCells(report.Totals, currencies, report.GrandTotal, 1000, report.Converted)...))
+	return header, rows
+}
+
+// TextRenderer is the Lesson 4 list plus shares and a grand total.
+type TextRenderer struct{}
+
+func (TextRenderer) Render(w io.Writer, report Report) error {
+	for _, row := range report.Rows {
+		line := textLine(row, report)
+		if report.Converted {
+			line += " (" + formatPermille(row.Permille) + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	if !report.Converted {
+		parts := []string{}
+		for _, c := range report.Totals.Currencies() {
+			parts = append(parts, report.Totals[c].String())
+		}
+		_, err := fmt.Fprintf(w, "total: %s\n", strings.Join(parts, " + "))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "total: %s\n", report.GrandTotal)
+	return err
+}
+
+// MarkdownRenderer writes a GitHub-style table for the wiki.
+type MarkdownRenderer struct{}
+
+func (MarkdownRenderer) Render(w io.Writer, report Report) error {
+	header, rows := tableRows(report)
+	align := []string{"---"}
+	for range header[1:] {
+		align = append(align, "---:")
+	}
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = strings.ReplaceAll(c, "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(escaped, " | ") + " |\n")
+	}
+	writeRow(header)
+	b.WriteString("| " + strings.Join(align, " | ") + " |\n")
+	for i, row := range rows {
+		if i == len(rows)-1 {
+			row[0] = "**" + row[0] + "**"
+		}
+		writeRow(row)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CSVRenderer writes the same table for spreadsheets.
+type CSVRenderer struct{}
+
+func (CSVRenderer) Render(w io.Writer, report Report) error {
+	header, rows := tableRows(report)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		row[0] = spreadsheetSafe(row[0])
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// spreadsheetSafe stops a category like "=HYPERLINK(...)" from being run
+// as a formula when the CSV is opened in a spreadsheet.
+func spreadsheetSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// JSONRenderer keeps amounts as decimal strings: a JSON number would be
+// read back as a float by most clients.
+type JSONRenderer struct{}
+
+type jsonMoney struct {
+	Amount   string   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+type jsonReportRow struct {
+	Category     string      `json:"category"`
+	Amounts      []jsonMoney `json:"amounts"`
+	Total        *jsonMoney  `json:"total,omitempty"`
+	SharePercent json.Number `json:"share_percent,omitempty"`
+}
+
+type jsonReport struct {
+	Currency   Currency        `json:"currency,omitempty"`
+	Rows       []jsonReportRow `json:"rows"`
+	Totals     []jsonMoney     `json:"totals"`
+	GrandTotal *jsonMoney      `json:"grand_total,omitempty"`
+}
+
+func toJSONMoney(t Totals) []jsonMoney {
+	out := []jsonMoney{}
+	for _, c := range t.Currencies() {
+		out = append(out, jsonMoney{Amount: t[c].Decimal(), Currency: c})
+	}
+	return out
+}
+
+func (JSONRenderer) Render(w io.Writer, report Report) error {
+	out := jsonReport{Currency: report.Currency(), Rows: []jsonReportRow{}, Totals: toJSONMoney(report.Totals)}
+	for _, row := range report.Rows {
+		jr := jsonReportRow{Category: row.Category, Amounts: toJSONMoney(row.Amounts)}
+		if report.Converted {
+			jr.Total = &jsonMoney{Amount: row.Total.Decimal(), Currency: row.Total.Currency}
+			jr.SharePercent = json.Number(strings.TrimSuffix(formatPermille(row.Permille), "%"))
+		}
+		out.Rows = append(out.Rows, jr)
+	}
+	if report.Converted {
+		out.GrandTotal = &jsonMoney{Amount: report.GrandTotal.Decimal(), Currency: report.GrandTotal.Currency}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// HTMLRenderer writes one self-contained page (inline CSS, no scripts or
+// external files) that can be mailed or attached as is. html/template
+// escapes every category name.
+type HTMLRenderer struct {
+	Title string
+}
+
+var reportPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3rem 0.8rem; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+tr.total { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<thead><tr>{{range $i, $h := .Header}}<th{{if $i}} class="num"{{end}}>{{$h}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range $r, $row := .Rows}}
+<tr{{if eq $r $.Last}} class="total"{{end}}>{{range $i, $c := $row}}<td{{if $i}} class="num"{{end}}>{{$c}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
+
+func (h HTMLRenderer) Render(w io.Writer, report Report) error {
+	header, rows := tableRows(report)
+	return reportPage.Execute(w, struct {
+		Title  string
+		Header []string
+		Rows   [][]string
+		Last   int
+	}{Title: h.Title, Header: header, Rows: rows, Last: len(rows) - 1})
 }
 
 // LESSON 5: Service-style processor
 // Why this matters: encapsulates workflow logic.
-func ProcessExpenses(items []Expense) ([]string, error) {
+func ProcessExpenses(items []Expense, rates *RateTable) ([]string, error) {
+	totals, err := normalizedTotals(items)
+	if err != nil {
+		return nil, err
+	}
+	return BuildReportLines(totals, rates)
+}
+
+func normalizedTotals(items []Expense) (map[string]Totals, error) {
 	normalized := make([]Expense, 0, len(items))
 	for _, e := range items {
 		e.Category = NormalizeCategory(e.Category)
@@ -79,73 +749,124 @@ func ProcessExpenses(items []Expense) ([]string, error) {
 		}
 		normalized = append(normalized, e)
 	}
-	totals := TotalsByCategory(normalized)
-	return BuildReportLines(totals), nil
+	return TotalsByCategory(normalized)
 }
 
 // LESSON 6: Basic command-like runner
-// Why this matters: thin orchestration at the edge.
-func RunReport(items []Expense) {
-	lines, err := ProcessExpenses(items)
+// Why this matters: thin orchestration at the edge. The caller picks the
+// renderer; the runner does not know which formats exist.
+func RunReport(w io.Writer, items []Expense, rates *RateTable, renderer ReportRenderer) error {
+	totals, err := normalizedTotals(items)
 	if err != nil {
-		fmt.Println("Lesson 6 error:", err)
-		return
+		return err
 	}
-	fmt.Println("Lesson 6 report:")
-	for _, line := range lines {
-		fmt.Println(line)
+	report, err := BuildReport(totals, rates)
+	if err != nil {
+		return err
 	}
+	return renderer.Render(w, report)
 }
 
 // LESSON 7: Search helper
-// Why this matters: simple filtering utility for future CLI/API.
-func FilterByMin(items []Expense, min float64) []Expense {
+// Why this matters: simple filtering utility for future CLI/API. Amounts
+// in other currencies are converted into min's currency to compare.
+func FilterByMin(items []Expense, min Money, rates *RateTable) ([]Expense, error) {
 	out := make([]Expense, 0)
 	for _, e := range items {
-		if e.Amount >= min {
+		amount, err := rates.Convert(e.Amount, min.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if amount.Minor >= min.Minor {
 			out = append(out, e)
 		}
 	}
-	return out
+	return out, nil
 }
 
 // LESSON 8: Budget status helper
 // Why this matters: domain-oriented output can drive UI/API.
-func BudgetStatus(limit float64, spent float64) string {
-	if spent < limit {
-		return "under"
+func BudgetStatus(limit Money, spent Money) (string, error) {
+	cmp, err := spent.Cmp(limit)
+	if err != nil {
+		return "", err
 	}
-	if spent > limit {
-		return "over"
+	switch cmp {
+	case -1:
+		return "under", nil
+	case 1:
+		return "over", nil
+	default:
+		return "exact", nil
 	}
-	return "exact"
 }
 
 // LESSON 9: Sum helper
 // Why this matters: reusable single-purpose utility.
-func SumAmounts(items []Expense) float64 {
-	total := 0.0
+func SumAmounts(items []Expense) (Totals, error) {
+	totals := Totals{}
 	for _, e := range items {
-		total += e.Amount
+		if err := totals.add(e.Amount); err != nil {
+			return nil, err
+		}
 	}
-	return total
+	return totals, nil
 }
 
 // LESSON 10: End-to-end sample
 // Why this matters: composes helpers into practical output.
 func main() {
+	rates, err := NewRateTable("EUR", map[Currency]string{"USD": "0.92", "GBP": "1.17"})
+	if err != nil {
+		fmt.Println("Lesson 10 rates error:", err)
+		return
+	}
 	items := []Expense{
-		{Category: " Food ", Amount: 20.5},
-		{Category: "food", Amount: 15.0},
-		{Category: "transport", Amount: 12.25},
+		{Category: " Food ", Amount: MustParseMoney("20.50", "EUR")},
+		{Category: "food", Amount: MustParseMoney("15", "EUR")},
+		{Category: "food", Amount: MustParseMoney("12.99", "USD")},
+		{Category: "transport", Amount: MustParseMoney("12.25", "EUR")},
+		{Category: "travel", Amount: MustParseMoney("80", "GBP")},
 	}
 
-	RunReport(items)
+	format := os.Getenv("REPORT_FORMAT")
+	if format == "" {
+		format = "text"
+	}
+	renderer, err := RendererFor(format)
+	if err != nil {
+		fmt.Println("Lesson 6 error:", err)
+		return
+	}
+	fmt.Println("Lesson 6 report:")
+	if err := RunReport(os.Stdout, items, rates, renderer); err != nil {
+		fmt.Println("Lesson 6 error:", err)
+		return
+	}
 
-	filtered := FilterByMin(items, 15)
-	spent := SumAmounts(filtered)
+	filtered, err := FilterByMin(items, MustParseMoney("15", "EUR"), rates)
+	if err != nil {
+		fmt.Println("Lesson 10 filter error:", err)
+		return
+	}
+	totals, err := SumAmounts(filtered)
+	if err != nil {
+		fmt.Println("Lesson 10 sum error:", err)
+		return
+	}
+	spent, err := totals.In(rates, "EUR")
+	if err != nil {
+		fmt.Println("Lesson 10 convert error:", err)
+		return
+	}
+	status, _ := BudgetStatus(MustParseMoney("150", "EUR"), spent)
 	fmt.Println("Lesson 10 filtered spent:", spent)
-	fmt.Println("Lesson 10 status:", BudgetStatus(40, spent))
+	fmt.Println("Lesson 10 status:", status)
+
+	// In float64, 0.1 + 0.2 is 0.30000000000000004; in minor units it is 30.
+	a, b := 0.1, 0.2
+	sum, _ := MustParseMoney("0.10", "EUR").Add(MustParseMoney("0.20", "EUR"))
+	fmt.Println("Lesson 10 exact sum:", sum, "float64 sum:", a+b)
 }
 
 // End of Go Projects 1-10
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -17,6 +23,8 @@ Suggested use:
 2) Call endpoints:
    - GET  http://localhost:8080/health
    - GET  http://localhost:8080/tasks
+   - GET  http://localhost:8080/tasks?limit=2&done=false&sort=-title&q=go
+   - GET  http://localhost:8080/tasks?limit=2&cursor=<next_cursor from previous page>
    - POST http://localhost:8080/tasks  {"title":"Write Go API"}
 
 Extra context:
@@ -32,9 +40,163 @@ type Task struct {
 
 type TaskRepo interface {
 	List() []Task
+	Query(q TaskQuery) []Task
 	Add(title string) Task
 }
 
+var ErrInvalidQuery = errors.New("invalid list query")
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var validSorts = map[string]bool{"id": true, "-id": true, "title": true, "-title": true}
+
+// TaskQuery is everything a repository needs to produce one page.
+// Sort is "id" or "title", with a leading "-" for descending order.
+type TaskQuery struct {
+	Limit  int
+	Done   *bool
+	Search string
+	Sort   string
+	After  *PagePosition
+}
+
+// PagePosition is the last row of the previous page (keyset pagination).
+type PagePosition struct {
+	Title string `json:"t"`
+	ID    int    `json:"i"`
+}
+
+type ListTasksRequest struct {
+	Limit  int
+	Cursor string
+	Done   *bool
+	Search string
+	Sort   string
+}
+
+type TaskPage struct {
+	Items      []Task `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursorPayload binds a position to the filters it was produced for.
+type cursorPayload struct {
+	Sort   string       `json:"s"`
+	Done   *bool        `json:"d,omitempty"`
+	Search string       `json:"q,omitempty"`
+	After  PagePosition `json:"a"`
+}
+
+// CursorCodec makes cursors opaque (base64) and tamper-proof (HMAC-SHA256).
+type CursorCodec struct {
+	secret []byte
+}
+
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{secret: secret}
+}
+
+func (c *CursorCodec) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+func (c *CursorCodec) Encode(p cursorPayload) (string, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(raw)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body)), nil
+}
+
+func (c *CursorCodec) Decode(cursor string) (cursorPayload, error) {
+	invalid := fmt.Errorf("%w: cursor is invalid", ErrInvalidQuery)
+	body, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return cursorPayload{}, invalid
+	}
+	given, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(given, c.sign(body)) {
+		return cursorPayload{}, invalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return cursorPayload{}, invalid
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return cursorPayload{}, invalid
+	}
+	return p, nil
+}
+
+func sameDoneFilter(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// randomSecret is the fallback when no secret is configured; cursors then
+// stop working after a restart, which is safe but inconvenient.
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+// compareTasks orders tasks by the sort key, using id as the tie-breaker.
+func compareTasks(sortKey string, a, b Task) int {
+	field, desc := strings.TrimPrefix(sortKey, "-"), strings.HasPrefix(sortKey, "-")
+	c := cmp.Compare(a.ID, b.ID)
+	if field == "title" && a.Title != b.Title {
+		c = cmp.Compare(a.Title, b.Title)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+// foldTitle is the one case fold search uses. The SQLite adapter in
+// 84-go-sqlite-http-11-20.go calls this same function as fold(), because
+// SQLite's own lower() only folds ASCII.
+func foldTitle(s string) string {
+	return strings.ToLower(s)
+}
+
+// applyTaskQuery gives in-process adapters the same semantics as the SQL one.
+func applyTaskQuery(items []Task, q TaskQuery) []Task {
+	search := foldTitle(q.Search)
+	out := make([]Task, 0)
+	for _, t := range items {
+		if q.Done != nil && t.Done != *q.Done {
+			continue
+		}
+		if search != "" && !strings.Contains(foldTitle(t.Title), search) {
+			continue
+		}
+		if q.After != nil && compareTasks(q.Sort, t, Task{ID: q.After.ID, Title: q.After.Title}) <= 0 {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return compareTasks(q.Sort, out[i], out[j]) < 0
+	})
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out
+}
+
 type InMemoryTaskRepo struct {
 	items []Task
 }
@@ -45,6 +207,10 @@ func (r *InMemoryTaskRepo) List() []Task {
 	return out
 }
 
+func (r *InMemoryTaskRepo) Query(q TaskQuery) []Task {
+	return applyTaskQuery(r.items, q)
+}
+
 func (r *InMemoryTaskRepo) Add(title string) Task {
 	nextID := 1
 	if len(r.items) > 0 {
@@ -56,7 +222,8 @@ func (r *InMemoryTaskRepo) Add(title string) Task {
 }
 
 type TaskService struct {
-	repo TaskRepo
+	repo    TaskRepo
+	cursors *CursorCodec
 }
 
 func (s *TaskService) CreateTask(title string) (Task, error) {
@@ -67,18 +234,52 @@ func (s *TaskService) CreateTask(title string) (Task, error) {
 	return s.repo.Add(clean), nil
 }
 
-func (s *TaskService) ListTasks(minID int) []Task {
-	all := s.repo.List()
-	if minID <= 0 {
-		return all
+// ListTasks validates the request, resolves the cursor and asks the repo for
+// one extra row so it knows whether another page exists.
+func (s *TaskService) ListTasks(req ListTasksRequest) (TaskPage, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxPageSize {
+		return TaskPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxPageSize)
+	}
+	sortKey := req.Sort
+	if sortKey == "" {
+		sortKey = "id"
+	}
+	if !validSorts[sortKey] {
+		return TaskPage{}, fmt.Errorf("%w: sort must be one of id, -id, title, -title", ErrInvalidQuery)
 	}
-	filtered := make([]Task, 0)
-	for _, t := range all {
-		if t.ID >= minID {
-			filtered = append(filtered, t)
+	q := TaskQuery{Limit: limit + 1, Done: req.Done, Search: strings.TrimSpace(req.Search), Sort: sortKey}
+	if req.Cursor != "" {
+		payload, err := s.cursors.Decode(req.Cursor)
+		if err != nil {
+			return TaskPage{}, err
 		}
+		if payload.Sort != q.Sort || payload.Search != q.Search || !sameDoneFilter(payload.Done, q.Done) {
+			return TaskPage{}, fmt.Errorf("%w: cursor does not match query", ErrInvalidQuery)
+		}
+		q.After = &payload.After
 	}
-	return filtered
+
+	items := s.repo.Query(q)
+	page := TaskPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		next, err := s.cursors.Encode(cursorPayload{
+			Sort:   q.Sort,
+			Done:   q.Done,
+			Search: q.Search,
+			After:  PagePosition{Title: last.Title, ID: last.ID},
+		})
+		if err != nil {
+			return TaskPage{}, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
 }
 
 type createTaskRequest struct {
@@ -91,6 +292,31 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+// parseListRequest maps ?limit=&cursor=&done=&sort=&q= onto the service request.
+func parseListRequest(r *http.Request) (ListTasksRequest, error) {
+	values := r.URL.Query()
+	req := ListTasksRequest{
+		Cursor: values.Get("cursor"),
+		Search: values.Get("q"),
+		Sort:   values.Get("sort"),
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return ListTasksRequest{}, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidQuery)
+		}
+		req.Limit = limit
+	}
+	if raw := values.Get("done"); raw != "" {
+		done, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ListTasksRequest{}, fmt.Errorf("%w: done must be true or false", ErrInvalidQuery)
+		}
+		req.Done = &done
+	}
+	return req, nil
+}
+
 // LESSON 1: Health handler
 // Why this matters: fast readiness signal for operations.
 func healthHandler(w http.ResponseWriter, _ *http.Request) {
@@ -106,16 +332,17 @@ func buildMux(service *TaskService) *http.ServeMux {
 	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			minID := 0
-			if raw := r.URL.Query().Get("min_id"); raw != "" {
-				parsed, err := strconv.Atoi(raw)
-				if err != nil {
-					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "min_id must be integer"})
-					return
-				}
-				minID = parsed
+			req, err := parseListRequest(r)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			page, err := service.ListTasks(req)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
 			}
-			writeJSON(w, http.StatusOK, service.ListTasks(minID))
+			writeJSON(w, http.StatusOK, page)
 			return
 
 		case http.MethodPost:
@@ -145,7 +372,7 @@ func buildMux(service *TaskService) *http.ServeMux {
 // Why this matters: composition root wires service and transport.
 func main() {
 	repo := &InMemoryTaskRepo{}
-	service := &TaskService{repo: repo}
+	service := &TaskService{repo: repo, cursors: NewCursorCodec(randomSecret())}
 	mux := buildMux(service)
 
 	addr := ":8080"
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -18,23 +24,70 @@ Suggested use:
 1) Run: go run lessons/code/72-go-capstone-1-10.go
 2) Create task: curl -X POST localhost:8082/tasks -d '{"title":"demo"}'
 3) List tasks:  curl localhost:8082/tasks
+4) Cancel task: curl -X POST localhost:8082/tasks/1/cancel
+   (works while queued or processing; a finished task answers 409)
+5) Tune the pool: WORKERS=8 go run lessons/code/72-go-capstone-1-10.go
+   A full queue answers 503 with Retry-After; Ctrl+C drains queued and
+   running tasks before the process exits (bounded by SHUTDOWN_TIMEOUT_S)
 
 Extra context:
 - lessons/notes/160-go-capstone-plan.md
 - lessons/notes/158-go-api-architecture-principles.md
 */
 
+type TaskStatus string
+
+const (
+	StatusQueued     TaskStatus = "queued"
+	StatusProcessing TaskStatus = "processing"
+	StatusDone       TaskStatus = "done"
+	StatusFailed     TaskStatus = "failed"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+// transitions is the whole lifecycle. done, failed and cancelled have no
+// entry, so nothing can leave them.
+var transitions = map[TaskStatus][]TaskStatus{
+	StatusQueued:     {StatusProcessing, StatusCancelled},
+	StatusProcessing: {StatusDone, StatusFailed, StatusCancelled},
+}
+
+func (s TaskStatus) CanMoveTo(next TaskStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrQueueFull         = errors.New("task queue is full")
+	ErrShuttingDown      = errors.New("service is shutting down")
+)
+
+// StatusChange records when a task entered a status.
+type StatusChange struct {
+	Status TaskStatus `json:"status"`
+	At     time.Time  `json:"at"`
+}
+
 type Task struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int            `json:"id"`
+	Title     string         `json:"title"`
+	Status    TaskStatus     `json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	History   []StatusChange `json:"history"`
 }
 
 type TaskRepo interface {
 	Add(title string) Task
 	List() []Task
-	UpdateStatus(id int, status string)
+	// UpdateStatus checks the transition and applies it in one step, so a
+	// cancel and the worker finishing cannot both win.
+	UpdateStatus(id int, status TaskStatus) (Task, error)
 }
 
 type InMemoryTaskRepo struct {
@@ -49,8 +102,16 @@ func (r *InMemoryTaskRepo) Add(title string) Task {
 	if len(r.items) > 0 {
 		nextID = r.items[len(r.items)-1].ID + 1
 	}
-	t := Task{ID: nextID, Title: title, Status: "queued", CreatedAt: time.Now()}
+	now := time.Now()
+	t := Task{ID: nextID, Title: title, Status: StatusQueued, CreatedAt: now,
+		History: []StatusChange{{Status: StatusQueued, At: now}}}
 	r.items = append(r.items, t)
+	return cloneTask(t)
+}
+
+// cloneTask keeps callers from appending to the stored history.
+func cloneTask(t Task) Task {
+	t.History = append([]StatusChange{}, t.History...)
 	return t
 }
 
@@ -58,28 +119,70 @@ func (r *InMemoryTaskRepo) List() []Task {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	out := make([]Task, len(r.items))
-	copy(out, r.items)
+	for i, t := range r.items {
+		out[i] = cloneTask(t)
+	}
 	return out
 }
 
-func (r *InMemoryTaskRepo) UpdateStatus(id int, status string) {
+func (r *InMemoryTaskRepo) UpdateStatus(id int, status TaskStatus) (Task, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	for i := range r.items {
-		if r.items[i].ID == id {
-			r.items[i].Status = status
-			return
+		if r.items[i].ID != id {
+			continue
 		}
+		current := r.items[i].Status
+		if !current.CanMoveTo(status) {
+			return Task{}, fmt.Errorf("task %d: %s -> %s: %w", id, current, status, ErrInvalidTransition)
+		}
+		r.items[i].Status = status
+		r.items[i].History = append(r.items[i].History, StatusChange{Status: status, At: time.Now()})
+		return cloneTask(r.items[i]), nil
 	}
+	return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
 }
 
 type TaskService struct {
 	repo      TaskRepo
 	workQueue chan Task
+	// work does the actual processing; it must return early once ctx is
+	// cancelled.
+	work func(ctx context.Context, task Task) error
+
+	// mu serialises enqueues with closing the queue, and guards running.
+	mu      sync.Mutex
+	closed  bool
+	running map[int]context.CancelFunc
+
+	workers sync.WaitGroup
+	// stopCtx is cancelled when Shutdown runs out of time; every task
+	// context derives from it.
+	stopCtx context.Context
+	stop    context.CancelFunc
 }
 
 func NewTaskService(repo TaskRepo, queueSize int) *TaskService {
-	return &TaskService{repo: repo, workQueue: make(chan Task, queueSize)}
+	stopCtx, stop := context.WithCancel(context.Background())
+	return &TaskService{
+		repo:      repo,
+		workQueue: make(chan Task, queueSize),
+		work:      simulateWork(40 * time.Millisecond),
+		running:   map[int]context.CancelFunc{},
+		stopCtx:   stopCtx,
+		stop:      stop,
+	}
+}
+
+func simulateWork(d time.Duration) func(ctx context.Context, task Task) error {
+	return func(ctx context.Context, _ Task) error {
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 func (s *TaskService) CreateTask(title string) (Task, error) {
@@ -87,6 +190,17 @@ func (s *TaskService) CreateTask(title string) (Task, error) {
 	if clean == "" {
 		return Task{}, fmt.Errorf("title cannot be empty")
 	}
+	// Only enqueuers hold mu and workers only ever drain the channel, so a
+	// free slot seen here is still free at the send below. Checking before
+	// Add keeps a rejected request from leaving a task nobody will run.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Task{}, ErrShuttingDown
+	}
+	if len(s.workQueue) == cap(s.workQueue) {
+		return Task{}, ErrQueueFull
+	}
 	t := s.repo.Add(clean)
 	s.workQueue <- t
 	return t, nil
@@ -96,14 +210,97 @@ func (s *TaskService) ListTasks() []Task {
 	return s.repo.List()
 }
 
+// CancelTask stops a task that is queued or processing. The status changes
+// first; the worker then sees its context cancelled, and its own attempt
+// to finish the task is rejected as an invalid transition.
+func (s *TaskService) CancelTask(id int) (Task, error) {
+	task, err := s.repo.UpdateStatus(id, StatusCancelled)
+	if err != nil {
+		return Task{}, err
+	}
+	s.mu.Lock()
+	if cancel, ok := s.running[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+	return task, nil
+}
+
 func (s *TaskService) StartWorker() {
+	s.StartWorkers(1)
+}
+
+// StartWorkers adds n goroutines that pull from the shared queue until
+// Shutdown closes it.
+func (s *TaskService) StartWorkers(n int) {
+	for i := 0; i < n; i++ {
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			for task := range s.workQueue {
+				s.process(task)
+			}
+		}()
+	}
+}
+
+// Shutdown stops intake, then waits for the workers to finish every task
+// already accepted. If ctx expires first, running work is cancelled,
+// whatever is still queued is marked cancelled, and ctx.Err() is returned
+// once the workers have exited.
+func (s *TaskService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.workQueue)
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
 	go func() {
-		for task := range s.workQueue {
-			s.repo.UpdateStatus(task.ID, "processing")
-			time.Sleep(40 * time.Millisecond)
-			s.repo.UpdateStatus(task.ID, "done")
-		}
+		s.workers.Wait()
+		close(drained)
 	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		s.stop()
+		<-drained
+		return ctx.Err()
+	}
+}
+
+func (s *TaskService) process(task Task) {
+	if s.stopCtx.Err() != nil {
+		_, _ = s.repo.UpdateStatus(task.ID, StatusCancelled)
+		return
+	}
+	ctx, cancel := context.WithCancel(s.stopCtx)
+	defer cancel()
+	// Registered before the task turns processing, so a cancel that sees
+	// "processing" always finds something to stop.
+	s.mu.Lock()
+	s.running[task.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, task.ID)
+		s.mu.Unlock()
+	}()
+
+	if _, err := s.repo.UpdateStatus(task.ID, StatusProcessing); err != nil {
+		return // cancelled while still in the queue
+	}
+	final := StatusDone
+	if err := s.work(ctx, task); err != nil {
+		final = StatusFailed
+		if s.stopCtx.Err() != nil {
+			final = StatusCancelled
+		}
+	}
+	// After a cancel this fails on purpose: cancelled is final.
+	_, _ = s.repo.UpdateStatus(task.ID, final)
 }
 
 type createTaskRequest struct {
@@ -134,6 +331,11 @@ func buildMux(service *TaskService) *http.ServeMux {
 				return
 			}
 			task, err := service.CreateTask(req.Title)
+			if errors.Is(err, ErrQueueFull) || errors.Is(err, ErrShuttingDown) {
+				w.Header().Set("Retry-After", "1")
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+				return
+			}
 			if err != nil {
 				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 				return
@@ -144,20 +346,72 @@ func buildMux(service *TaskService) *http.ServeMux {
 		}
 	})
 
+	mux.HandleFunc("POST /tasks/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		task, err := service.CancelTask(id)
+		switch {
+		case errors.Is(err, ErrTaskNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case errors.Is(err, ErrInvalidTransition):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		default:
+			writeJSON(w, http.StatusOK, task)
+		}
+	})
+	mux.HandleFunc("/tasks/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	})
+
 	return mux
 }
 
+func envInt(name string, fallback int) int {
+	if raw := os.Getenv(name); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return fallback
+}
+
 func main() {
 	repo := &InMemoryTaskRepo{}
-	service := NewTaskService(repo, 100)
-	service.StartWorker()
+	service := NewTaskService(repo, envInt("QUEUE_SIZE", 100))
+	workers := envInt("WORKERS", 4)
+	service.StartWorkers(workers)
+
+	server := &http.Server{Addr: ":8082", Handler: buildMux(service)}
+	go func() {
+		fmt.Println("Go capstone server listening on", server.Addr, "workers:", workers)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Println("server error:", err)
+		}
+	}()
 
-	mux := buildMux(service)
-	addr := ":8082"
-	fmt.Println("Go capstone server listening on", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		fmt.Println("server error:", err)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+
+	// One deadline covers both steps. HTTP goes first so no request can
+	// enqueue after the workers start draining.
+	timeout := time.Duration(envInt("SHUTDOWN_TIMEOUT_S", 10)) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Println("http shutdown error:", err)
+	}
+	if err := service.Shutdown(ctx); err != nil {
+		fmt.Println("worker drain cut short:", err)
+		return
 	}
+	fmt.Println("Go capstone shutdown complete")
 }
 
 // End of Go Capstone 1-10
@@ -5,7 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 /*
@@ -14,6 +20,8 @@ GO PERSISTENCE + REPOSITORY (Lessons 1-10)
 Suggested use:
 1) Run: go run lessons/code/80-go-persistence-repo-1-10.go
 2) Delete `lessons/code/tmp_tasks.json` and rerun to observe first-run behavior
+3) Truncate `lessons/code/tmp_tasks.json` to a few bytes and rerun to observe
+   recovery from `tmp_tasks.json.bak`
 
 Extra context:
 - lessons/notes/167-go-persistence-first-principles.md
@@ -24,17 +32,24 @@ Extra context:
 // LESSON 1: Domain model
 // Why this matters: clear domain types make persistence format explicit.
 type Task struct {
-	ID    int    `json:"id"`
+	ID    int64  `json:"id"`
 	Title string `json:"title"`
 	Done  bool   `json:"done"`
 }
 
+// ErrTaskNotFound is the same for every adapter, so callers can check it
+// with errors.Is instead of matching message text.
+var ErrTaskNotFound = errors.New("task not found")
+
 // LESSON 2: Repository boundary
 // Why this matters: service depends on behavior, not storage details.
+// Every adapter promises the same: IDs 1, 2, 3... that are never reused
+// (not even after a restart), List in ID order, MarkDone on an unknown ID
+// returns ErrTaskNotFound, and all methods are safe for concurrent use.
 type TaskRepository interface {
 	List() ([]Task, error)
 	Add(title string) (Task, error)
-	MarkDone(id int) error
+	MarkDone(id int64) error
 }
 
 // LESSON 3: Service with DI
@@ -57,7 +72,7 @@ func (s *TaskService) CreateTask(title string) (Task, error) {
 	return s.repo.Add(clean)
 }
 
-func (s *TaskService) CompleteTask(id int) error {
+func (s *TaskService) CompleteTask(id int64) error {
 	if id <= 0 {
 		return errors.New("id must be positive")
 	}
@@ -71,17 +86,22 @@ func (s *TaskService) Tasks() ([]Task, error) {
 // LESSON 5: In-memory adapter
 // Why this matters: fastest adapter for learning and unit tests.
 type InMemoryTaskRepo struct {
+	mu    sync.Mutex
 	items []Task
 }
 
 func (r *InMemoryTaskRepo) List() ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	out := make([]Task, len(r.items))
 	copy(out, r.items)
 	return out, nil
 }
 
 func (r *InMemoryTaskRepo) Add(title string) (Task, error) {
-	nextID := 1
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nextID := int64(1)
 	if len(r.items) > 0 {
 		nextID = r.items[len(r.items)-1].ID + 1
 	}
@@ -90,90 +110,301 @@ func (r *InMemoryTaskRepo) Add(title string) (Task, error) {
 	return t, nil
 }
 
-func (r *InMemoryTaskRepo) MarkDone(id int) error {
+func (r *InMemoryTaskRepo) MarkDone(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	for i := range r.items {
 		if r.items[i].ID == id {
 			r.items[i].Done = true
 			return nil
 		}
 	}
-	return fmt.Errorf("task id %d not found", id)
+	return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
 }
 
 // LESSON 6: File adapter
 // Why this matters: persistence survives process restart.
 type JSONFileTaskRepo struct {
 	path string
+	mu   sync.Mutex
 }
 
 func NewJSONFileTaskRepo(path string) *JSONFileTaskRepo {
 	return &JSONFileTaskRepo{path: path}
 }
 
-func (r *JSONFileTaskRepo) load() ([]Task, error) {
-	data, err := os.ReadFile(r.path)
+const (
+	lockTimeout    = 5 * time.Second
+	lockRetryDelay = 5 * time.Millisecond
+)
+
+// withLock serializes goroutines (mutex) and processes (a sidecar lock file
+// holding the owner's PID). The lock is a separate file because the data file
+// itself is replaced by rename on every save. Exclusive creation behaves the
+// same on every OS, unlike flock; the PID inside lets a waiter see that the
+// owner crashed and take the lock over instead of waiting for a human.
+func (r *JSONFileTaskRepo) withLock(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lockPath := r.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		owner, err := acquireLock(lockPath)
+		if err != nil {
+			return err
+		}
+		if owner == 0 {
+			break
+		}
+		if !processAlive(owner) && breakLock(lockPath, owner) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock %s: still held by process %d after %s", r.path, owner, lockTimeout)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+	defer os.Remove(lockPath)
+	return fn()
+}
+
+// acquireLock links a file that already holds our PID to lockPath; the link
+// fails if lockPath exists, so a lock is never seen without its owner. It
+// returns 0 once the lock is ours, otherwise the PID of the current owner.
+func acquireLock(lockPath string) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprint(tmp, os.Getpid())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
+		return 0, err
+	}
+	for {
+		err := os.Link(tmp.Name(), lockPath)
+		if err == nil {
+			return 0, nil
+		}
+		if !os.IsExist(err) {
+			return 0, err
+		}
+		owner, err := lockOwner(lockPath)
 		if os.IsNotExist(err) {
-			return []Task{}, nil
+			continue // released between the two calls
 		}
-		return nil, err
+		return owner, err
 	}
-	var items []Task
-	if len(data) == 0 {
-		return []Task{}, nil
+}
+
+func lockOwner(lockPath string) (int, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0, err
 	}
-	if err := json.Unmarshal(data, &items); err != nil {
-		return nil, fmt.Errorf("invalid json file format: %w", err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("lock %s: no owner PID inside", lockPath)
 	}
-	return items, nil
+	return pid, nil
 }
 
-func (r *JSONFileTaskRepo) save(items []Task) error {
-	data, err := json.MarshalIndent(items, "", "  ")
+// processAlive asks the OS about pid. Signal 0 checks without delivering
+// anything on Unix; Windows only supports Kill, but FindProcess there
+// already fails once the process is gone.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
 	if err != nil {
-		return err
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		return true
 	}
-	return os.WriteFile(r.path, data, 0o644)
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
 }
 
-func (r *JSONFileTaskRepo) List() ([]Task, error) {
-	return r.load()
+// breakLock moves a dead owner's lock aside. Two waiters can both see the
+// same dead owner; if the file this one moved turns out to hold another
+// PID, the lock was taken in between and is linked back.
+func breakLock(lockPath string, dead int) bool {
+	aside, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".stale-*")
+	if err != nil {
+		return false
+	}
+	aside.Close()
+	defer os.Remove(aside.Name())
+	if os.Rename(lockPath, aside.Name()) != nil {
+		return false
+	}
+	if owner, err := lockOwner(aside.Name()); err == nil && owner != dead {
+		_ = os.Link(aside.Name(), lockPath)
+	}
+	return true
 }
 
-func (r *JSONFileTaskRepo) Add(title string) (Task, error) {
-	items, err := r.load()
+// taskFile is the CLI's (lesson 132) wrapper around the same array. next_id
+// remembers IDs of deleted tasks, so it must survive every save here too.
+type taskFile struct {
+	NextID int64  `json:"next_id"`
+	Tasks  []Task `json:"tasks"`
+}
+
+// decodeTasks reads a bare array or a taskFile. nextID is 0 for a bare
+// array, which save then writes back unchanged.
+func decodeTasks(data []byte) (items []Task, nextID int64, err error) {
+	items = []Task{}
+	if len(data) == 0 {
+		return items, 0, nil
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapped taskFile
+		if json.Unmarshal(data, &wrapped) != nil || wrapped.Tasks == nil {
+			return nil, 0, fmt.Errorf("invalid json file format: %w", err)
+		}
+		items = wrapped.Tasks
+		nextID = max(wrapped.NextID, 1)
+		for _, t := range items {
+			nextID = max(nextID, t.ID+1)
+		}
+	}
+	return items, nextID, nil
+}
+
+// load falls back to the backup generation when the data file exists but is
+// empty or unreadable (for example truncated by a crash or a bad copy).
+func (r *JSONFileTaskRepo) load() ([]Task, int64, error) {
+	data, err := os.ReadFile(r.path)
 	if err != nil {
-		return Task{}, err
+		if os.IsNotExist(err) {
+			return []Task{}, 0, nil
+		}
+		return nil, 0, err
 	}
-	nextID := 1
-	if len(items) > 0 {
-		nextID = items[len(items)-1].ID + 1
+	items, nextID, parseErr := decodeTasks(data)
+	if parseErr == nil && len(data) > 0 {
+		return items, nextID, nil
 	}
-	t := Task{ID: nextID, Title: title, Done: false}
-	items = append(items, t)
-	if err := r.save(items); err != nil {
-		return Task{}, err
+	if backup, err := os.ReadFile(r.path + ".bak"); err == nil && len(backup) > 0 {
+		if recovered, nextID, err := decodeTasks(backup); err == nil {
+			return recovered, nextID, nil
+		}
 	}
-	return t, nil
+	return items, nextID, parseErr
 }
 
-func (r *JSONFileTaskRepo) MarkDone(id int) error {
-	items, err := r.load()
+// save keeps the current valid file as path+".bak", then atomically replaces
+// the data file. A corrupt current file never overwrites a good backup.
+// A nextID of 0 writes the bare array this lesson has always used.
+func (r *JSONFileTaskRepo) save(items []Task, nextID int64) error {
+	var doc any = items
+	if nextID > 0 {
+		doc = taskFile{NextID: nextID, Tasks: items}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return err
 	}
-	found := false
-	for i := range items {
-		if items[i].ID == id {
-			items[i].Done = true
-			found = true
-			break
+	if current, err := os.ReadFile(r.path); err == nil && len(current) > 0 {
+		if _, _, err := decodeTasks(current); err == nil {
+			if err := writeFileAtomic(r.path+".bak", current); err != nil {
+				return err
+			}
 		}
 	}
-	if !found {
-		return fmt.Errorf("task id %d not found", id)
+	return writeFileAtomic(r.path, data)
+}
+
+// writeFileAtomic writes a temp file in the same directory, fsyncs it and
+// renames it over path, so readers see the old or the new file, never half.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
 	}
-	return r.save(items)
+	// The rename is only durable once the directory entry is flushed too.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// List takes no lock: save replaces the file by rename, so a reader sees
+// the previous file or the next one, never half of either.
+func (r *JSONFileTaskRepo) List() ([]Task, error) {
+	items, _, err := r.load()
+	return items, err
+}
+
+func (r *JSONFileTaskRepo) Add(title string) (Task, error) {
+	var t Task
+	err := r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		id := int64(1)
+		if len(items) > 0 {
+			id = items[len(items)-1].ID + 1
+		}
+		if nextID > 0 {
+			// Never reuse the ID of a task the CLI already deleted.
+			id = max(id, nextID)
+			nextID = id + 1
+		}
+		t = Task{ID: id, Title: title, Done: false}
+		return r.save(append(items, t), nextID)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+func (r *JSONFileTaskRepo) MarkDone(id int64) error {
+	return r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		found := false
+		for i := range items {
+			if items[i].ID == id {
+				items[i].Done = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+		}
+		return r.save(items, nextID)
+	})
 }
 
 // LESSON 7: One workflow, multiple adapters
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 /*
@@ -16,105 +28,517 @@ Suggested use:
 1) Run: go run lessons/code/82-go-persistence-http-11-20.go
 2) Call:
    - POST /tasks {"title":"learn go persistence"}
-   - POST /tasks/1/done
-   - GET  /tasks
+   - GET  /tasks/1                 (note the ETag header, e.g. "1")
+   - POST /tasks/1/done  with header If-Match: "1"  (repeat it: the stale tag gets 412)
+   - GET  /tasks?limit=2&done=false&sort=-title&q=go
+   - GET  /tasks?limit=2&cursor=<next_cursor from previous page>
 
 Extra context:
 - lessons/notes/167-go-persistence-first-principles.md
 - lessons/notes/168-go-repository-adapter-pattern.md
 */
 
+// Version starts at 1 and increases on every write; it doubles as the ETag.
 type Task struct {
-	ID    int    `json:"id"`
-	Title string `json:"title"`
-	Done  bool   `json:"done"`
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Done    bool   `json:"done"`
+	Version int    `json:"version"`
 }
 
+var (
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrVersionConflict = errors.New("task was modified by someone else")
+	ErrInvalidIfMatch  = errors.New("If-Match must be a quoted version")
+)
+
 type TaskRepository interface {
 	List() ([]Task, error)
+	Query(q TaskQuery) ([]Task, error)
+	Get(id int) (Task, error)
 	Add(title string) (Task, error)
-	MarkDone(id int) error
+	// MarkDone is compare-and-swap on the version (0 means "any version").
+	MarkDone(id int, expectedVersion int) (Task, error)
+}
+
+var ErrInvalidQuery = errors.New("invalid list query")
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var validSorts = map[string]bool{"id": true, "-id": true, "title": true, "-title": true}
+
+// TaskQuery is everything a repository needs to produce one page.
+// Sort is "id" or "title", with a leading "-" for descending order.
+type TaskQuery struct {
+	Limit  int
+	Done   *bool
+	Search string
+	Sort   string
+	After  *PagePosition
+}
+
+// PagePosition is the last row of the previous page (keyset pagination).
+type PagePosition struct {
+	Title string `json:"t"`
+	ID    int    `json:"i"`
+}
+
+type ListTasksRequest struct {
+	Limit  int
+	Cursor string
+	Done   *bool
+	Search string
+	Sort   string
+}
+
+type TaskPage struct {
+	Items      []Task `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursorPayload binds a position to the filters it was produced for.
+type cursorPayload struct {
+	Sort   string       `json:"s"`
+	Done   *bool        `json:"d,omitempty"`
+	Search string       `json:"q,omitempty"`
+	After  PagePosition `json:"a"`
+}
+
+// CursorCodec makes cursors opaque (base64) and tamper-proof (HMAC-SHA256).
+type CursorCodec struct {
+	secret []byte
+}
+
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{secret: secret}
+}
+
+func (c *CursorCodec) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+func (c *CursorCodec) Encode(p cursorPayload) (string, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(raw)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body)), nil
+}
+
+func (c *CursorCodec) Decode(cursor string) (cursorPayload, error) {
+	invalid := fmt.Errorf("%w: cursor is invalid", ErrInvalidQuery)
+	body, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return cursorPayload{}, invalid
+	}
+	given, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(given, c.sign(body)) {
+		return cursorPayload{}, invalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return cursorPayload{}, invalid
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return cursorPayload{}, invalid
+	}
+	return p, nil
+}
+
+func sameDoneFilter(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// randomSecret is the fallback when no secret is configured; cursors then
+// stop working after a restart, which is safe but inconvenient.
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+// compareTasks orders tasks by the sort key, using id as the tie-breaker.
+func compareTasks(sortKey string, a, b Task) int {
+	field, desc := strings.TrimPrefix(sortKey, "-"), strings.HasPrefix(sortKey, "-")
+	c := cmp.Compare(a.ID, b.ID)
+	if field == "title" && a.Title != b.Title {
+		c = cmp.Compare(a.Title, b.Title)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+// foldTitle is the one case fold search uses. The SQLite adapter in
+// 84-go-sqlite-http-11-20.go calls this same function as fold(), because
+// SQLite's own lower() only folds ASCII.
+func foldTitle(s string) string {
+	return strings.ToLower(s)
+}
+
+// applyTaskQuery gives in-process adapters the same semantics as the SQL one.
+func applyTaskQuery(items []Task, q TaskQuery) []Task {
+	search := foldTitle(q.Search)
+	out := make([]Task, 0)
+	for _, t := range items {
+		if q.Done != nil && t.Done != *q.Done {
+			continue
+		}
+		if search != "" && !strings.Contains(foldTitle(t.Title), search) {
+			continue
+		}
+		if q.After != nil && compareTasks(q.Sort, t, Task{ID: q.After.ID, Title: q.After.Title}) <= 0 {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return compareTasks(q.Sort, out[i], out[j]) < 0
+	})
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out
 }
 
 type JSONFileTaskRepo struct {
 	path string
+	mu   sync.Mutex
 }
 
 func NewJSONFileTaskRepo(path string) *JSONFileTaskRepo {
 	return &JSONFileTaskRepo{path: path}
 }
 
-func (r *JSONFileTaskRepo) load() ([]Task, error) {
-	data, err := os.ReadFile(r.path)
+const (
+	lockTimeout    = 5 * time.Second
+	lockRetryDelay = 5 * time.Millisecond
+)
+
+// withLock serializes goroutines (mutex) and processes (a sidecar lock file
+// holding the owner's PID). The lock is a separate file because the data file
+// itself is replaced by rename on every save. Exclusive creation behaves the
+// same on every OS, unlike flock; the PID inside lets a waiter see that the
+// owner crashed and take the lock over instead of waiting for a human.
+func (r *JSONFileTaskRepo) withLock(fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lockPath := r.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		owner, err := acquireLock(lockPath)
+		if err != nil {
+			return err
+		}
+		if owner == 0 {
+			break
+		}
+		if !processAlive(owner) && breakLock(lockPath, owner) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock %s: still held by process %d after %s", r.path, owner, lockTimeout)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+	defer os.Remove(lockPath)
+	return fn()
+}
+
+// acquireLock links a file that already holds our PID to lockPath; the link
+// fails if lockPath exists, so a lock is never seen without its owner. It
+// returns 0 once the lock is ours, otherwise the PID of the current owner.
+func acquireLock(lockPath string) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".tmp-*")
 	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprint(tmp, os.Getpid())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	for {
+		err := os.Link(tmp.Name(), lockPath)
+		if err == nil {
+			return 0, nil
+		}
+		if !os.IsExist(err) {
+			return 0, err
+		}
+		owner, err := lockOwner(lockPath)
 		if os.IsNotExist(err) {
-			return []Task{}, nil
+			continue // released between the two calls
 		}
-		return nil, err
+		return owner, err
+	}
+}
+
+func lockOwner(lockPath string) (int, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("lock %s: no owner PID inside", lockPath)
+	}
+	return pid, nil
+}
+
+// processAlive asks the OS about pid. Signal 0 checks without delivering
+// anything on Unix; Windows only supports Kill, but FindProcess there
+// already fails once the process is gone.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		return true
 	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// breakLock moves a dead owner's lock aside. Two waiters can both see the
+// same dead owner; if the file this one moved turns out to hold another
+// PID, the lock was taken in between and is linked back.
+func breakLock(lockPath string, dead int) bool {
+	aside, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".stale-*")
+	if err != nil {
+		return false
+	}
+	aside.Close()
+	defer os.Remove(aside.Name())
+	if os.Rename(lockPath, aside.Name()) != nil {
+		return false
+	}
+	if owner, err := lockOwner(aside.Name()); err == nil && owner != dead {
+		_ = os.Link(aside.Name(), lockPath)
+	}
+	return true
+}
+
+// taskFile is the CLI's (lesson 132) wrapper around the same array. next_id
+// remembers IDs of deleted tasks, so it must survive every save here too.
+type taskFile struct {
+	NextID int    `json:"next_id"`
+	Tasks  []Task `json:"tasks"`
+}
+
+// decodeTasks reads a bare array or a taskFile. nextID is 0 for a bare
+// array, which save then writes back unchanged.
+func decodeTasks(data []byte) (items []Task, nextID int, err error) {
+	items = []Task{}
 	if len(data) == 0 {
-		return []Task{}, nil
+		return items, 0, nil
 	}
-	var items []Task
 	if err := json.Unmarshal(data, &items); err != nil {
-		return nil, err
+		var wrapped taskFile
+		if json.Unmarshal(data, &wrapped) != nil || wrapped.Tasks == nil {
+			return nil, 0, fmt.Errorf("invalid json file format: %w", err)
+		}
+		items = wrapped.Tasks
+		nextID = max(wrapped.NextID, 1)
+		for _, t := range items {
+			nextID = max(nextID, t.ID+1)
+		}
 	}
-	return items, nil
+	// Files written before versions existed start every task at version 1.
+	for i := range items {
+		if items[i].Version == 0 {
+			items[i].Version = 1
+		}
+	}
+	return items, nextID, nil
 }
 
-func (r *JSONFileTaskRepo) save(items []Task) error {
-	data, err := json.MarshalIndent(items, "", "  ")
+// load falls back to the backup generation when the data file exists but is
+// empty or unreadable (for example truncated by a crash or a bad copy).
+func (r *JSONFileTaskRepo) load() ([]Task, int, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Task{}, 0, nil
+		}
+		return nil, 0, err
+	}
+	items, nextID, parseErr := decodeTasks(data)
+	if parseErr == nil && len(data) > 0 {
+		return items, nextID, nil
+	}
+	if backup, err := os.ReadFile(r.path + ".bak"); err == nil && len(backup) > 0 {
+		if recovered, nextID, err := decodeTasks(backup); err == nil {
+			return recovered, nextID, nil
+		}
+	}
+	return items, nextID, parseErr
+}
+
+// save keeps the current valid file as path+".bak", then atomically replaces
+// the data file. A corrupt current file never overwrites a good backup.
+// A nextID of 0 writes the bare array this lesson has always used.
+func (r *JSONFileTaskRepo) save(items []Task, nextID int) error {
+	var doc any = items
+	if nextID > 0 {
+		doc = taskFile{NextID: nextID, Tasks: items}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(r.path, data, 0o644)
+	if current, err := os.ReadFile(r.path); err == nil && len(current) > 0 {
+		if _, _, err := decodeTasks(current); err == nil {
+			if err := writeFileAtomic(r.path+".bak", current); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(r.path, data)
 }
 
+// writeFileAtomic writes a temp file in the same directory, fsyncs it and
+// renames it over path, so readers see the old or the new file, never half.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the rename succeeded
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// The rename is only durable once the directory entry is flushed too.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// List takes no lock: save replaces the file by rename, so a reader sees
+// the previous file or the next one, never half of either.
 func (r *JSONFileTaskRepo) List() ([]Task, error) {
-	return r.load()
+	items, _, err := r.load()
+	return items, err
 }
 
-func (r *JSONFileTaskRepo) Add(title string) (Task, error) {
-	items, err := r.load()
+func (r *JSONFileTaskRepo) Query(q TaskQuery) ([]Task, error) {
+	items, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	return applyTaskQuery(items, q), nil
+}
+
+func (r *JSONFileTaskRepo) Get(id int) (Task, error) {
+	items, err := r.List()
 	if err != nil {
 		return Task{}, err
 	}
-	nextID := 1
-	if len(items) > 0 {
-		nextID = items[len(items)-1].ID + 1
+	for _, t := range items {
+		if t.ID == id {
+			return t, nil
+		}
 	}
-	t := Task{ID: nextID, Title: title, Done: false}
-	items = append(items, t)
-	if err := r.save(items); err != nil {
+	return Task{}, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+}
+
+func (r *JSONFileTaskRepo) Add(title string) (Task, error) {
+	var t Task
+	err := r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		id := 1
+		if len(items) > 0 {
+			id = items[len(items)-1].ID + 1
+		}
+		if nextID > 0 {
+			// Never reuse the ID of a task the CLI already deleted.
+			id = max(id, nextID)
+			nextID = id + 1
+		}
+		t = Task{ID: id, Title: title, Done: false, Version: 1}
+		return r.save(append(items, t), nextID)
+	})
+	if err != nil {
 		return Task{}, err
 	}
 	return t, nil
 }
 
-func (r *JSONFileTaskRepo) MarkDone(id int) error {
-	items, err := r.load()
-	if err != nil {
-		return err
-	}
-	found := false
-	for i := range items {
-		if items[i].ID == id {
+// MarkDone checks the version and writes under the same lock, so the check
+// cannot go stale between compare and swap.
+func (r *JSONFileTaskRepo) MarkDone(id int, expectedVersion int) (Task, error) {
+	var updated Task
+	err := r.withLock(func() error {
+		items, nextID, err := r.load()
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			if items[i].ID != id {
+				continue
+			}
+			if expectedVersion != 0 && items[i].Version != expectedVersion {
+				return fmt.Errorf("task id %d: %w", id, ErrVersionConflict)
+			}
 			items[i].Done = true
-			found = true
+			items[i].Version++
+			updated = items[i]
+			return r.save(items, nextID)
 		}
+		return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	})
+	if err != nil {
+		return Task{}, err
 	}
-	if !found {
-		return fmt.Errorf("task id %d not found", id)
-	}
-	return r.save(items)
+	return updated, nil
 }
 
 type TaskService struct {
-	repo TaskRepository
+	repo    TaskRepository
+	cursors *CursorCodec
 }
 
-func NewTaskService(repo TaskRepository) *TaskService {
-	return &TaskService{repo: repo}
+func NewTaskService(repo TaskRepository, cursorSecret []byte) *TaskService {
+	return &TaskService{repo: repo, cursors: NewCursorCodec(cursorSecret)}
 }
 
 func (s *TaskService) CreateTask(title string) (Task, error) {
@@ -125,17 +549,77 @@ func (s *TaskService) CreateTask(title string) (Task, error) {
 	return s.repo.Add(clean)
 }
 
-func (s *TaskService) CompleteTask(id int) error {
+// CompleteTask marks a task done. ifMatch is the version the caller last
+// saw; 0 skips the check.
+func (s *TaskService) CompleteTask(id int, ifMatch int) (Task, error) {
 	if id <= 0 {
-		return errors.New("id must be positive")
+		return Task{}, errors.New("id must be positive")
 	}
-	return s.repo.MarkDone(id)
+	return s.repo.MarkDone(id, ifMatch)
+}
+
+func (s *TaskService) GetTask(id int) (Task, error) {
+	if id <= 0 {
+		return Task{}, errors.New("id must be positive")
+	}
+	return s.repo.Get(id)
 }
 
 func (s *TaskService) Tasks() ([]Task, error) {
 	return s.repo.List()
 }
 
+// ListTasks validates the request, resolves the cursor and asks the repo for
+// one extra row so it knows whether another page exists.
+func (s *TaskService) ListTasks(req ListTasksRequest) (TaskPage, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxPageSize {
+		return TaskPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxPageSize)
+	}
+	sortKey := req.Sort
+	if sortKey == "" {
+		sortKey = "id"
+	}
+	if !validSorts[sortKey] {
+		return TaskPage{}, fmt.Errorf("%w: sort must be one of id, -id, title, -title", ErrInvalidQuery)
+	}
+	q := TaskQuery{Limit: limit + 1, Done: req.Done, Search: strings.TrimSpace(req.Search), Sort: sortKey}
+	if req.Cursor != "" {
+		payload, err := s.cursors.Decode(req.Cursor)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		if payload.Sort != q.Sort || payload.Search != q.Search || !sameDoneFilter(payload.Done, q.Done) {
+			return TaskPage{}, fmt.Errorf("%w: cursor does not match query", ErrInvalidQuery)
+		}
+		q.After = &payload.After
+	}
+
+	items, err := s.repo.Query(q)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	page := TaskPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		next, err := s.cursors.Encode(cursorPayload{
+			Sort:   q.Sort,
+			Done:   q.Done,
+			Search: q.Search,
+			After:  PagePosition{Title: last.Title, ID: last.ID},
+		})
+		if err != nil {
+			return TaskPage{}, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
 type createTaskRequest struct {
 	Title string `json:"title"`
 }
@@ -146,6 +630,66 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+// parseListRequest maps ?limit=&cursor=&done=&sort=&q= onto the service request.
+func parseListRequest(r *http.Request) (ListTasksRequest, error) {
+	values := r.URL.Query()
+	req := ListTasksRequest{
+		Cursor: values.Get("cursor"),
+		Search: values.Get("q"),
+		Sort:   values.Get("sort"),
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return ListTasksRequest{}, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidQuery)
+		}
+		req.Limit = limit
+	}
+	if raw := values.Get("done"); raw != "" {
+		done, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ListTasksRequest{}, fmt.Errorf("%w: done must be true or false", ErrInvalidQuery)
+		}
+		req.Done = &done
+	}
+	return req, nil
+}
+
+// writeTaskError maps repository errors onto status codes; anything that is
+// not a known storage outcome is treated as bad input, as before.
+func writeTaskError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrVersionConflict):
+		status = http.StatusPreconditionFailed
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func etag(task Task) string {
+	return strconv.Quote(strconv.Itoa(task.Version))
+}
+
+// ifMatchVersion reads If-Match. A missing header or "*" means no
+// precondition (0); anything that is not a quoted version is a bad request.
+func ifMatchVersion(r *http.Request) (int, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, nil
+	}
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil {
+		return 0, ErrInvalidIfMatch
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalidIfMatch
+	}
+	return version, nil
+}
+
 func buildMux(service *TaskService) *http.ServeMux {
 	mux := http.NewServeMux()
 
@@ -153,12 +697,21 @@ func buildMux(service *TaskService) *http.ServeMux {
 	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			items, err := service.Tasks()
+			req, err := parseListRequest(r)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			page, err := service.ListTasks(req)
+			if errors.Is(err, ErrInvalidQuery) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
 			if err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list failure"})
 				return
 			}
-			writeJSON(w, http.StatusOK, items)
+			writeJSON(w, http.StatusOK, page)
 		case http.MethodPost:
 			var req createTaskRequest
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -170,33 +723,58 @@ func buildMux(service *TaskService) *http.ServeMux {
 				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 				return
 			}
+			w.Header().Set("ETag", etag(task))
 			writeJSON(w, http.StatusCreated, task)
 		default:
 			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		}
 	})
 
-	// LESSON 15-19: completion endpoint with path parsing
+	// LESSON 15-19: read + completion endpoints with path parsing
+	// GET /tasks/{id} hands out an ETag; POST /tasks/{id}/done honors If-Match.
 	mux.HandleFunc("/tasks/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
-			return
-		}
 		path := strings.TrimPrefix(r.URL.Path, "/tasks/")
-		if !strings.HasSuffix(path, "/done") {
+		idPart, isDone := strings.CutSuffix(path, "/done")
+		if strings.Contains(idPart, "/") {
 			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 			return
 		}
-		idPart := strings.TrimSuffix(path, "/done")
-		var id int
-		if _, err := fmt.Sscanf(idPart, "%d", &id); err != nil {
+		wantMethod := http.MethodGet
+		if isDone {
+			wantMethod = http.MethodPost
+		}
+		if r.Method != wantMethod {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		id, err := strconv.Atoi(idPart)
+		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
 			return
 		}
-		if err := service.CompleteTask(id); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+
+		if !isDone {
+			task, err := service.GetTask(id)
+			if err != nil {
+				writeTaskError(w, err)
+				return
+			}
+			w.Header().Set("ETag", etag(task))
+			writeJSON(w, http.StatusOK, task)
 			return
 		}
+
+		ifMatch, err := ifMatchVersion(r)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		task, err := service.CompleteTask(id, ifMatch)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		w.Header().Set("ETag", etag(task))
 		writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
 	})
 
@@ -210,7 +788,11 @@ func buildMux(service *TaskService) *http.ServeMux {
 
 func main() {
 	repo := NewJSONFileTaskRepo("lessons/code/tmp_tasks_api.json")
-	service := NewTaskService(repo)
+	secret := []byte(os.Getenv("TASKS_CURSOR_SECRET"))
+	if len(secret) == 0 {
+		secret = randomSecret()
+	}
+	service := NewTaskService(repo, secret)
 	mux := buildMux(service)
 
 	addr := ":8084"
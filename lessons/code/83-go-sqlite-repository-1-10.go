@@ -1,10 +1,17 @@
 package main
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -15,11 +22,15 @@ GO SQLITE REPOSITORY (Lessons 1-10)
 Suggested use:
 1) Install driver (if needed): go get modernc.org/sqlite
 2) Run: go run lessons/code/83-go-sqlite-repository-1-10.go
+3) Inspect schema history:
+   - go run lessons/code/83-go-sqlite-repository-1-10.go migrate status
+   - go run lessons/code/83-go-sqlite-repository-1-10.go migrate down -dry-run
 
 Extra context:
 - lessons/notes/170-what-is-sqlite-in-go.md
 - lessons/notes/171-go-database-sql-first-principles.md
 - lessons/notes/172-go-sqlite-gotchas.md
+- lessons/notes/200-go-schema-migrations-first-principles.md
 */
 
 // LESSON 1: Domain model
@@ -30,6 +41,8 @@ type Task struct {
 	Done  bool
 }
 
+var ErrTaskNotFound = errors.New("task not found")
+
 // LESSON 2: Repository boundary
 // Why this matters: service stays independent from SQL details.
 type TaskRepository interface {
@@ -48,16 +61,269 @@ func NewSQLiteTaskRepo(db *sql.DB) *SQLiteTaskRepo {
 	return &SQLiteTaskRepo{db: db}
 }
 
+// OpenSQLite opens a database file for the adapter. SQLite allows one
+// writer at a time; without a busy timeout a second connection that wants
+// to write fails at once with "database is locked" instead of waiting.
+func OpenSQLite(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+}
+
 // LESSON 4: Migration step
-// Why this matters: queries fail if schema does not exist.
-func (r *SQLiteTaskRepo) Migrate() error {
-	query := `
+// Why this matters: queries fail if schema does not exist, and schemas change
+// over time, so each change is a numbered, reversible, recorded step.
+var taskMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tasks",
+		Up: `
 CREATE TABLE IF NOT EXISTS tasks (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   title TEXT NOT NULL,
   done INTEGER NOT NULL DEFAULT 0
-);`
-	_, err := r.db.Exec(query)
+);`,
+		Down: `DROP TABLE tasks;`,
+	},
+}
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was edited")
+	ErrUnknownMigration = errors.New("database has a migration this binary does not know")
+)
+
+// Migration is one numbered schema step. Never edit a migration after it
+// has shipped; add a new one instead (the checksum check enforces this).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + "\n--down--\n" + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+type MigrationStatus struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// Migrator applies migrations in version order and records each one in
+// schema_migrations inside the same transaction as the schema change.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	DryRun     bool
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  applied_at TEXT NOT NULL
+);`)
+	return err
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt string
+}
+
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		out[version] = a
+	}
+	return out, rows.Err()
+}
+
+// Verify fails when history in the database disagrees with the code.
+func (m *Migrator) Verify() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	return m.verify(applied)
+}
+
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := map[int]bool{}
+	for i, mig := range m.migrations {
+		if mig.Version <= 0 || (i > 0 && mig.Version == m.migrations[i-1].Version) {
+			return fmt.Errorf("migration %d (%s): versions must be positive and unique", mig.Version, mig.Name)
+		}
+		known[mig.Version] = true
+		if a, ok := applied[mig.Version]; ok && a.checksum != mig.Checksum() {
+			return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, ErrChecksumMismatch)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("migration %d: %w", version, ErrUnknownMigration)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		a, ok := applied[mig.Version]
+		out = append(out, MigrationStatus{Migration: mig, Applied: ok, AppliedAt: a.appliedAt})
+	}
+	return out, nil
+}
+
+// Up applies every pending migration and returns what ran (or would run
+// when DryRun is set).
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Up, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+				mig.Version, mig.Name, mig.Checksum(), time.Now().UTC().Format(time.RFC3339))
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) up: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the newest `steps` applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Down, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) down: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// inTx runs a schema step and its bookkeeping row atomically.
+func (m *Migrator) inTx(step string, record string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(step); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runMigrateCommand implements `migrate status|up|down [-dry-run] [-steps N]`.
+func runMigrateCommand(migrator *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate status|up|down [-dry-run] [-steps N]")
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "print the plan without changing the database")
+	steps := fs.Int("steps", 1, "how many migrations to revert (down only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	migrator.DryRun = *dryRun
+	prefix := ""
+	if *dryRun {
+		prefix = "(dry run) "
+	}
+
+	switch args[0] {
+	case "status":
+		items, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range items {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt
+			}
+			fmt.Fprintf(out, "%04d %-24s %s\n", st.Migration.Version, st.Migration.Name, state)
+		}
+		return migrator.Verify()
+	case "up":
+		done, err := migrator.Up()
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sup   %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	case "down":
+		done, err := migrator.Down(*steps)
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sdown %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func (r *SQLiteTaskRepo) Migrate() error {
+	_, err := NewMigrator(r.db, taskMigrations).Up()
 	return err
 }
 
@@ -112,7 +378,7 @@ func (r *SQLiteTaskRepo) MarkDone(id int64) error {
 		return err
 	}
 	if affected == 0 {
-		return fmt.Errorf("task id %d not found", id)
+		return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
 	}
 	return nil
 }
@@ -152,13 +418,21 @@ func (s *TaskService) Tasks() ([]Task, error) {
 // LESSON 10: End-to-end demo
 // Why this matters: shows SQL adapter behind stable service interface.
 func main() {
-	db, err := sql.Open("sqlite", "lessons/code/tmp_tasks.db")
+	db, err := OpenSQLite("lessons/code/tmp_tasks.db")
 	if err != nil {
 		fmt.Println("open db error:", err)
 		return
 	}
 	defer db.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(NewMigrator(db, taskMigrations), os.Args[2:], os.Stdout); err != nil {
+			fmt.Println("migrate error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	repo := NewSQLiteTaskRepo(db)
 	if err := repo.Migrate(); err != nil {
 		fmt.Println("migrate error:", err)
@@ -1,14 +1,34 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // recurrence timezones must not depend on the host's zoneinfo
 
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 )
 
 /*
@@ -17,131 +37,3313 @@ GO SQLITE + HTTP (Lessons 11-20)
 Suggested use:
 1) Install driver (if needed): go get modernc.org/sqlite
 2) Run: go run lessons/code/84-go-sqlite-http-11-20.go
+   (schema only: go run lessons/code/84-go-sqlite-http-11-20.go migrate status|up|down)
 3) Call:
-   - POST /tasks {"title":"study sql"}
-   - POST /tasks/1/done
-   - GET  /tasks
+   - POST   /tasks {"title":"study sql","due_at":"2026-05-01T17:00:00Z",
+                    "priority":"high","tags":["school"],"assignee":"alice"}
+   - GET    /tasks?limit=2&done=false&sort=-title&q=sql
+   - GET    /tasks?tag=school&due_before=2026-06-01&assignee=alice&priority=high
+   - GET    /tasks?limit=2&cursor=<next_cursor from previous page>
+   - GET    /tasks/1                      (note the ETag header, e.g. "1")
+   - PATCH  /tasks/1 {"title":"study sql joins","done":true}
+            with header If-Match: "1"    (repeat it: the stale tag gets 412)
+   - POST   /tasks/1/done                 (send X-Actor: alice to name who did it)
+   - DELETE /tasks/1                      (soft delete: GET now answers 404)
+   - POST   /tasks/1/restore
+   - GET    /tasks/1/history
+   - POST   /tasks {"title":"write chapter","parent_id":1}   (a subtask of 1)
+   - POST   /tasks/1/blockers {"blocker_id":2}            (1 cannot be done before 2)
+   - GET    /tasks/1/graph                                (work order ending with 1)
+   - DELETE /tasks/1/blockers/2
+   - POST   /tasks {"title":"water plants","recurrence":{"every":1,"unit":"week","timezone":"Europe/Berlin"},
+                    "due_at":"2026-05-04T07:00:00Z"}
+            or {"title":"standup","recurrence":{"cron":"30 9 * * 1-5","timezone":"America/New_York"}}
+            completing an occurrence creates the next one; the scheduler (once a
+            minute) also creates it when its time arrives
+   - GET    /tasks/export?format=csv              (or format=ndjson, the default)
+   - POST   /tasks/import?format=csv&dry_run=true with the exported file as body
+            (drop dry_run to write: every record or none, errors listed per line)
 
 Extra context:
 - lessons/notes/171-go-database-sql-first-principles.md
 - lessons/notes/172-go-sqlite-gotchas.md
+- lessons/notes/200-go-schema-migrations-first-principles.md
 */
 
+// Version starts at 1 and increases on every write; it doubles as the ETag.
+// Overdue is derived from DueAt and the clock, so it is never stored.
 type Task struct {
-	ID    int64  `json:"id"`
-	Title string `json:"title"`
-	Done  bool   `json:"done"`
+	ID       int64      `json:"id"`
+	Title    string     `json:"title"`
+	Done     bool       `json:"done"`
+	Version  int64      `json:"version"`
+	DueAt    *time.Time `json:"due_at,omitempty"`
+	Priority Priority   `json:"priority"`
+	Tags     []string   `json:"tags"`
+	Assignee string     `json:"assignee,omitempty"`
+	ParentID *int64     `json:"parent_id,omitempty"`
+	// Only the newest occurrence of a series carries the rule; it is handed
+	// over to the next occurrence when that one is created.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	Overdue    bool        `json:"overdue"`
 }
 
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+var validPriorities = map[Priority]bool{PriorityLow: true, PriorityNormal: true, PriorityHigh: true, PriorityUrgent: true}
+
+const (
+	maxTags        = 10
+	maxTagLength   = 32
+	maxAssigneeLen = 64
+)
+
+func (t Task) IsOverdue(now time.Time) bool {
+	return !t.Done && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+// normalizeTask is the single validation path for creates and updates:
+// trimmed title, default priority, lowercase de-duplicated sorted tags and
+// UTC due dates, so every adapter stores the same canonical form.
+func normalizeTask(t *Task) error {
+	t.Title = strings.TrimSpace(t.Title)
+	if t.Title == "" {
+		return ErrTitleRequired
+	}
+	if t.Priority == "" {
+		t.Priority = PriorityNormal
+	}
+	if !validPriorities[t.Priority] {
+		return fmt.Errorf("%w: priority must be one of low, normal, high, urgent", ErrInvalidTask)
+	}
+	tags, err := normalizeTags(t.Tags)
+	if err != nil {
+		return err
+	}
+	t.Tags = tags
+	t.Assignee = strings.TrimSpace(t.Assignee)
+	if len(t.Assignee) > maxAssigneeLen {
+		return fmt.Errorf("%w: assignee must be at most %d characters", ErrInvalidTask, maxAssigneeLen)
+	}
+	if t.DueAt != nil {
+		if t.DueAt.IsZero() {
+			return fmt.Errorf("%w: due_at must be a real date", ErrInvalidTask)
+		}
+		// Stored as RFC 3339 text, so sub-second precision would not round-trip.
+		due := t.DueAt.UTC().Truncate(time.Second)
+		t.DueAt = &due
+	}
+	if t.ParentID != nil {
+		if *t.ParentID <= 0 {
+			return fmt.Errorf("%w: parent_id must be positive", ErrInvalidTask)
+		}
+		if *t.ParentID == t.ID {
+			return fmt.Errorf("task id %d cannot be its own parent: %w", t.ID, ErrDependencyCycle)
+		}
+	}
+	if t.Recurrence != nil {
+		if t.Recurrence.isZero() {
+			t.Recurrence = nil
+			return nil
+		}
+		if err := normalizeRecurrence(t.Recurrence); err != nil {
+			return err
+		}
+		if t.DueAt == nil {
+			return fmt.Errorf("%w: a recurring task needs a due date", ErrInvalidTask)
+		}
+	}
+	return nil
+}
+
+func normalizeTags(raw []string) ([]string, error) {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength || strings.ContainsAny(tag, ", \t") {
+			return nil, fmt.Errorf("%w: tags must be 1-%d characters without spaces or commas", ErrInvalidTask, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags", ErrInvalidTask, maxTags)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Recurrence is either a five-field cron expression or "every N days/weeks".
+// Both are evaluated on the wall clock of Timezone, so "every day at 09:00
+// Europe/Berlin" stays at 09:00 across daylight saving changes.
+type Recurrence struct {
+	Cron     string `json:"cron,omitempty"`
+	Every    int    `json:"every,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+const maxRecurrenceEvery = 365
+
+func (r Recurrence) isZero() bool {
+	return r.Cron == "" && r.Every == 0
+}
+
+func normalizeRecurrence(r *Recurrence) error {
+	r.Cron = strings.Join(strings.Fields(r.Cron), " ")
+	r.Unit = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(r.Unit), "s"))
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(r.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidTask, r.Timezone)
+	}
+	switch {
+	case r.Cron != "" && r.Every != 0:
+		return fmt.Errorf("%w: recurrence takes either cron or every, not both", ErrInvalidTask)
+	case r.Cron != "":
+		spec, err := parseCron(r.Cron)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+		}
+		// Catches rules like "0 0 30 2 *" that parse but never fire; five
+		// years from 2000 include a February 29th.
+		if _, err := spec.next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+		}
+	case r.Every < 1 || r.Every > maxRecurrenceEvery:
+		return fmt.Errorf("%w: every must be between 1 and %d", ErrInvalidTask, maxRecurrenceEvery)
+	case r.Unit != "day" && r.Unit != "week":
+		return fmt.Errorf("%w: unit must be day or week", ErrInvalidTask)
+	}
+	return nil
+}
+
+// Next returns the first occurrence strictly after `after`.
+func (r Recurrence) Next(after time.Time) (time.Time, error) {
+	return r.NextAfter(after, after)
+}
+
+// NextAfter returns the first occurrence strictly after t of the series
+// that has an occurrence at anchor. Interval rules count whole steps from
+// anchor, so a series that missed a year is one calculation, not a loop.
+func (r Recurrence) NextAfter(anchor, t time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if r.Cron != "" {
+		spec, err := parseCron(r.Cron)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return spec.next(t.In(loc))
+	}
+	days := r.Every
+	if r.Unit == "week" {
+		days *= 7
+	}
+	// AddDate works on the wall clock, which is what keeps 09:00 at 09:00.
+	// Days around DST are 23 or 25 hours long, so the estimate from elapsed
+	// hours can be off by one step either way.
+	start := anchor.In(loc)
+	steps := 0
+	if t.After(anchor) {
+		steps = int(t.Sub(anchor).Hours()/24) / days
+	}
+	due := start.AddDate(0, 0, steps*days)
+	for steps > 0 && due.After(t) {
+		steps--
+		due = start.AddDate(0, 0, steps*days)
+	}
+	for !due.After(t) {
+		steps++
+		due = start.AddDate(0, 0, steps*days)
+	}
+	return due.UTC(), nil
+}
+
+// cronSpec holds the allowed values of each field of "min hour dom month dow".
+type cronSpec struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool
+}
+
+func parseCron(expr string) (cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSpec{}, fmt.Errorf("cron %q: want 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := [5]map[int]bool{}
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return cronSpec{}, fmt.Errorf("cron %q field %d: %w", expr, i+1, err)
+		}
+		sets[i] = set
+	}
+	if sets[4][7] {
+		sets[4][0] = true // both 0 and 7 mean Sunday
+	}
+	return cronSpec{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField accepts *, N, A-B and comma lists of those, each with an
+// optional /step.
+func parseCronField(field string, lo, hi int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("bad step %q", part)
+			}
+			step = n
+		}
+		from, to := lo, hi
+		if rangePart != "*" {
+			a, b, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return nil, fmt.Errorf("bad value %q", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return nil, fmt.Errorf("bad range %q", part)
+				}
+			} else if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// dayMatches follows classic cron: when both day fields are restricted, a
+// day matching either one is enough.
+func (c cronSpec) dayMatches(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// next walks forward on the wall clock of t's location, skipping a whole
+// month, day or hour as soon as that field cannot match. Wall-clock times
+// that do not exist (a skipped DST hour) are normalized forward by time.Date.
+func (c cronSpec) next(t time.Time) (time.Time, error) {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		var candidate time.Time
+		switch {
+		case !c.month[int(t.Month())]:
+			candidate = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			candidate = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !c.hour[t.Hour()]:
+			candidate = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !c.minute[t.Minute()]:
+			candidate = t.Add(time.Minute)
+		default:
+			return t.UTC(), nil
+		}
+		if !candidate.After(t) {
+			candidate = t.Add(time.Minute) // ambiguous DST hour: never step backwards
+		}
+		t = candidate
+	}
+	return time.Time{}, errors.New("cron expression never matches within five years")
+}
+
+var (
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrTitleRequired   = errors.New("title is required")
+	ErrInvalidID       = errors.New("id must be positive")
+	ErrVersionConflict = errors.New("task was modified by someone else")
+	ErrTaskNotDeleted  = errors.New("task is not deleted")
+	ErrInvalidTask     = errors.New("invalid task")
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	ErrTaskBlocked     = errors.New("task has open blockers")
+	ErrInvalidImport   = errors.New("invalid import")
+	ErrInvalidIfMatch  = errors.New("If-Match must be a quoted version")
+)
+
+// HistoryEntry is one field change. Deleting and restoring show up as
+// changes to deleted_at, so the trail never has gaps.
+type HistoryEntry struct {
+	TaskID    int64     `json:"task_id"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// taskChanges lists the fields that differ; a create is a change from the
+// zero Task.
+func taskChanges(before, after Task) []HistoryEntry {
+	changes := []HistoryEntry{}
+	created := before.ID == 0
+	if before.Title != after.Title {
+		changes = append(changes, HistoryEntry{Field: "title", OldValue: before.Title, NewValue: after.Title})
+	}
+	if created || before.Done != after.Done {
+		old := strconv.FormatBool(before.Done)
+		if created {
+			old = ""
+		}
+		changes = append(changes, HistoryEntry{Field: "done", OldValue: old, NewValue: strconv.FormatBool(after.Done)})
+	}
+	if formatDue(before.DueAt) != formatDue(after.DueAt) {
+		changes = append(changes, HistoryEntry{Field: "due_at", OldValue: formatDue(before.DueAt), NewValue: formatDue(after.DueAt)})
+	}
+	if before.Priority != after.Priority {
+		changes = append(changes, HistoryEntry{Field: "priority", OldValue: string(before.Priority), NewValue: string(after.Priority)})
+	}
+	if oldTags, newTags := strings.Join(before.Tags, ","), strings.Join(after.Tags, ","); oldTags != newTags {
+		changes = append(changes, HistoryEntry{Field: "tags", OldValue: oldTags, NewValue: newTags})
+	}
+	if before.Assignee != after.Assignee {
+		changes = append(changes, HistoryEntry{Field: "assignee", OldValue: before.Assignee, NewValue: after.Assignee})
+	}
+	if formatID(before.ParentID) != formatID(after.ParentID) {
+		changes = append(changes, HistoryEntry{Field: "parent_id", OldValue: formatID(before.ParentID), NewValue: formatID(after.ParentID)})
+	}
+	if oldRule, newRule := formatRecurrence(before.Recurrence), formatRecurrence(after.Recurrence); oldRule != newRule {
+		changes = append(changes, HistoryEntry{Field: "recurrence", OldValue: oldRule, NewValue: newRule})
+	}
+	return changes
+}
+
+func formatRecurrence(rule *Recurrence) string {
+	if value, ok := recurrenceValue(rule).(string); ok {
+		return value
+	}
+	return ""
+}
+
+func formatID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatInt(*id, 10)
+}
+
+// formatIDs is the CSV form of a list of ids: space separated, like tags.
+func formatIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, " ")
+}
+
+func sameParent(a, b *int64) bool {
+	return formatID(a) == formatID(b)
+}
+
+type EdgeKind string
+
+const (
+	EdgeBlocks  EdgeKind = "blocks"
+	EdgeSubtask EdgeKind = "subtask"
+)
+
+// TaskEdge means From has to be finished before To: a blocker before the
+// task it blocks, a subtask before its parent. Cycle checks and the graph
+// ordering both treat the two kinds as one graph.
+type TaskEdge struct {
+	From int64    `json:"from"`
+	To   int64    `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// TaskGraph lists a task and everything that has to be finished before it,
+// in an order that can actually be worked through.
+type TaskGraph struct {
+	Order []Task     `json:"order"`
+	Edges []TaskEdge `json:"edges"`
+}
+
+// formatDue is the storage and history form of a due date ("" for none).
+func formatDue(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	return due.UTC().Format(time.RFC3339)
+}
+
+// Reads never return soft-deleted tasks; every write takes the actor so it
+// can be recorded in the history.
 type TaskRepository interface {
 	List() ([]Task, error)
-	Add(title string) (Task, error)
-	MarkDone(id int64) error
+	Query(q TaskQuery) ([]Task, error)
+	Get(id int64) (Task, error)
+	// Add assigns ID and Version; everything else is taken as given.
+	Add(task Task, actor string) (Task, error)
+	// Update and Delete are compare-and-swap: they only apply when the stored
+	// version equals the expected one (0 means "any version").
+	Update(task Task, expectedVersion int64, actor string) (Task, error)
+	MarkDone(id int64, actor string) error
+	Delete(id int64, expectedVersion int64, actor string) error
+	Restore(id int64, actor string) (Task, error)
+	History(id int64) ([]HistoryEntry, error)
+	// AddBlocker and Update (when it moves a task under a new parent) return
+	// ErrDependencyCycle instead of writing an edge that closes a loop.
+	AddBlocker(taskID, blockerID int64, actor string) error
+	RemoveBlocker(taskID, blockerID int64, actor string) error
+	// Edges returns every edge between two live tasks.
+	Edges() ([]TaskEdge, error)
+	// SeriesHeads returns the live tasks with a rule and a due date that are
+	// done or were due by dueBy: the only ones a scheduler pass can spawn from.
+	SeriesHeads(dueBy time.Time) ([]Task, error)
+	// SpawnOccurrence clears the rule on head (compare-and-swap) and adds
+	// next, atomically. It returns the updated head and the new task.
+	SpawnOccurrence(headID, expectedVersion int64, next Task, actor string) (Task, Task, error)
+	// Import adds every item or none of them and returns the new tasks in
+	// item order.
+	Import(items []ImportItem, actor string) ([]Task, error)
+}
+
+// ImportItem is one task of an import batch. ParentItem, when set, is the
+// index of an earlier item whose new ID becomes the parent; otherwise
+// Task.ParentID (if any) names a task that already exists. Blockers work
+// the same way: BlockerItems index items of the batch, Blockers name
+// existing tasks.
+type ImportItem struct {
+	Task         Task
+	ParentItem   *int
+	Blockers     []int64
+	BlockerItems []int
+}
+
+// importBlockers resolves the blockers of item to ids, once created holds
+// the new task of every item.
+func importBlockers(item ImportItem, created []Task) []int64 {
+	blockers := slices.Clone(item.Blockers)
+	for _, b := range item.BlockerItems {
+		blockers = append(blockers, created[b].ID)
+	}
+	return blockers
+}
+
+var ErrInvalidQuery = errors.New("invalid list query")
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var validSorts = map[string]bool{"id": true, "-id": true, "title": true, "-title": true}
+
+// TaskQuery is everything a repository needs to produce one page.
+// Sort is "id" or "title", with a leading "-" for descending order.
+// DueBefore only matches tasks that have a due date.
+type TaskQuery struct {
+	Limit     int
+	Done      *bool
+	Search    string
+	Tag       string
+	Assignee  string
+	Priority  Priority
+	DueBefore *time.Time
+	Sort      string
+	After     *PagePosition
+}
+
+// PagePosition is the last row of the previous page (keyset pagination).
+type PagePosition struct {
+	Title string `json:"t"`
+	ID    int64  `json:"i"`
+}
+
+type ListTasksRequest struct {
+	Limit     int
+	Cursor    string
+	Done      *bool
+	Search    string
+	Tag       string
+	Assignee  string
+	Priority  Priority
+	DueBefore *time.Time
+	Sort      string
+}
+
+type TaskPage struct {
+	Items      []Task `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursorPayload binds a position to the filters it was produced for.
+type cursorPayload struct {
+	Sort      string       `json:"s"`
+	Done      *bool        `json:"d,omitempty"`
+	Search    string       `json:"q,omitempty"`
+	Tag       string       `json:"g,omitempty"`
+	Assignee  string       `json:"u,omitempty"`
+	Priority  Priority     `json:"p,omitempty"`
+	DueBefore *time.Time   `json:"b,omitempty"`
+	After     PagePosition `json:"a"`
+}
+
+// matches reports whether the cursor was issued for exactly this query.
+func (p cursorPayload) matches(q TaskQuery) bool {
+	return p.Sort == q.Sort && p.Search == q.Search && p.Tag == q.Tag &&
+		p.Assignee == q.Assignee && p.Priority == q.Priority &&
+		sameDoneFilter(p.Done, q.Done) && sameDueFilter(p.DueBefore, q.DueBefore)
+}
+
+// CursorCodec makes cursors opaque (base64) and tamper-proof (HMAC-SHA256).
+type CursorCodec struct {
+	secret []byte
+}
+
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{secret: secret}
+}
+
+func (c *CursorCodec) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+func (c *CursorCodec) Encode(p cursorPayload) (string, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(raw)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body)), nil
+}
+
+func (c *CursorCodec) Decode(cursor string) (cursorPayload, error) {
+	invalid := fmt.Errorf("%w: cursor is invalid", ErrInvalidQuery)
+	body, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return cursorPayload{}, invalid
+	}
+	given, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(given, c.sign(body)) {
+		return cursorPayload{}, invalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return cursorPayload{}, invalid
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return cursorPayload{}, invalid
+	}
+	return p, nil
+}
+
+func sameDoneFilter(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameDueFilter(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// randomSecret is the fallback when no secret is configured; cursors then
+// stop working after a restart, which is safe but inconvenient.
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+type SQLiteTaskRepo struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func NewSQLiteTaskRepo(db *sql.DB) *SQLiteTaskRepo {
+	return &SQLiteTaskRepo{db: db, now: time.Now}
+}
+
+var taskMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_tasks",
+		Up: `
+CREATE TABLE IF NOT EXISTS tasks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title TEXT NOT NULL,
+  done INTEGER NOT NULL DEFAULT 0
+);`,
+		Down: `DROP TABLE tasks;`,
+	},
+	{
+		Version: 2,
+		Name:    "add_task_version",
+		Up:      `ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+		Down:    `ALTER TABLE tasks DROP COLUMN version;`,
+	},
+	{
+		Version: 3,
+		Name:    "add_soft_delete_and_history",
+		Up: `
+ALTER TABLE tasks ADD COLUMN deleted_at TEXT;
+CREATE TABLE task_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  task_id INTEGER NOT NULL REFERENCES tasks(id),
+  field TEXT NOT NULL,
+  old_value TEXT NOT NULL,
+  new_value TEXT NOT NULL,
+  actor TEXT NOT NULL,
+  changed_at TEXT NOT NULL
+);
+CREATE INDEX task_history_task_id ON task_history (task_id, id);`,
+		Down: `
+DROP TABLE task_history;
+ALTER TABLE tasks DROP COLUMN deleted_at;`,
+	},
+	{
+		Version: 4,
+		Name:    "add_task_details",
+		Up: `
+ALTER TABLE tasks ADD COLUMN due_at TEXT;
+ALTER TABLE tasks ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal';
+ALTER TABLE tasks ADD COLUMN assignee TEXT NOT NULL DEFAULT '';
+CREATE INDEX tasks_due_at ON tasks (due_at) WHERE due_at IS NOT NULL;
+CREATE TABLE task_tags (
+  task_id INTEGER NOT NULL REFERENCES tasks(id),
+  tag TEXT NOT NULL,
+  PRIMARY KEY (task_id, tag)
+);
+CREATE INDEX task_tags_tag ON task_tags (tag, task_id);`,
+		Down: `
+DROP TABLE task_tags;
+DROP INDEX tasks_due_at;
+ALTER TABLE tasks DROP COLUMN assignee;
+ALTER TABLE tasks DROP COLUMN priority;
+ALTER TABLE tasks DROP COLUMN due_at;`,
+	},
+	{
+		Version: 5,
+		Name:    "add_task_relations",
+		// parent_id has no REFERENCES clause: SQLite refuses to DROP a column
+		// that takes part in a foreign key, which would make Down impossible.
+		Up: `
+ALTER TABLE tasks ADD COLUMN parent_id INTEGER;
+CREATE INDEX tasks_parent_id ON tasks (parent_id) WHERE parent_id IS NOT NULL;
+CREATE TABLE task_dependencies (
+  task_id INTEGER NOT NULL REFERENCES tasks(id),
+  blocker_id INTEGER NOT NULL REFERENCES tasks(id),
+  PRIMARY KEY (task_id, blocker_id),
+  CHECK (task_id <> blocker_id)
+);
+CREATE INDEX task_dependencies_blocker ON task_dependencies (blocker_id);`,
+		Down: `
+DROP TABLE task_dependencies;
+DROP INDEX tasks_parent_id;
+ALTER TABLE tasks DROP COLUMN parent_id;`,
+	},
+	{
+		Version: 6,
+		Name:    "add_task_recurrence",
+		Up: `
+ALTER TABLE tasks ADD COLUMN recurrence TEXT;
+CREATE INDEX tasks_recurring ON tasks (id) WHERE recurrence IS NOT NULL;`,
+		Down: `
+DROP INDEX tasks_recurring;
+ALTER TABLE tasks DROP COLUMN recurrence;`,
+	},
+}
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was edited")
+	ErrUnknownMigration = errors.New("database has a migration this binary does not know")
+)
+
+// Migration is one numbered schema step. Never edit a migration after it
+// has shipped; add a new one instead (the checksum check enforces this).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + "\n--down--\n" + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+type MigrationStatus struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// Migrator applies migrations in version order and records each one in
+// schema_migrations inside the same transaction as the schema change.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	DryRun     bool
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  applied_at TEXT NOT NULL
+);`)
+	return err
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt string
+}
+
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		out[version] = a
+	}
+	return out, rows.Err()
+}
+
+// Verify fails when history in the database disagrees with the code.
+func (m *Migrator) Verify() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	return m.verify(applied)
+}
+
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := map[int]bool{}
+	for i, mig := range m.migrations {
+		if mig.Version <= 0 || (i > 0 && mig.Version == m.migrations[i-1].Version) {
+			return fmt.Errorf("migration %d (%s): versions must be positive and unique", mig.Version, mig.Name)
+		}
+		known[mig.Version] = true
+		if a, ok := applied[mig.Version]; ok && a.checksum != mig.Checksum() {
+			return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, ErrChecksumMismatch)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("migration %d: %w", version, ErrUnknownMigration)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		a, ok := applied[mig.Version]
+		out = append(out, MigrationStatus{Migration: mig, Applied: ok, AppliedAt: a.appliedAt})
+	}
+	return out, nil
+}
+
+// Up applies every pending migration and returns what ran (or would run
+// when DryRun is set).
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Up, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+				mig.Version, mig.Name, mig.Checksum(), time.Now().UTC().Format(time.RFC3339))
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) up: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the newest `steps` applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if !m.DryRun {
+			err := m.inTx(mig.Down, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+			if err != nil {
+				return done, fmt.Errorf("migration %d (%s) down: %w", mig.Version, mig.Name, err)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// inTx runs a schema step and its bookkeeping row atomically.
+func (m *Migrator) inTx(step string, record string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(step); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runMigrateCommand implements `migrate status|up|down [-dry-run] [-steps N]`.
+func runMigrateCommand(migrator *Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate status|up|down [-dry-run] [-steps N]")
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "print the plan without changing the database")
+	steps := fs.Int("steps", 1, "how many migrations to revert (down only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	migrator.DryRun = *dryRun
+	prefix := ""
+	if *dryRun {
+		prefix = "(dry run) "
+	}
+
+	switch args[0] {
+	case "status":
+		items, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range items {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt
+			}
+			fmt.Fprintf(out, "%04d %-28s %s\n", st.Migration.Version, st.Migration.Name, state)
+		}
+		return migrator.Verify()
+	case "up":
+		done, err := migrator.Up()
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sup   %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	case "down":
+		done, err := migrator.Down(*steps)
+		for _, mig := range done {
+			fmt.Fprintf(out, "%sdown %04d %s\n", prefix, mig.Version, mig.Name)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func (r *SQLiteTaskRepo) Migrate() error {
+	_, err := NewMigrator(r.db, taskMigrations).Up()
+	return err
+}
+
+func (r *SQLiteTaskRepo) List() ([]Task, error) {
+	return r.query(` FROM tasks WHERE deleted_at IS NULL ORDER BY id`)
+}
+
+// SeriesHeads returns the live tasks holding a rule that are done or were
+// due by dueBy. A head due later cannot have reached its next occurrence
+// yet, so the scheduler never has to read the rest of the table.
+func (r *SQLiteTaskRepo) SeriesHeads(dueBy time.Time) ([]Task, error) {
+	return r.query(` FROM tasks
+WHERE deleted_at IS NULL AND recurrence IS NOT NULL AND due_at IS NOT NULL AND (done = 1 OR due_at <= ?)
+ORDER BY id`, formatDue(&dueBy))
+}
+
+// query runs SELECT taskColumns plus from, then loads the tags of exactly
+// those rows with the same from clause.
+func (r *SQLiteTaskRepo) query(from string, args ...any) ([]Task, error) {
+	rows, err := r.db.Query(`SELECT `+taskColumns+from, args...)
+	if err != nil {
+		return nil, err
+	}
+	items, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	return items, loadTags(r.db, items, `SELECT id`+from, args...)
+}
+
+// foldTitle is the one case fold search uses, in Go and in SQL alike.
+// SQLite's lower() only folds ASCII ("É" stays "É"), so init registers this
+// function as fold() instead of relying on it.
+func foldTitle(s string) string {
+	return strings.ToLower(s)
+}
+
+// Functions registered on the driver exist on every connection it opens
+// afterwards, which is why this runs in init rather than in Migrate.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("fold", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if s, ok := args[0].(string); ok {
+			return foldTitle(s), nil
+		}
+		return args[0], nil
+	})
+}
+
+// Query builds SQL from fixed fragments only; every user value is a parameter.
+func (r *SQLiteTaskRepo) Query(q TaskQuery) ([]Task, error) {
+	where := []string{"deleted_at IS NULL"}
+	args := []any{}
+	if q.Done != nil {
+		doneInt := 0
+		if *q.Done {
+			doneInt = 1
+		}
+		where = append(where, "done = ?")
+		args = append(args, doneInt)
+	}
+	if q.Search != "" {
+		where = append(where, "instr(fold(title), ?) > 0")
+		args = append(args, foldTitle(q.Search))
+	}
+	if q.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM task_tags WHERE task_tags.task_id = tasks.id AND tag = ?)")
+		args = append(args, q.Tag)
+	}
+	if q.Assignee != "" {
+		where = append(where, "assignee = ?")
+		args = append(args, q.Assignee)
+	}
+	if q.Priority != "" {
+		where = append(where, "priority = ?")
+		args = append(args, string(q.Priority))
+	}
+	if q.DueBefore != nil {
+		// Same fixed-width UTC format on both sides, so text order is time order.
+		where = append(where, "due_at IS NOT NULL AND due_at < ?")
+		args = append(args, formatDue(q.DueBefore))
+	}
+
+	op, dir := ">", "ASC"
+	if strings.HasPrefix(q.Sort, "-") {
+		op, dir = "<", "DESC"
+	}
+	byTitle := strings.TrimPrefix(q.Sort, "-") == "title"
+	if q.After != nil {
+		if byTitle {
+			where = append(where, "(title "+op+" ? OR (title = ? AND id "+op+" ?))")
+			args = append(args, q.After.Title, q.After.Title, q.After.ID)
+		} else {
+			where = append(where, "id "+op+" ?")
+			args = append(args, q.After.ID)
+		}
+	}
+
+	from := ` FROM tasks WHERE ` + strings.Join(where, " AND ")
+	if byTitle {
+		from += " ORDER BY title " + dir + ", id " + dir
+	} else {
+		from += " ORDER BY id " + dir
+	}
+	if q.Limit > 0 {
+		from += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	return r.query(from, args...)
+}
+
+const taskColumns = `id, title, done, version, due_at, priority, assignee, parent_id, recurrence`
+
+// scanTask reads one taskColumns row from either *sql.Row or *sql.Rows.
+// Tags live in their own table; loadTags fills them in afterwards.
+func scanTask(row interface{ Scan(...any) error }) (Task, error) {
+	var t Task
+	var doneInt int
+	var dueAt sql.NullString
+	var parentID sql.NullInt64
+	var recurrence sql.NullString
+	if err := row.Scan(&t.ID, &t.Title, &doneInt, &t.Version, &dueAt, &t.Priority, &t.Assignee, &parentID, &recurrence); err != nil {
+		return Task{}, err
+	}
+	if recurrence.Valid {
+		t.Recurrence = &Recurrence{}
+		if err := json.Unmarshal([]byte(recurrence.String), t.Recurrence); err != nil {
+			return Task{}, fmt.Errorf("task id %d: bad recurrence %q: %w", t.ID, recurrence.String, err)
+		}
+	}
+	t.Done = doneInt == 1
+	if parentID.Valid {
+		t.ParentID = &parentID.Int64
+	}
+	t.Tags = []string{}
+	if dueAt.Valid {
+		due, err := time.Parse(time.RFC3339, dueAt.String)
+		if err != nil {
+			return Task{}, fmt.Errorf("task id %d: bad due_at %q: %w", t.ID, dueAt.String, err)
+		}
+		t.DueAt = &due
+	}
+	return t, nil
+}
+
+// loadTags fetches tags for a whole page in one query instead of one per task.
+// selectIDs is the query that picked items, selecting only id, reused as a
+// subquery: binding one ? per task would hit SQLite's variable limit on a
+// long list.
+func loadTags(q queryer, items []Task, selectIDs string, args ...any) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := map[int64]int{}
+	for i, t := range items {
+		byID[t.ID] = i
+	}
+	rows, err := q.Query(`SELECT task_id, tag FROM task_tags WHERE task_id IN (`+selectIDs+`) ORDER BY tag`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		// A task added since the list query is not in items; skip it.
+		i, ok := byID[id]
+		if !ok {
+			continue
+		}
+		items[i].Tags = append(items[i].Tags, tag)
+	}
+	return rows.Err()
+}
+
+func writeTags(tx *sql.Tx, id int64, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, id); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT INTO task_tags (task_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recurrenceValue stores the rule as JSON text, NULL when there is none.
+func recurrenceValue(rule *Recurrence) any {
+	if rule == nil {
+		return nil
+	}
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+// dueValue maps "no due date" to NULL rather than an empty string.
+func dueValue(due *time.Time) any {
+	if due == nil {
+		return nil
+	}
+	return formatDue(due)
+}
+
+func scanTasks(rows *sql.Rows) ([]Task, error) {
+	defer rows.Close()
+
+	items := []Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// getLiveTask only sees tasks that are not soft-deleted.
+func getLiveTask(q queryer, id int64) (Task, error) {
+	t, err := scanTask(q.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ? AND deleted_at IS NULL`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	items := []Task{t}
+	if err := loadTags(q, items, `SELECT id FROM tasks WHERE id = ?`, id); err != nil {
+		return Task{}, err
+	}
+	return items[0], nil
+}
+
+func (r *SQLiteTaskRepo) Get(id int64) (Task, error) {
+	return getLiveTask(r.db, id)
+}
+
+// inTx commits fn's writes together with their history rows, or neither.
+func (r *SQLiteTaskRepo) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteTaskRepo) timestamp() string {
+	return r.now().UTC().Format(time.RFC3339Nano)
+}
+
+func insertHistory(tx *sql.Tx, id int64, changes []HistoryEntry, actor string, at string) error {
+	for _, c := range changes {
+		_, err := tx.Exec(`
+INSERT INTO task_history (task_id, field, old_value, new_value, actor, changed_at)
+VALUES (?, ?, ?, ?, ?, ?)`, id, c.Field, c.OldValue, c.NewValue, actor, at)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkParent runs inside the write transaction so the parent cannot vanish
+// or grow a loop between the check and the write.
+func checkParent(tx *sql.Tx, task Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+	if _, err := getLiveTask(tx, *task.ParentID); err != nil {
+		if errors.Is(err, ErrTaskNotFound) {
+			return fmt.Errorf("%w: parent task %d not found", ErrInvalidTask, *task.ParentID)
+		}
+		return err
+	}
+	return checkNewEdge(tx, task.ID, *task.ParentID)
+}
+
+// checkNewEdge rejects from -> to when to already reaches from. It walks
+// both edge kinds, including edges of deleted tasks, because a restore
+// must never bring a cycle back.
+func checkNewEdge(tx *sql.Tx, from, to int64) error {
+	if from == 0 {
+		return nil // a task that does not exist yet has no edges to loop through
+	}
+	var found int
+	err := tx.QueryRow(`
+WITH RECURSIVE edges(src, dst) AS (
+  SELECT blocker_id, task_id FROM task_dependencies
+  UNION ALL
+  SELECT id, parent_id FROM tasks WHERE parent_id IS NOT NULL
+),
+reach(id) AS (
+  SELECT ?
+  UNION
+  SELECT edges.dst FROM edges JOIN reach ON edges.src = reach.id
+)
+SELECT 1 FROM reach WHERE id = ? LIMIT 1`, to, from).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("task %d -> %d: %w", from, to, ErrDependencyCycle)
+}
+
+func parentValue(id *int64) any {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+func (r *SQLiteTaskRepo) Add(task Task, actor string) (Task, error) {
+	var t Task
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		t, err = r.insertTask(tx, task, actor)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return t, nil
+}
+
+func (r *SQLiteTaskRepo) insertTask(tx *sql.Tx, task Task, actor string) (Task, error) {
+	if err := checkParent(tx, task); err != nil {
+		return Task{}, err
+	}
+	doneInt := 0
+	if task.Done {
+		doneInt = 1
+	}
+	result, err := tx.Exec(`
+INSERT INTO tasks (title, done, due_at, priority, assignee, parent_id, recurrence) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		task.Title, doneInt, dueValue(task.DueAt), string(task.Priority), task.Assignee, parentValue(task.ParentID),
+		recurrenceValue(task.Recurrence))
+	if err != nil {
+		return Task{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Task{}, err
+	}
+	if err := writeTags(tx, id, task.Tags); err != nil {
+		return Task{}, err
+	}
+	t := task
+	t.ID, t.Version = id, 1
+	return t, insertHistory(tx, id, taskChanges(Task{}, t), actor, r.timestamp())
+}
+
+// Update is a compare-and-swap: the version check and the write happen in
+// one statement, so two writers can never both win.
+func (r *SQLiteTaskRepo) Update(task Task, expectedVersion int64, actor string) (Task, error) {
+	var updated Task
+	err := r.inTx(func(tx *sql.Tx) error {
+		var err error
+		updated, err = r.updateTask(tx, task, expectedVersion, actor)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return updated, nil
+}
+
+func (r *SQLiteTaskRepo) updateTask(tx *sql.Tx, task Task, expectedVersion int64, actor string) (Task, error) {
+	current, err := getLiveTask(tx, task.ID)
+	if err != nil {
+		return Task{}, err
+	}
+	if expectedVersion != 0 && expectedVersion != current.Version {
+		return Task{}, fmt.Errorf("task id %d: %w", task.ID, ErrVersionConflict)
+	}
+	if !sameParent(current.ParentID, task.ParentID) {
+		if err := checkParent(tx, task); err != nil {
+			return Task{}, err
+		}
+	}
+	if task.Done && !current.Done {
+		if err := checkBlockers(tx, task.ID); err != nil {
+			return Task{}, err
+		}
+	}
+	doneInt := 0
+	if task.Done {
+		doneInt = 1
+	}
+	result, err := tx.Exec(`
+UPDATE tasks SET title = ?, done = ?, due_at = ?, priority = ?, assignee = ?, parent_id = ?, recurrence = ?,
+  version = version + 1
+WHERE id = ? AND version = ? AND deleted_at IS NULL`,
+		task.Title, doneInt, dueValue(task.DueAt), string(task.Priority), task.Assignee, parentValue(task.ParentID),
+		recurrenceValue(task.Recurrence), task.ID, current.Version)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := expectOneRow(result, task.ID); err != nil {
+		return Task{}, err
+	}
+	if err := writeTags(tx, task.ID, task.Tags); err != nil {
+		return Task{}, err
+	}
+	updated := task
+	updated.Version = current.Version + 1
+	return updated, insertHistory(tx, task.ID, taskChanges(current, updated), actor, r.timestamp())
+}
+
+// SpawnOccurrence moves the recurrence rule from head to a new task in one
+// transaction. The version check on head makes it safe for the scheduler
+// and a completing request to race: only one of them creates the occurrence.
+func (r *SQLiteTaskRepo) SpawnOccurrence(headID, expectedVersion int64, next Task, actor string) (Task, Task, error) {
+	var head, created Task
+	err := r.inTx(func(tx *sql.Tx) error {
+		current, err := getLiveTask(tx, headID)
+		if err != nil {
+			return err
+		}
+		if current.Recurrence == nil {
+			return fmt.Errorf("task id %d has no recurrence to hand over: %w", headID, ErrVersionConflict)
+		}
+		current.Recurrence = nil
+		if head, err = r.updateTask(tx, current, expectedVersion, actor); err != nil {
+			return err
+		}
+		created, err = r.insertTask(tx, next, actor)
+		return err
+	})
+	if err != nil {
+		return Task{}, Task{}, err
+	}
+	return head, created, nil
+}
+
+// Import runs the whole batch in one transaction: a failing item rolls back
+// every task inserted before it. Blocker edges go in once every task of the
+// batch has an id, with the same checks as AddBlocker.
+func (r *SQLiteTaskRepo) Import(items []ImportItem, actor string) ([]Task, error) {
+	created := make([]Task, 0, len(items))
+	err := r.inTx(func(tx *sql.Tx) error {
+		for i, item := range items {
+			task := item.Task
+			if item.ParentItem != nil {
+				parent := created[*item.ParentItem].ID
+				task.ParentID = &parent
+			}
+			t, err := r.insertTask(tx, task, actor)
+			if err != nil {
+				return fmt.Errorf("import item %d: %w", i+1, err)
+			}
+			created = append(created, t)
+		}
+		for i, item := range items {
+			for _, blocker := range importBlockers(item, created) {
+				if err := r.insertBlocker(tx, created[i].ID, blocker, actor); err != nil {
+					return fmt.Errorf("import item %d: %w", i+1, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// MarkDone reads and writes in one transaction, so a rename that lands in
+// between cannot be overwritten with the title it read.
+func (r *SQLiteTaskRepo) MarkDone(id int64, actor string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		current, err := getLiveTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if !current.Done {
+			if err := checkBlockers(tx, id); err != nil {
+				return err
+			}
+		}
+		result, err := tx.Exec(`
+UPDATE tasks SET done = 1, version = version + 1
+WHERE id = ? AND version = ? AND deleted_at IS NULL`, id, current.Version)
+		if err != nil {
+			return err
+		}
+		if err := expectOneRow(result, id); err != nil {
+			return err
+		}
+		updated := current
+		updated.Done = true
+		updated.Version++
+		return insertHistory(tx, id, taskChanges(current, updated), actor, r.timestamp())
+	})
+}
+
+// checkBlockers refuses completion while any live blocker is still open.
+// It runs in the same transaction as the write, so a blocker reopened in
+// between cannot slip past; deleted blockers no longer count.
+func checkBlockers(tx *sql.Tx, id int64) error {
+	var blocked bool
+	err := tx.QueryRow(`
+SELECT EXISTS (
+  SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocker_id
+  WHERE d.task_id = ? AND b.done = 0 AND b.deleted_at IS NULL
+)`, id).Scan(&blocked)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return fmt.Errorf("task id %d: %w", id, ErrTaskBlocked)
+	}
+	return nil
+}
+
+// Delete is a soft delete: the row stays, deleted_at hides it from reads.
+func (r *SQLiteTaskRepo) Delete(id int64, expectedVersion int64, actor string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		current, err := getLiveTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && expectedVersion != current.Version {
+			return fmt.Errorf("task id %d: %w", id, ErrVersionConflict)
+		}
+		at := r.timestamp()
+		result, err := tx.Exec(`
+UPDATE tasks SET deleted_at = ?, version = version + 1
+WHERE id = ? AND version = ? AND deleted_at IS NULL`, at, id, current.Version)
+		if err != nil {
+			return err
+		}
+		if err := expectOneRow(result, id); err != nil {
+			return err
+		}
+		return insertHistory(tx, id, []HistoryEntry{{Field: "deleted_at", OldValue: "", NewValue: at}}, actor, at)
+	})
+}
+
+func (r *SQLiteTaskRepo) Restore(id int64, actor string) (Task, error) {
+	var restored Task
+	err := r.inTx(func(tx *sql.Tx) error {
+		var deletedAt sql.NullString
+		err := tx.QueryRow(`SELECT deleted_at FROM tasks WHERE id = ?`, id).Scan(&deletedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if !deletedAt.Valid {
+			return fmt.Errorf("task id %d: %w", id, ErrTaskNotDeleted)
+		}
+		at := r.timestamp()
+		if _, err := tx.Exec(`UPDATE tasks SET deleted_at = NULL, version = version + 1 WHERE id = ?`, id); err != nil {
+			return err
+		}
+		if err := insertHistory(tx, id, []HistoryEntry{{Field: "deleted_at", OldValue: deletedAt.String, NewValue: ""}}, actor, at); err != nil {
+			return err
+		}
+		restored, err = getLiveTask(tx, id)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return restored, nil
+}
+
+// History includes deleted tasks: being able to see who deleted a task is
+// the point of keeping it.
+func (r *SQLiteTaskRepo) History(id int64) ([]HistoryEntry, error) {
+	var exists int
+	err := r.db.QueryRow(`SELECT 1 FROM tasks WHERE id = ?`, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`
+SELECT task_id, field, old_value, new_value, actor, changed_at
+FROM task_history WHERE task_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		var e HistoryEntry
+		var changedAt string
+		if err := rows.Scan(&e.TaskID, &e.Field, &e.OldValue, &e.NewValue, &e.Actor, &changedAt); err != nil {
+			return nil, err
+		}
+		if e.ChangedAt, err = time.Parse(time.RFC3339Nano, changedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *SQLiteTaskRepo) AddBlocker(taskID, blockerID int64, actor string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		return r.insertBlocker(tx, taskID, blockerID, actor)
+	})
+}
+
+// insertBlocker checks both tasks and the cycle walk in the same
+// transaction as the insert, so the check cannot go stale.
+func (r *SQLiteTaskRepo) insertBlocker(tx *sql.Tx, taskID, blockerID int64, actor string) error {
+	if _, err := getLiveTask(tx, taskID); err != nil {
+		return err
+	}
+	if _, err := getLiveTask(tx, blockerID); err != nil {
+		return err
+	}
+	if err := checkNewEdge(tx, blockerID, taskID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`INSERT OR IGNORE INTO task_dependencies (task_id, blocker_id) VALUES (?, ?)`, taskID, blockerID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return err // adding an existing edge is a no-op, not a change
+	}
+	change := HistoryEntry{Field: "blocked_by", OldValue: "", NewValue: strconv.FormatInt(blockerID, 10)}
+	return insertHistory(tx, taskID, []HistoryEntry{change}, actor, r.timestamp())
+}
+
+func (r *SQLiteTaskRepo) RemoveBlocker(taskID, blockerID int64, actor string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		if _, err := getLiveTask(tx, taskID); err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM task_dependencies WHERE task_id = ? AND blocker_id = ?`, taskID, blockerID)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("task id %d is not blocked by %d: %w", taskID, blockerID, ErrTaskNotFound)
+		}
+		change := HistoryEntry{Field: "blocked_by", OldValue: strconv.FormatInt(blockerID, 10), NewValue: ""}
+		return insertHistory(tx, taskID, []HistoryEntry{change}, actor, r.timestamp())
+	})
+}
+
+func (r *SQLiteTaskRepo) Edges() ([]TaskEdge, error) {
+	rows, err := r.db.Query(`
+SELECT d.blocker_id, d.task_id, 'blocks'
+FROM task_dependencies d
+JOIN tasks b ON b.id = d.blocker_id AND b.deleted_at IS NULL
+JOIN tasks t ON t.id = d.task_id AND t.deleted_at IS NULL
+UNION ALL
+SELECT c.id, c.parent_id, 'subtask'
+FROM tasks c
+JOIN tasks p ON p.id = c.parent_id AND p.deleted_at IS NULL
+WHERE c.deleted_at IS NULL
+ORDER BY 2, 1, 3`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := []TaskEdge{}
+	for rows.Next() {
+		var e TaskEdge
+		if err := rows.Scan(&e.From, &e.To, &e.Kind); err != nil {
+			return nil, err
+		}
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
+
+// expectOneRow explains "zero rows touched": the row was read in this
+// transaction, so the only way to miss it is a concurrent version bump.
+func expectOneRow(result sql.Result, id int64) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("task id %d: %w", id, ErrVersionConflict)
+	}
+	return nil
+}
+
+// InMemoryTaskRepo is the test double: same contract, same soft delete and
+// history rules, no database.
+type InMemoryTaskRepo struct {
+	mu       sync.Mutex
+	items    []Task
+	deleted  map[int64]time.Time
+	history  []HistoryEntry
+	blockers []TaskEdge
+	nextID   int64
+	now      func() time.Time
+}
+
+func NewInMemoryTaskRepo() *InMemoryTaskRepo {
+	return &InMemoryTaskRepo{deleted: map[int64]time.Time{}, nextID: 1, now: time.Now}
+}
+
+// cloneTask keeps callers from sharing the stored tag slice or due date.
+func cloneTask(t Task) Task {
+	t.Tags = append([]string{}, t.Tags...)
+	if t.DueAt != nil {
+		due := *t.DueAt
+		t.DueAt = &due
+	}
+	if t.ParentID != nil {
+		parent := *t.ParentID
+		t.ParentID = &parent
+	}
+	if t.Recurrence != nil {
+		rule := *t.Recurrence
+		t.Recurrence = &rule
+	}
+	return t
+}
+
+func (r *InMemoryTaskRepo) live() []Task {
+	out := make([]Task, 0, len(r.items))
+	for _, t := range r.items {
+		if _, gone := r.deleted[t.ID]; !gone {
+			out = append(out, cloneTask(t))
+		}
+	}
+	return out
+}
+
+func (r *InMemoryTaskRepo) index(id int64) int {
+	for i := range r.items {
+		if r.items[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *InMemoryTaskRepo) record(id int64, changes []HistoryEntry, actor string, at time.Time) {
+	for _, c := range changes {
+		c.TaskID, c.Actor, c.ChangedAt = id, actor, at.UTC()
+		r.history = append(r.history, c)
+	}
+}
+
+func (r *InMemoryTaskRepo) List() ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.live(), nil
+}
+
+func (r *InMemoryTaskRepo) SeriesHeads(dueBy time.Time) ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	heads := []Task{}
+	for _, t := range r.live() {
+		if t.Recurrence != nil && t.DueAt != nil && (t.Done || !t.DueAt.After(dueBy)) {
+			heads = append(heads, t)
+		}
+	}
+	return heads, nil
+}
+
+func (r *InMemoryTaskRepo) Query(q TaskQuery) ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return applyTaskQuery(r.live(), q), nil
+}
+
+func (r *InMemoryTaskRepo) Get(id int64) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getLive(id)
+}
+
+func (r *InMemoryTaskRepo) getLive(id int64) (Task, error) {
+	i := r.index(id)
+	if _, gone := r.deleted[id]; i < 0 || gone {
+		return Task{}, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	return cloneTask(r.items[i]), nil
+}
+
+// allEdges mirrors the SQL adapter's cycle check: deleted tasks included.
+func (r *InMemoryTaskRepo) allEdges() []TaskEdge {
+	edges := append([]TaskEdge{}, r.blockers...)
+	for _, t := range r.items {
+		if t.ParentID != nil {
+			edges = append(edges, TaskEdge{From: t.ID, To: *t.ParentID, Kind: EdgeSubtask})
+		}
+	}
+	return edges
+}
+
+func (r *InMemoryTaskRepo) checkNewEdge(from, to int64) error {
+	if from == 0 {
+		return nil
+	}
+	next := map[int64][]int64{}
+	for _, e := range r.allEdges() {
+		next[e.From] = append(next[e.From], e.To)
+	}
+	seen := map[int64]bool{to: true}
+	stack := []int64{to}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == from {
+			return fmt.Errorf("task %d -> %d: %w", from, to, ErrDependencyCycle)
+		}
+		for _, n := range next[id] {
+			if !seen[n] {
+				seen[n] = true
+				stack = append(stack, n)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryTaskRepo) checkParent(task Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+	if _, err := r.getLive(*task.ParentID); err != nil {
+		return fmt.Errorf("%w: parent task %d not found", ErrInvalidTask, *task.ParentID)
+	}
+	return r.checkNewEdge(task.ID, *task.ParentID)
+}
+
+// checkBlockers is the in-memory twin of the SQLite EXISTS query; the
+// caller holds the lock.
+func (r *InMemoryTaskRepo) checkBlockers(id int64) error {
+	for _, e := range r.blockers {
+		if e.To != id {
+			continue
+		}
+		if _, gone := r.deleted[e.From]; gone {
+			continue
+		}
+		if !r.items[r.index(e.From)].Done {
+			return fmt.Errorf("task id %d: %w", id, ErrTaskBlocked)
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryTaskRepo) Add(task Task, actor string) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.add(task, actor)
+}
+
+func (r *InMemoryTaskRepo) add(task Task, actor string) (Task, error) {
+	if err := r.checkParent(task); err != nil {
+		return Task{}, err
+	}
+	t := cloneTask(task)
+	t.ID, t.Version = r.nextID, 1
+	r.nextID++
+	r.items = append(r.items, t)
+	r.record(t.ID, taskChanges(Task{}, t), actor, r.now())
+	return cloneTask(t), nil
+}
+
+func (r *InMemoryTaskRepo) Update(task Task, expectedVersion int64, actor string) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(task, expectedVersion, actor)
+}
+
+func (r *InMemoryTaskRepo) update(task Task, expectedVersion int64, actor string) (Task, error) {
+	current, err := r.getLive(task.ID)
+	if err != nil {
+		return Task{}, err
+	}
+	if expectedVersion != 0 && expectedVersion != current.Version {
+		return Task{}, fmt.Errorf("task id %d: %w", task.ID, ErrVersionConflict)
+	}
+	if !sameParent(current.ParentID, task.ParentID) {
+		if err := r.checkParent(task); err != nil {
+			return Task{}, err
+		}
+	}
+	if task.Done && !current.Done {
+		if err := r.checkBlockers(task.ID); err != nil {
+			return Task{}, err
+		}
+	}
+	updated := cloneTask(task)
+	updated.Version = current.Version + 1
+	r.items[r.index(task.ID)] = updated
+	r.record(task.ID, taskChanges(current, updated), actor, r.now())
+	return cloneTask(updated), nil
+}
+
+func (r *InMemoryTaskRepo) SpawnOccurrence(headID, expectedVersion int64, next Task, actor string) (Task, Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, err := r.getLive(headID)
+	if err != nil {
+		return Task{}, Task{}, err
+	}
+	if current.Recurrence == nil {
+		return Task{}, Task{}, fmt.Errorf("task id %d has no recurrence to hand over: %w", headID, ErrVersionConflict)
+	}
+	// Validate the new task before touching head so a failure changes nothing.
+	if err := r.checkParent(next); err != nil {
+		return Task{}, Task{}, err
+	}
+	current.Recurrence = nil
+	head, err := r.update(current, expectedVersion, actor)
+	if err != nil {
+		return Task{}, Task{}, err
+	}
+	created, err := r.add(next, actor)
+	if err != nil {
+		return Task{}, Task{}, err
+	}
+	return head, created, nil
+}
+
+// Import only ever appends, so undoing a failed batch is a truncate.
+func (r *InMemoryTaskRepo) Import(items []ImportItem, actor string) ([]Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	itemCount, historyCount, blockerCount, nextID := len(r.items), len(r.history), len(r.blockers), r.nextID
+	fail := func(i int, err error) ([]Task, error) {
+		r.items, r.history, r.blockers, r.nextID = r.items[:itemCount], r.history[:historyCount], r.blockers[:blockerCount], nextID
+		return nil, fmt.Errorf("import item %d: %w", i+1, err)
+	}
+	created := make([]Task, 0, len(items))
+	for i, item := range items {
+		task := item.Task
+		if item.ParentItem != nil {
+			parent := created[*item.ParentItem].ID
+			task.ParentID = &parent
+		}
+		t, err := r.add(task, actor)
+		if err != nil {
+			return fail(i, err)
+		}
+		created = append(created, t)
+	}
+	for i, item := range items {
+		for _, blocker := range importBlockers(item, created) {
+			if err := r.addBlocker(created[i].ID, blocker, actor); err != nil {
+				return fail(i, err)
+			}
+		}
+	}
+	return created, nil
+}
+
+func (r *InMemoryTaskRepo) MarkDone(id int64, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, err := r.getLive(id)
+	if err != nil {
+		return err
+	}
+	if !current.Done {
+		if err := r.checkBlockers(id); err != nil {
+			return err
+		}
+	}
+	updated := current
+	updated.Done = true
+	updated.Version++
+	r.items[r.index(id)] = updated
+	r.record(id, taskChanges(current, updated), actor, r.now())
+	return nil
+}
+
+func (r *InMemoryTaskRepo) Delete(id int64, expectedVersion int64, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, err := r.getLive(id)
+	if err != nil {
+		return err
+	}
+	if expectedVersion != 0 && expectedVersion != current.Version {
+		return fmt.Errorf("task id %d: %w", id, ErrVersionConflict)
+	}
+	at := r.now().UTC()
+	r.deleted[id] = at
+	r.items[r.index(id)].Version++
+	r.record(id, []HistoryEntry{{Field: "deleted_at", OldValue: "", NewValue: at.Format(time.RFC3339Nano)}}, actor, at)
+	return nil
+}
+
+func (r *InMemoryTaskRepo) Restore(id int64, actor string) (Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.index(id)
+	if i < 0 {
+		return Task{}, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	deletedAt, gone := r.deleted[id]
+	if !gone {
+		return Task{}, fmt.Errorf("task id %d: %w", id, ErrTaskNotDeleted)
+	}
+	delete(r.deleted, id)
+	r.items[i].Version++
+	r.record(id, []HistoryEntry{{Field: "deleted_at", OldValue: deletedAt.Format(time.RFC3339Nano), NewValue: ""}}, actor, r.now())
+	return cloneTask(r.items[i]), nil
+}
+
+func (r *InMemoryTaskRepo) History(id int64) ([]HistoryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.index(id) < 0 {
+		return nil, fmt.Errorf("task id %d: %w", id, ErrTaskNotFound)
+	}
+	entries := []HistoryEntry{}
+	for _, e := range r.history {
+		if e.TaskID == id {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (r *InMemoryTaskRepo) AddBlocker(taskID, blockerID int64, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addBlocker(taskID, blockerID, actor)
+}
+
+func (r *InMemoryTaskRepo) addBlocker(taskID, blockerID int64, actor string) error {
+	if _, err := r.getLive(taskID); err != nil {
+		return err
+	}
+	if _, err := r.getLive(blockerID); err != nil {
+		return err
+	}
+	if err := r.checkNewEdge(blockerID, taskID); err != nil {
+		return err
+	}
+	edge := TaskEdge{From: blockerID, To: taskID, Kind: EdgeBlocks}
+	if slices.Contains(r.blockers, edge) {
+		return nil
+	}
+	r.blockers = append(r.blockers, edge)
+	r.record(taskID, []HistoryEntry{{Field: "blocked_by", OldValue: "", NewValue: strconv.FormatInt(blockerID, 10)}}, actor, r.now())
+	return nil
+}
+
+func (r *InMemoryTaskRepo) RemoveBlocker(taskID, blockerID int64, actor string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.getLive(taskID); err != nil {
+		return err
+	}
+	i := slices.Index(r.blockers, TaskEdge{From: blockerID, To: taskID, Kind: EdgeBlocks})
+	if i < 0 {
+		return fmt.Errorf("task id %d is not blocked by %d: %w", taskID, blockerID, ErrTaskNotFound)
+	}
+	r.blockers = slices.Delete(r.blockers, i, i+1)
+	r.record(taskID, []HistoryEntry{{Field: "blocked_by", OldValue: strconv.FormatInt(blockerID, 10), NewValue: ""}}, actor, r.now())
+	return nil
+}
+
+func (r *InMemoryTaskRepo) Edges() ([]TaskEdge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	edges := []TaskEdge{}
+	for _, e := range r.allEdges() {
+		_, fromGone := r.deleted[e.From]
+		_, toGone := r.deleted[e.To]
+		if !fromGone && !toGone {
+			edges = append(edges, e)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].To != edges[j].To {
+			return edges[i].To < edges[j].To
+		}
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].Kind < edges[j].Kind
+	})
+	return edges, nil
+}
+
+// compareTasks orders tasks by the sort key, using id as the tie-breaker.
+func compareTasks(sortKey string, a, b Task) int {
+	field, desc := strings.TrimPrefix(sortKey, "-"), strings.HasPrefix(sortKey, "-")
+	c := cmp.Compare(a.ID, b.ID)
+	if field == "title" && a.Title != b.Title {
+		c = cmp.Compare(a.Title, b.Title)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+// applyTaskQuery gives the in-memory adapter the same semantics as the SQL one.
+func applyTaskQuery(items []Task, q TaskQuery) []Task {
+	search := strings.ToLower(q.Search)
+	out := make([]Task, 0)
+	for _, t := range items {
+		if q.Done != nil && t.Done != *q.Done {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(t.Title), search) {
+			continue
+		}
+		if q.Tag != "" && !slices.Contains(t.Tags, q.Tag) {
+			continue
+		}
+		if (q.Assignee != "" && t.Assignee != q.Assignee) || (q.Priority != "" && t.Priority != q.Priority) {
+			continue
+		}
+		if q.DueBefore != nil && (t.DueAt == nil || !t.DueAt.Before(*q.DueBefore)) {
+			continue
+		}
+		if q.After != nil && compareTasks(q.Sort, t, Task{ID: q.After.ID, Title: q.After.Title}) <= 0 {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return compareTasks(q.Sort, out[i], out[j]) < 0
+	})
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out
+}
+
+// TaskRecord is the exchange form of a task: one NDJSON line or one CSV row.
+// Version and derived fields stay behind. ID is the task's id where it was
+// exported; import only uses it to link parent_id and blockers between
+// records.
+type TaskRecord struct {
+	ID         int64       `json:"id,omitempty"`
+	Title      string      `json:"title"`
+	Done       bool        `json:"done"`
+	DueAt      *time.Time  `json:"due_at,omitempty"`
+	Priority   Priority    `json:"priority,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	Assignee   string      `json:"assignee,omitempty"`
+	ParentID   *int64      `json:"parent_id,omitempty"`
+	Blockers   []int64     `json:"blockers,omitempty"`
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Line is where the record starts in the import, for error reports.
+	Line int `json:"-"`
+}
+
+func recordFromTask(t Task) TaskRecord {
+	return TaskRecord{
+		ID:         t.ID,
+		Title:      t.Title,
+		Done:       t.Done,
+		DueAt:      t.DueAt,
+		Priority:   t.Priority,
+		Tags:       t.Tags,
+		Assignee:   t.Assignee,
+		ParentID:   t.ParentID,
+		Recurrence: t.Recurrence,
+	}
+}
+
+type ExchangeFormat string
+
+const (
+	FormatNDJSON ExchangeFormat = "ndjson"
+	FormatCSV    ExchangeFormat = "csv"
+)
+
+const (
+	maxImportRecords = 10000
+	maxImportBytes   = 8 << 20
+	maxNDJSONLine    = 1 << 20
+	exportBatchSize  = 500
+)
+
+// parseExchangeFormat defaults to NDJSON, the format that round-trips
+// every field without a second encoding inside a cell.
+func parseExchangeFormat(raw string) (ExchangeFormat, error) {
+	switch ExchangeFormat(strings.ToLower(raw)) {
+	case "", FormatNDJSON:
+		return FormatNDJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("%w: format must be csv or ndjson", ErrInvalidQuery)
+}
+
+func (f ExchangeFormat) contentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// csvColumns is the export order. Import finds columns by header name, so
+// a file may reorder them or leave optional ones out; only title is required.
+// Tags and blockers are space separated (a tag cannot contain spaces) and
+// the recurrence rule is the same JSON object the API uses.
+var csvColumns = []string{"id", "title", "done", "due_at", "priority", "tags", "assignee", "parent_id", "blockers", "recurrence"}
+
+type recordWriter interface {
+	Write(rec TaskRecord) error
+	Flush() error
+}
+
+type ndjsonRecordWriter struct {
+	enc *json.Encoder
+}
+
+func (w ndjsonRecordWriter) Write(rec TaskRecord) error { return w.enc.Encode(rec) }
+func (w ndjsonRecordWriter) Flush() error               { return nil }
+
+type csvRecordWriter struct {
+	w *csv.Writer
+}
+
+func (w csvRecordWriter) Write(rec TaskRecord) error {
+	return w.w.Write([]string{
+		formatID(&rec.ID),
+		rec.Title,
+		strconv.FormatBool(rec.Done),
+		formatDue(rec.DueAt),
+		string(rec.Priority),
+		strings.Join(rec.Tags, " "),
+		rec.Assignee,
+		formatID(rec.ParentID),
+		formatIDs(rec.Blockers),
+		formatRecurrence(rec.Recurrence),
+	})
+}
+
+func (w csvRecordWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func newRecordWriter(w io.Writer, format ExchangeFormat) (recordWriter, error) {
+	if format != FormatCSV {
+		return ndjsonRecordWriter{enc: json.NewEncoder(w)}, nil
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	return csvRecordWriter{w: cw}, nil
+}
+
+// ImportRowError is one rejected record; Line is 1-based and counts the
+// CSV header.
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"error"`
+}
+
+// readRecords decodes a whole import. A bad record is reported against its
+// line and reading goes on, so one response lists every problem; only an
+// unreadable stream or a broken header stops it.
+func readRecords(r io.Reader, format ExchangeFormat) ([]TaskRecord, []ImportRowError, error) {
+	if format == FormatCSV {
+		return readCSVRecords(r)
+	}
+	return readNDJSONRecords(r)
+}
+
+func readNDJSONRecords(r io.Reader) ([]TaskRecord, []ImportRowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+	records := []TaskRecord{}
+	rowErrors := []ImportRowError{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if len(records)+len(rowErrors) == maxImportRecords {
+			return nil, nil, fmt.Errorf("%w: more than %d records", ErrInvalidImport, maxImportRecords)
+		}
+		var rec TaskRecord
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: "invalid JSON: " + err.Error()})
+			continue
+		}
+		rec.Line = line
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, nil, fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidImport, line+1, maxNDJSONLine)
+		}
+		return nil, nil, err
+	}
+	return records, rowErrors, nil
+}
+
+func readCSVRecords(r io.Reader) ([]TaskRecord, []ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // row width is checked against the header below
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return []TaskRecord{}, []ImportRowError{}, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: header: %w", ErrInvalidImport, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		// Spreadsheets like to save a byte order mark in front of the header.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvColumns, name) {
+			return nil, nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+		if _, dup := columns[name]; dup {
+			return nil, nil, fmt.Errorf("%w: column %q appears twice", ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, nil, fmt.Errorf("%w: a title column is required", ErrInvalidImport)
+	}
+
+	records := []TaskRecord{}
+	rowErrors := []ImportRowError{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// After a quoting error the reader may be out of step with the
+			// rows, so nothing after it can be trusted.
+			rowErrors = append(rowErrors, ImportRowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(records)+len(rowErrors) == maxImportRecords {
+			return nil, nil, fmt.Errorf("%w: more than %d records", ErrInvalidImport, maxImportRecords)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) != len(header) {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: fmt.Sprintf("want %d fields, got %d", len(header), len(row))})
+			continue
+		}
+		rec, err := parseCSVRecord(row, columns)
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rec.Line = line
+		records = append(records, rec)
+	}
+	return records, rowErrors, nil
+}
+
+func parseCSVRecord(row []string, columns map[string]int) (TaskRecord, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	rec := TaskRecord{
+		Title:    row[columns["title"]],
+		Priority: Priority(field("priority")),
+		Tags:     strings.Fields(field("tags")),
+		Assignee: field("assignee"),
+	}
+	if raw := field("id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return TaskRecord{}, fmt.Errorf("id %q is not an integer", raw)
+		}
+		rec.ID = id
+	}
+	if raw := field("done"); raw != "" {
+		done, err := strconv.ParseBool(raw)
+		if err != nil {
+			return TaskRecord{}, fmt.Errorf("done %q must be true or false", raw)
+		}
+		rec.Done = done
+	}
+	if raw := field("due_at"); raw != "" {
+		due, err := parseDueBefore(raw)
+		if err != nil {
+			return TaskRecord{}, fmt.Errorf("due_at %q must be RFC 3339 or YYYY-MM-DD", raw)
+		}
+		rec.DueAt = &due
+	}
+	if raw := field("parent_id"); raw != "" {
+		parent, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return TaskRecord{}, fmt.Errorf("parent_id %q is not an integer", raw)
+		}
+		rec.ParentID = &parent
+	}
+	for _, raw := range strings.Fields(field("blockers")) {
+		blocker, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return TaskRecord{}, fmt.Errorf("blocker %q is not an integer", raw)
+		}
+		rec.Blockers = append(rec.Blockers, blocker)
+	}
+	if raw := field("recurrence"); raw != "" {
+		rec.Recurrence = &Recurrence{}
+		if err := json.Unmarshal([]byte(raw), rec.Recurrence); err != nil {
+			return TaskRecord{}, fmt.Errorf("recurrence is not a JSON object: %v", err)
+		}
+	}
+	return rec, nil
+}
+
+type TaskService struct {
+	repo    TaskRepository
+	cursors *CursorCodec
+	now     func() time.Time
+}
+
+func NewTaskService(repo TaskRepository, cursorSecret []byte) *TaskService {
+	return &TaskService{repo: repo, cursors: NewCursorCodec(cursorSecret), now: time.Now}
+}
+
+// NewTask is what a caller may choose when creating a task; ID, Version and
+// Done are never up to the caller.
+type NewTask struct {
+	Title    string     `json:"title"`
+	DueAt    *time.Time `json:"due_at"`
+	Priority Priority   `json:"priority"`
+	Tags     []string   `json:"tags"`
+	Assignee string     `json:"assignee"`
+	ParentID *int64     `json:"parent_id"`
+	// A recurring task without a due date starts at the rule's next occurrence.
+	Recurrence *Recurrence `json:"recurrence"`
+}
+
+// actor names whoever asked for a write; it ends up in the task history.
+func (s *TaskService) CreateTask(input NewTask, actor string) (Task, error) {
+	task := Task{
+		Title:      input.Title,
+		DueAt:      input.DueAt,
+		Priority:   input.Priority,
+		Tags:       input.Tags,
+		Assignee:   input.Assignee,
+		ParentID:   input.ParentID,
+		Recurrence: input.Recurrence,
+	}
+	if err := s.scheduleFirst(&task); err != nil {
+		return Task{}, err
+	}
+	if err := normalizeTask(&task); err != nil {
+		return Task{}, err
+	}
+	return s.derive(s.repo.Add(task, actor))
+}
+
+// scheduleFirst gives a recurring task without a due date its first occurrence.
+func (s *TaskService) scheduleFirst(task *Task) error {
+	if task.Recurrence == nil || task.Recurrence.isZero() || task.DueAt != nil {
+		return nil
+	}
+	if err := normalizeRecurrence(task.Recurrence); err != nil {
+		return err
+	}
+	due, err := task.Recurrence.Next(s.now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	task.DueAt = &due
+	return nil
+}
+
+// derive fills fields computed from the clock on the way out of the service.
+func (s *TaskService) derive(task Task, err error) (Task, error) {
+	if err != nil {
+		return Task{}, err
+	}
+	task.Overdue = task.IsOverdue(s.now())
+	return task, nil
+}
+
+func (s *TaskService) deriveAll(items []Task) []Task {
+	now := s.now()
+	for i := range items {
+		items[i].Overdue = items[i].IsOverdue(now)
+	}
+	return items
+}
+
+// CompleteTask marks a task done. ifMatch is the version the caller last
+// saw; 0 skips the check.
+func (s *TaskService) CompleteTask(id int64, ifMatch int64, actor string) (Task, error) {
+	done := true
+	return s.UpdateTask(id, TaskPatch{Done: &done}, ifMatch, actor)
+}
+
+func (s *TaskService) Tasks() ([]Task, error) {
+	items, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	return s.deriveAll(items), nil
+}
+
+// ListTasks validates the request, resolves the cursor and asks the repo for
+// one extra row so it knows whether another page exists.
+func (s *TaskService) ListTasks(req ListTasksRequest) (TaskPage, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxPageSize {
+		return TaskPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxPageSize)
+	}
+	sortKey := req.Sort
+	if sortKey == "" {
+		sortKey = "id"
+	}
+	if !validSorts[sortKey] {
+		return TaskPage{}, fmt.Errorf("%w: sort must be one of id, -id, title, -title", ErrInvalidQuery)
+	}
+	if req.Priority != "" && !validPriorities[req.Priority] {
+		return TaskPage{}, fmt.Errorf("%w: priority must be one of low, normal, high, urgent", ErrInvalidQuery)
+	}
+	q := TaskQuery{
+		Limit:     limit + 1,
+		Done:      req.Done,
+		Search:    strings.TrimSpace(req.Search),
+		Tag:       strings.ToLower(strings.TrimSpace(req.Tag)),
+		Assignee:  strings.TrimSpace(req.Assignee),
+		Priority:  req.Priority,
+		DueBefore: req.DueBefore,
+		Sort:      sortKey,
+	}
+	if req.Cursor != "" {
+		payload, err := s.cursors.Decode(req.Cursor)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		if !payload.matches(q) {
+			return TaskPage{}, fmt.Errorf("%w: cursor does not match query", ErrInvalidQuery)
+		}
+		q.After = &payload.After
+	}
+
+	items, err := s.repo.Query(q)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	page := TaskPage{Items: s.deriveAll(items)}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		next, err := s.cursors.Encode(cursorPayload{
+			Sort:      q.Sort,
+			Done:      q.Done,
+			Search:    q.Search,
+			Tag:       q.Tag,
+			Assignee:  q.Assignee,
+			Priority:  q.Priority,
+			DueBefore: q.DueBefore,
+			After:     PagePosition{Title: last.Title, ID: last.ID},
+		})
+		if err != nil {
+			return TaskPage{}, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
+func (s *TaskService) GetTask(id int64) (Task, error) {
+	if id <= 0 {
+		return Task{}, ErrInvalidID
+	}
+	return s.derive(s.repo.Get(id))
+}
+
+// TaskPatch holds optional fields; nil means "leave unchanged". An empty
+// assignee, tag list or recurrence ({}) clears it, parent_id 0 detaches a
+// subtask; a due date can be moved but not removed.
+type TaskPatch struct {
+	Title    *string    `json:"title"`
+	Done     *bool      `json:"done"`
+	DueAt    *time.Time `json:"due_at"`
+	Priority *Priority  `json:"priority"`
+	Tags     *[]string  `json:"tags"`
+	Assignee *string    `json:"assignee"`
+	ParentID *int64     `json:"parent_id"`
+	// Recurrence replaces the whole rule.
+	Recurrence *Recurrence `json:"recurrence"`
+}
+
+// UpdateTask is read-modify-write guarded by the version that was read, so
+// a concurrent change between Get and Update is a conflict, not a lost update.
+func (s *TaskService) UpdateTask(id int64, patch TaskPatch, ifMatch int64, actor string) (Task, error) {
+	if id <= 0 {
+		return Task{}, ErrInvalidID
+	}
+	task, err := s.repo.Get(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if ifMatch != 0 && ifMatch != task.Version {
+		return Task{}, fmt.Errorf("task id %d: %w", id, ErrVersionConflict)
+	}
+	wasDone := task.Done
+	if patch.Title != nil {
+		task.Title = *patch.Title
+	}
+	if patch.Done != nil {
+		task.Done = *patch.Done
+	}
+	if patch.DueAt != nil {
+		task.DueAt = patch.DueAt
+	}
+	if patch.Priority != nil {
+		task.Priority = *patch.Priority
+	}
+	if patch.Tags != nil {
+		task.Tags = *patch.Tags
+	}
+	if patch.Assignee != nil {
+		task.Assignee = *patch.Assignee
+	}
+	if patch.ParentID != nil {
+		task.ParentID = patch.ParentID
+		if *patch.ParentID == 0 {
+			task.ParentID = nil
+		}
+	}
+	if patch.Recurrence != nil {
+		task.Recurrence = patch.Recurrence
+		if err := s.scheduleFirst(&task); err != nil {
+			return Task{}, err
+		}
+	}
+	if err := normalizeTask(&task); err != nil {
+		return Task{}, err
+	}
+	updated, err := s.repo.Update(task, task.Version, actor)
+	if err != nil {
+		return Task{}, err
+	}
+	if updated.Done && !wasDone && updated.Recurrence != nil {
+		// Completing an occurrence creates the next one right away. If that
+		// fails the completion still stands; the scheduler retries, because
+		// a done task that still holds the rule is always due for a spawn.
+		if head, _, err := s.spawnNext(updated, actor); err == nil {
+			updated = head
+		}
+	}
+	return s.derive(updated, nil)
+}
+
+const schedulerActor = "scheduler"
+
+// nextOccurrence is the due date of the task that follows head: the next
+// occurrence, as long as at most one has come since head was due. After
+// downtime the missed ones are skipped and the series resumes at the first
+// occurrence after now, so a month offline creates one task, not thirty.
+func nextOccurrence(head Task, now time.Time) (time.Time, error) {
+	due, err := head.Recurrence.Next(*head.DueAt)
+	if err != nil || due.After(now) {
+		return due, err
+	}
+	following, err := head.Recurrence.Next(due)
+	if err != nil || following.After(now) {
+		return due, err
+	}
+	return head.Recurrence.NextAfter(*head.DueAt, now)
+}
+
+func (s *TaskService) spawnNext(head Task, actor string) (Task, Task, error) {
+	due, err := nextOccurrence(head, s.now())
+	if err != nil {
+		return Task{}, Task{}, err
+	}
+	next := Task{
+		Title:      head.Title,
+		DueAt:      &due,
+		Priority:   head.Priority,
+		Tags:       head.Tags,
+		Assignee:   head.Assignee,
+		ParentID:   head.ParentID,
+		Recurrence: head.Recurrence,
+	}
+	return s.repo.SpawnOccurrence(head.ID, head.Version, next, actor)
+}
+
+// MaterializeDue is one scheduler pass: every series head that is done, or
+// whose next occurrence has arrived, hands its rule to a new task.
+func (s *TaskService) MaterializeDue() ([]Task, error) {
+	now := s.now()
+	items, err := s.repo.SeriesHeads(now)
+	if err != nil {
+		return nil, err
+	}
+	created := []Task{}
+	var errs []error
+	for _, head := range items {
+		next, err := head.Recurrence.Next(*head.DueAt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task id %d: %w", head.ID, err))
+			continue
+		}
+		if !head.Done && now.Before(next) {
+			continue
+		}
+		_, task, err := s.spawnNext(head, schedulerActor)
+		if errors.Is(err, ErrVersionConflict) || errors.Is(err, ErrTaskNotFound) {
+			continue // changed or deleted since SeriesHeads; the next pass sees the new state
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task id %d: %w", head.ID, err))
+			continue
+		}
+		created = append(created, s.deriveAll([]Task{task})[0])
+	}
+	return created, errors.Join(errs...)
+}
+
+// RunScheduler runs one pass per tick until ctx is cancelled. Ticks come in
+// from outside (a time.Ticker in main, a plain channel in tests), which keeps
+// the scheduler free of real time.
+func (s *TaskService) RunScheduler(ctx context.Context, ticks <-chan time.Time, report func([]Task, error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			created, err := s.MaterializeDue()
+			if report != nil {
+				report(created, err)
+			}
+		}
+	}
+}
+
+func (s *TaskService) DeleteTask(id int64, ifMatch int64, actor string) error {
+	if id <= 0 {
+		return ErrInvalidID
+	}
+	return s.repo.Delete(id, ifMatch, actor)
 }
 
-type SQLiteTaskRepo struct {
-	db *sql.DB
+func (s *TaskService) RestoreTask(id int64, actor string) (Task, error) {
+	if id <= 0 {
+		return Task{}, ErrInvalidID
+	}
+	return s.derive(s.repo.Restore(id, actor))
 }
 
-func NewSQLiteTaskRepo(db *sql.DB) *SQLiteTaskRepo {
-	return &SQLiteTaskRepo{db: db}
+func (s *TaskService) TaskHistory(id int64) ([]HistoryEntry, error) {
+	if id <= 0 {
+		return nil, ErrInvalidID
+	}
+	return s.repo.History(id)
 }
 
-func (r *SQLiteTaskRepo) Migrate() error {
-	_, err := r.db.Exec(`
-CREATE TABLE IF NOT EXISTS tasks (
-  id INTEGER PRIMARY KEY AUTOINCREMENT,
-  title TEXT NOT NULL,
-  done INTEGER NOT NULL DEFAULT 0
-);`)
-	return err
+func (s *TaskService) AddBlocker(id, blockerID int64, actor string) error {
+	if id <= 0 || blockerID <= 0 {
+		return ErrInvalidID
+	}
+	if id == blockerID {
+		return fmt.Errorf("task id %d cannot block itself: %w", id, ErrDependencyCycle)
+	}
+	return s.repo.AddBlocker(id, blockerID, actor)
 }
 
-func (r *SQLiteTaskRepo) List() ([]Task, error) {
-	rows, err := r.db.Query(`SELECT id, title, done FROM tasks ORDER BY id`)
+func (s *TaskService) RemoveBlocker(id, blockerID int64, actor string) error {
+	if id <= 0 || blockerID <= 0 {
+		return ErrInvalidID
+	}
+	return s.repo.RemoveBlocker(id, blockerID, actor)
+}
+
+// TaskGraph collects the task plus everything upstream of it (blockers and
+// subtasks, transitively) and orders them with Kahn's algorithm. Among tasks
+// that are ready at the same time the lowest id goes first, so the order is
+// stable across calls and adapters.
+func (s *TaskService) TaskGraph(id int64) (TaskGraph, error) {
+	if id <= 0 {
+		return TaskGraph{}, ErrInvalidID
+	}
+	if _, err := s.repo.Get(id); err != nil {
+		return TaskGraph{}, err
+	}
+	all, err := s.repo.Edges()
 	if err != nil {
-		return nil, err
+		return TaskGraph{}, err
+	}
+	upstream := map[int64][]TaskEdge{}
+	for _, e := range all {
+		upstream[e.To] = append(upstream[e.To], e)
 	}
-	defer rows.Close()
 
-	items := []Task{}
-	for rows.Next() {
-		var t Task
-		var doneInt int
-		if err := rows.Scan(&t.ID, &t.Title, &doneInt); err != nil {
-			return nil, err
+	nodes := map[int64]bool{id: true}
+	edges := []TaskEdge{}
+	stack := []int64{id}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, e := range upstream[n] {
+			edges = append(edges, e)
+			if !nodes[e.From] {
+				nodes[e.From] = true
+				stack = append(stack, e.From)
+			}
 		}
-		t.Done = doneInt == 1
-		items = append(items, t)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+
+	indegree := map[int64]int{}
+	downstream := map[int64][]int64{}
+	for _, e := range edges {
+		indegree[e.To]++
+		downstream[e.From] = append(downstream[e.From], e.To)
 	}
-	return items, nil
+	ready := []int64{}
+	for n := range nodes {
+		if indegree[n] == 0 {
+			ready = append(ready, n)
+		}
+	}
+	graph := TaskGraph{Order: []Task{}, Edges: edges}
+	for len(ready) > 0 {
+		slices.Sort(ready)
+		n := ready[0]
+		ready = ready[1:]
+		task, err := s.derive(s.repo.Get(n))
+		if err != nil {
+			return TaskGraph{}, err
+		}
+		graph.Order = append(graph.Order, task)
+		for _, next := range downstream[n] {
+			indegree[next]--
+			if indegree[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if len(graph.Order) != len(nodes) {
+		// Writes reject cycles, so this only fires on data edited behind our back.
+		return TaskGraph{}, fmt.Errorf("task id %d: %w", id, ErrDependencyCycle)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.Kind < b.Kind
+	})
+	return graph, nil
 }
 
-func (r *SQLiteTaskRepo) Add(title string) (Task, error) {
-	result, err := r.db.Exec(`INSERT INTO tasks (title, done) VALUES (?, 0)`, title)
+// ExportTasks streams every live task in id order. It reads keyset pages so
+// only one page of tasks is in memory at a time; a task written while the
+// export runs may or may not be in it, but never twice. Parents and
+// blockers come from Edges, which only links live tasks, so a file never
+// names a task it does not contain.
+func (s *TaskService) ExportTasks(w io.Writer, format ExchangeFormat) error {
+	out, err := newRecordWriter(w, format)
 	if err != nil {
-		return Task{}, err
+		return err
 	}
-	id, err := result.LastInsertId()
+	edges, err := s.repo.Edges()
 	if err != nil {
-		return Task{}, err
+		return err
+	}
+	hasParent := map[int64]bool{}
+	blockers := map[int64][]int64{}
+	for _, e := range edges {
+		if e.Kind == EdgeSubtask {
+			hasParent[e.From] = true
+		} else {
+			blockers[e.To] = append(blockers[e.To], e.From)
+		}
+	}
+	q := TaskQuery{Limit: exportBatchSize, Sort: "id"}
+	for {
+		items, err := s.repo.Query(q)
+		if err != nil {
+			return err
+		}
+		for _, task := range items {
+			rec := recordFromTask(task)
+			if !hasParent[task.ID] {
+				rec.ParentID = nil
+			}
+			rec.Blockers = blockers[task.ID]
+			if err := out.Write(rec); err != nil {
+				return err
+			}
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		if len(items) < exportBatchSize {
+			return nil
+		}
+		q.After = &PagePosition{ID: items[len(items)-1].ID}
 	}
-	return Task{ID: id, Title: title, Done: false}, nil
 }
 
-func (r *SQLiteTaskRepo) MarkDone(id int64) error {
-	result, err := r.db.Exec(`UPDATE tasks SET done = 1 WHERE id = ?`, id)
+// ImportResult reports an import. IDs holds the new id of every record, in
+// input order, and is only filled in once the batch is committed.
+type ImportResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Records int              `json:"records"`
+	IDs     []int64          `json:"ids,omitempty"`
+	Errors  []ImportRowError `json:"errors,omitempty"`
+}
+
+// ImportTasks validates every record before anything is written and then
+// hands the batch to the repository, which writes all of it or none. A
+// dry run stops after validation. Any rejected record rejects the import;
+// the result then lists every problem, not just the first.
+func (s *TaskService) ImportTasks(r io.Reader, format ExchangeFormat, dryRun bool, actor string) (ImportResult, error) {
+	records, rowErrors, err := readRecords(r, format)
 	if err != nil {
-		return err
+		return ImportResult{}, err
 	}
-	affected, err := result.RowsAffected()
+	result := ImportResult{DryRun: dryRun, Records: len(records) + len(rowErrors)}
+	items, order, planErrors := s.planImport(records)
+	result.Errors = append(rowErrors, planErrors...)
+	if len(result.Errors) > 0 {
+		sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+		return result, fmt.Errorf("%w: %d of %d records rejected", ErrInvalidImport, len(result.Errors), result.Records)
+	}
+	if dryRun || len(items) == 0 {
+		return result, nil
+	}
+	created, err := s.repo.Import(items, actor)
 	if err != nil {
+		return ImportResult{}, err
+	}
+	result.IDs = make([]int64, len(records))
+	for i, task := range created {
+		result.IDs[order[i]] = task.ID
+	}
+	return result, nil
+}
+
+// planImport turns records into repository items: normalized tasks, with
+// parent_id and blockers pointing either at another record of the file (by
+// its exported id) or at a task that already exists here. Items come out
+// parents first; order[i] is the record that item i came from.
+func (s *TaskService) planImport(records []TaskRecord) ([]ImportItem, []int, []ImportRowError) {
+	var rowErrors []ImportRowError
+	reject := func(i int, err error) {
+		rowErrors = append(rowErrors, ImportRowError{Line: records[i].Line, Message: err.Error()})
+	}
+
+	bySourceID := map[int64]int{}
+	for i, rec := range records {
+		if rec.ID < 0 {
+			reject(i, fmt.Errorf("%w: id must be positive", ErrInvalidTask))
+			continue
+		}
+		if rec.ID == 0 {
+			continue
+		}
+		if first, dup := bySourceID[rec.ID]; dup {
+			reject(i, fmt.Errorf("%w: id %d already used on line %d", ErrInvalidTask, rec.ID, records[first].Line))
+			continue
+		}
+		bySourceID[rec.ID] = i
+	}
+
+	// existing looks up each id outside the file once, however many
+	// records point at it.
+	found := map[int64]error{}
+	existing := func(id int64, role string) error {
+		err, seen := found[id]
+		if !seen {
+			_, err = s.repo.Get(id)
+			found[id] = err
+		}
+		if errors.Is(err, ErrTaskNotFound) {
+			return fmt.Errorf("%w: %s task %d not found", ErrInvalidTask, role, id)
+		}
 		return err
 	}
-	if affected == 0 {
-		return fmt.Errorf("task id %d not found", id)
+
+	tasks := make([]Task, len(records))
+	parentRecord := map[int]int{}
+	blockerRecords := map[int][]int{}
+	blockerIDs := map[int][]int64{}
+	for i, rec := range records {
+		task := Task{
+			Title:      rec.Title,
+			Done:       rec.Done,
+			DueAt:      rec.DueAt,
+			Priority:   rec.Priority,
+			Tags:       rec.Tags,
+			Assignee:   rec.Assignee,
+			Recurrence: rec.Recurrence,
+		}
+		if rec.ParentID != nil {
+			if p, ok := bySourceID[*rec.ParentID]; ok {
+				parentRecord[i] = p
+			} else {
+				task.ParentID = rec.ParentID
+			}
+		}
+		if err := s.scheduleFirst(&task); err != nil {
+			reject(i, err)
+			continue
+		}
+		if err := normalizeTask(&task); err != nil {
+			reject(i, err)
+			continue
+		}
+		if task.ParentID != nil {
+			if err := existing(*task.ParentID, "parent"); err != nil {
+				reject(i, err)
+				continue
+			}
+		}
+		var blockerErr error
+		for _, b := range rec.Blockers {
+			if rec.ID != 0 && b == rec.ID {
+				blockerErr = fmt.Errorf("blocker %d: %w", b, ErrDependencyCycle)
+				break
+			}
+			if p, ok := bySourceID[b]; ok {
+				blockerRecords[i] = append(blockerRecords[i], p)
+				continue
+			}
+			if blockerErr = existing(b, "blocker"); blockerErr != nil {
+				break
+			}
+			blockerIDs[i] = append(blockerIDs[i], b)
+		}
+		if blockerErr != nil {
+			reject(i, blockerErr)
+			continue
+		}
+		tasks[i] = task
 	}
-	return nil
+	if len(rowErrors) > 0 {
+		return nil, nil, rowErrors
+	}
+
+	// Depth-first over parent links within the file: a record is placed
+	// after its parent, and meeting a record that is still being placed
+	// means the links loop.
+	const (
+		unplaced = iota
+		placing
+		placed
+	)
+	state := make([]int, len(records))
+	position := make([]int, len(records))
+	items := make([]ImportItem, 0, len(records))
+	order := make([]int, 0, len(records))
+	var place func(i int) error
+	place = func(i int) error {
+		switch state[i] {
+		case placing:
+			return fmt.Errorf("parent_id %d: %w", *records[i].ParentID, ErrDependencyCycle)
+		case placed:
+			return nil
+		}
+		state[i] = placing
+		item := ImportItem{Task: tasks[i]}
+		if p, ok := parentRecord[i]; ok {
+			if err := place(p); err != nil {
+				return err
+			}
+			item.ParentItem = &position[p]
+		}
+		state[i] = placed
+		position[i] = len(items)
+		items = append(items, item)
+		order = append(order, i)
+		return nil
+	}
+	for i := range records {
+		if err := place(i); err != nil {
+			reject(i, err)
+		}
+	}
+	if len(rowErrors) > 0 {
+		return nil, nil, rowErrors
+	}
+
+	// A loop can only run through records of the file: a task that exists
+	// already has no edges to tasks that do not. So one walk over the links
+	// between records, both kinds as in checkNewEdge, finds every loop the
+	// batch would close before anything is written. The record reported is
+	// the one the walk came back to.
+	next := make([][]int, len(records))
+	for i := range records {
+		for _, b := range blockerRecords[i] {
+			next[b] = append(next[b], i)
+		}
+		if p, ok := parentRecord[i]; ok {
+			next[i] = append(next[i], p)
+		}
+	}
+	state = make([]int, len(records))
+	looped := map[int]bool{}
+	var walk func(i int)
+	walk = func(i int) {
+		state[i] = placing
+		for _, j := range next[i] {
+			switch state[j] {
+			case unplaced:
+				walk(j)
+			case placing:
+				looped[j] = true
+			}
+		}
+		state[i] = placed
+	}
+	for i := range records {
+		if state[i] == unplaced {
+			walk(i)
+		}
+	}
+	for i := range records {
+		if looped[i] {
+			reject(i, fmt.Errorf("blockers: %w", ErrDependencyCycle))
+		}
+	}
+	if len(rowErrors) > 0 {
+		return nil, nil, rowErrors
+	}
+
+	// Blockers may come later in the file, so they are linked once every
+	// record has its place.
+	for k, i := range order {
+		items[k].Blockers = blockerIDs[i]
+		for _, b := range blockerRecords[i] {
+			items[k].BlockerItems = append(items[k].BlockerItems, position[b])
+		}
+	}
+	return items, order, nil
 }
 
-type TaskService struct {
-	repo TaskRepository
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
 }
 
-func NewTaskService(repo TaskRepository) *TaskService {
-	return &TaskService{repo: repo}
+// statusFromError keeps the error -> HTTP status decision in one place.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrTaskNotDeleted), errors.Is(err, ErrDependencyCycle), errors.Is(err, ErrTaskBlocked):
+		return http.StatusConflict
+	case errors.Is(err, ErrTitleRequired), errors.Is(err, ErrInvalidID), errors.Is(err, ErrInvalidIfMatch), errors.Is(err, ErrInvalidQuery),
+		errors.Is(err, ErrInvalidTask), errors.Is(err, ErrInvalidImport):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
-func (s *TaskService) CreateTask(title string) (Task, error) {
-	clean := strings.TrimSpace(title)
-	if clean == "" {
-		return Task{}, errors.New("title is required")
+func writeError(w http.ResponseWriter, err error) {
+	status := statusFromError(err)
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		msg = "internal error"
 	}
-	return s.repo.Add(clean)
+	writeJSON(w, status, map[string]string{"error": msg})
 }
 
-func (s *TaskService) CompleteTask(id int64) error {
-	if id <= 0 {
-		return errors.New("id must be positive")
+// parseListRequest maps ?limit=&cursor=&done=&sort=&q=&tag=&assignee=&priority=&due_before=
+// onto the service request.
+func parseListRequest(r *http.Request) (ListTasksRequest, error) {
+	values := r.URL.Query()
+	req := ListTasksRequest{
+		Cursor:   values.Get("cursor"),
+		Search:   values.Get("q"),
+		Tag:      values.Get("tag"),
+		Assignee: values.Get("assignee"),
+		Priority: Priority(values.Get("priority")),
+		Sort:     values.Get("sort"),
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return ListTasksRequest{}, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidQuery)
+		}
+		req.Limit = limit
+	}
+	if raw := values.Get("done"); raw != "" {
+		done, err := strconv.ParseBool(raw)
+		if err != nil {
+			return ListTasksRequest{}, fmt.Errorf("%w: done must be true or false", ErrInvalidQuery)
+		}
+		req.Done = &done
 	}
-	return s.repo.MarkDone(id)
+	if raw := values.Get("due_before"); raw != "" {
+		due, err := parseDueBefore(raw)
+		if err != nil {
+			return ListTasksRequest{}, fmt.Errorf("%w: due_before must be RFC 3339 or YYYY-MM-DD", ErrInvalidQuery)
+		}
+		req.DueBefore = &due
+	}
+	return req, nil
 }
 
-func (s *TaskService) Tasks() ([]Task, error) {
-	return s.repo.List()
+// parseDueBefore accepts a full timestamp or a plain date (midnight UTC).
+func parseDueBefore(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, raw)
 }
 
-type createTaskRequest struct {
-	Title string `json:"title"`
+func etag(task Task) string {
+	return strconv.Quote(strconv.FormatInt(task.Version, 10))
 }
 
-func writeJSON(w http.ResponseWriter, status int, payload any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(payload)
+// ifMatchVersion reads If-Match. A missing header or "*" means no
+// precondition (0); anything that is not a quoted version is a bad request.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, nil
+	}
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil {
+		return 0, ErrInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalidIfMatch
+	}
+	return version, nil
+}
+
+// requestActor is who the history records for this request. There is no
+// authentication here yet, so callers identify themselves with X-Actor.
+func requestActor(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get("X-Actor")); actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+func pathID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(r.PathValue("id"), 10, 64)
+}
+
+// trackingWriter remembers whether any of a streamed body has been sent,
+// which decides if an error can still become a proper error response.
+type trackingWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *trackingWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+// methodNotAllowed answers paths that exist but were called with the wrong verb.
+func methodNotAllowed(allow string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Allow", allow)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
 }
 
 func buildMux(service *TaskService) *http.ServeMux {
@@ -149,62 +3351,268 @@ func buildMux(service *TaskService) *http.ServeMux {
 
 	// LESSON 11-15: list + create task endpoint
 	// Why this matters: transport delegates to service, not SQL directly.
-	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			items, err := service.Tasks()
-			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list failure"})
-				return
-			}
-			writeJSON(w, http.StatusOK, items)
-		case http.MethodPost:
-			var req createTaskRequest
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
-				return
-			}
-			task, err := service.CreateTask(req.Title)
-			if err != nil {
-				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-				return
-			}
-			writeJSON(w, http.StatusCreated, task)
-		default:
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseListRequest(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		page, err := service.ListTasks(req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	mux.HandleFunc("POST /tasks", func(w http.ResponseWriter, r *http.Request) {
+		var req NewTask
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		task, err := service.CreateTask(req, requestActor(r))
+		if err != nil {
+			writeError(w, err)
+			return
 		}
+		w.Header().Set("ETag", etag(task))
+		writeJSON(w, http.StatusCreated, task)
 	})
 
-	// LESSON 16-19: completion endpoint
-	// Why this matters: stable API contract for state transitions.
-	mux.HandleFunc("/tasks/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	// LESSON 16-19: single-resource endpoints
+	// Why this matters: method + path patterns replace hand-written path parsing.
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
 			return
 		}
-		path := strings.TrimPrefix(r.URL.Path, "/tasks/")
-		if !strings.HasSuffix(path, "/done") {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		task, err := service.GetTask(id)
+		if err != nil {
+			writeError(w, err)
 			return
 		}
-		idPart := strings.TrimSuffix(path, "/done")
-		var id int64
-		if _, err := fmt.Sscanf(idPart, "%d", &id); err != nil {
+		w.Header().Set("ETag", etag(task))
+		writeJSON(w, http.StatusOK, task)
+	})
+	mux.HandleFunc("PATCH /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		ifMatch, err := ifMatchVersion(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var patch TaskPatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		task, err := service.UpdateTask(id, patch, ifMatch, requestActor(r))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("ETag", etag(task))
+		writeJSON(w, http.StatusOK, task)
+	})
+	mux.HandleFunc("DELETE /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		ifMatch, err := ifMatchVersion(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := service.DeleteTask(id, ifMatch, requestActor(r)); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /tasks/{id}/done", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
 			return
 		}
-		if err := service.CompleteTask(id); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		ifMatch, err := ifMatchVersion(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		task, err := service.CompleteTask(id, ifMatch, requestActor(r))
+		if err != nil {
+			writeError(w, err)
 			return
 		}
+		w.Header().Set("ETag", etag(task))
 		writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
 	})
+	mux.HandleFunc("POST /tasks/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		task, err := service.RestoreTask(id, requestActor(r))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("ETag", etag(task))
+		writeJSON(w, http.StatusOK, task)
+	})
+	mux.HandleFunc("GET /tasks/{id}/history", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		entries, err := service.TaskHistory(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]HistoryEntry{"items": entries})
+	})
+	mux.HandleFunc("POST /tasks/{id}/blockers", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		var req struct {
+			BlockerID int64 `json:"blocker_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if err := service.AddBlocker(id, req.BlockerID, requestActor(r)); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /tasks/{id}/blockers/{blockerID}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		blockerID, err := strconv.ParseInt(r.PathValue("blockerID"), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "blocker id must be integer"})
+			return
+		}
+		if err := service.RemoveBlocker(id, blockerID, requestActor(r)); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /tasks/{id}/graph", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id must be integer"})
+			return
+		}
+		graph, err := service.TaskGraph(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, graph)
+	})
+
+	mux.HandleFunc("GET /tasks/export", func(w http.ResponseWriter, r *http.Request) {
+		format, err := parseExchangeFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", format.contentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="tasks.`+string(format)+`"`)
+		out := &trackingWriter{ResponseWriter: w}
+		if err := service.ExportTasks(out, format); err != nil {
+			if !out.wrote {
+				w.Header().Del("Content-Disposition")
+				writeError(w, err)
+				return
+			}
+			// The 200 is already on the wire; dropping the connection is the
+			// only way left to tell the client the body is incomplete.
+			panic(http.ErrAbortHandler)
+		}
+	})
+	mux.HandleFunc("POST /tasks/import", func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+		format, err := parseExchangeFormat(values.Get("format"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		dryRun := false
+		if raw := values.Get("dry_run"); raw != "" {
+			if dryRun, err = strconv.ParseBool(raw); err != nil {
+				writeError(w, fmt.Errorf("%w: dry_run must be true or false", ErrInvalidQuery))
+				return
+			}
+		}
+		body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+		result, err := service.ImportTasks(body, format, dryRun, requestActor(r))
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("import is larger than %d bytes", tooLarge.Limit)})
+		case len(result.Errors) > 0:
+			writeJSON(w, http.StatusBadRequest, result)
+		case err != nil:
+			writeError(w, err)
+		case dryRun:
+			writeJSON(w, http.StatusOK, result)
+		default:
+			writeJSON(w, http.StatusCreated, result)
+		}
+	})
+
+	// Method-less patterns are less specific, so they only catch wrong verbs.
+	// A GET pattern also answers HEAD, so Allow lists both.
+	mux.HandleFunc("/tasks", methodNotAllowed("GET, HEAD, POST"))
+	mux.HandleFunc("/tasks/{id}", methodNotAllowed("GET, HEAD, PATCH, DELETE"))
+	mux.HandleFunc("/tasks/{id}/done", methodNotAllowed("POST"))
+	mux.HandleFunc("/tasks/{id}/restore", methodNotAllowed("POST"))
+	mux.HandleFunc("/tasks/{id}/history", methodNotAllowed("GET, HEAD"))
+	mux.HandleFunc("/tasks/{id}/blockers", methodNotAllowed("POST"))
+	mux.HandleFunc("/tasks/{id}/blockers/{blockerID}", methodNotAllowed("DELETE"))
+	mux.HandleFunc("/tasks/{id}/graph", methodNotAllowed("GET, HEAD"))
+	// A method-less /tasks/export would clash with GET /tasks/{id} (neither
+	// is more specific), so the wrong verbs are listed one by one.
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		mux.HandleFunc(method+" /tasks/export", methodNotAllowed("GET, HEAD"))
+	}
+	for _, method := range []string{"GET", "PUT", "PATCH", "DELETE"} {
+		mux.HandleFunc(method+" /tasks/import", methodNotAllowed("POST"))
+	}
 
 	// LESSON 20: health endpoint
 	// Why this matters: operational checks are part of real API design.
-	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
+	mux.HandleFunc("/health", methodNotAllowed("GET, HEAD"))
+
+	// Anything unmatched gets the same JSON error shape as the API.
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	})
 
 	return mux
 }
@@ -217,15 +3625,41 @@ func main() {
 	}
 	defer db.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(NewMigrator(db, taskMigrations), os.Args[2:], os.Stdout); err != nil {
+			fmt.Println("migrate error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Startup applies pending migrations and refuses to serve on a mismatch.
 	repo := NewSQLiteTaskRepo(db)
 	if err := repo.Migrate(); err != nil {
 		fmt.Println("migrate error:", err)
 		return
 	}
 
-	service := NewTaskService(repo)
+	secret := []byte(os.Getenv("TASKS_CURSOR_SECRET"))
+	if len(secret) == 0 {
+		secret = randomSecret()
+	}
+	service := NewTaskService(repo, secret)
 	mux := buildMux(service)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	go service.RunScheduler(ctx, ticker.C, func(created []Task, err error) {
+		for _, task := range created {
+			fmt.Println("scheduler created task", task.ID, "due", formatDue(task.DueAt))
+		}
+		if err != nil {
+			fmt.Println("scheduler error:", err)
+		}
+	})
+
 	addr := ":8085"
 	fmt.Println("Go SQLite HTTP lessons server on", addr)
 	if err := http.ListenAndServe(addr, mux); err != nil {